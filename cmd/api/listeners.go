@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/ingest/ais"
+	"go-ingest-service/internal/ingest/graphite"
+	"go-ingest-service/internal/ingest/listener"
+	"go-ingest-service/internal/ingest/nmea"
+	"go-ingest-service/internal/ingest/sparkplug"
+	"go-ingest-service/internal/ingest/statsd"
+)
+
+// startListeners starts the optional protocol listeners that are
+// enabled in the configuration. They run until ctx is cancelled; the returned
+// WaitGroup completes once they have stopped and flushed pending points.
+func startListeners(ctx context.Context) *sync.WaitGroup {
+	cfg := config.AppConfig
+	var wg sync.WaitGroup
+	if cfg.StatsDPort == "" && cfg.GraphitePort == "" && cfg.NMEAPort == "" && cfg.AISPort == "" && cfg.SparkplugBroker == "" {
+		return &wg
+	}
+
+	templates, err := listener.ParseTemplates(cfg.MetricTemplates)
+	if err != nil {
+		log.Fatalf("[API] Invalid METRIC_TEMPLATES: %v", err)
+	}
+
+	// The batcher is stopped only after every listener has returned, so its
+	// final flush includes the points they add while shutting down.
+	batcher := listener.NewBatcher("Listener", cfg.ListenerBatchSize, cfg.ListenerFlushInterval)
+	batcherCtx, stopBatcher := context.WithCancel(context.Background())
+	var listeners sync.WaitGroup
+	run := func(name string, serve func(context.Context) error) {
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			if err := serve(ctx); err != nil {
+				log.Fatalf("[API] %s listener failed: %v", name, err)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		batcher.Run(batcherCtx)
+	}()
+	if cfg.StatsDPort != "" {
+		srv := &statsd.Server{
+			Addr:          ":" + cfg.StatsDPort,
+			FlushInterval: cfg.StatsDFlushInterval,
+			Templates:     templates,
+			DefaultShip:   cfg.ListenerDefaultShipID,
+			Batcher:       batcher,
+		}
+		run("StatsD", srv.ListenAndServe)
+	}
+	if cfg.GraphitePort != "" {
+		srv := &graphite.Server{
+			Addr:        ":" + cfg.GraphitePort,
+			Templates:   templates,
+			DefaultShip: cfg.ListenerDefaultShipID,
+			Batcher:     batcher,
+		}
+		run("Graphite", srv.ListenAndServe)
+	}
+	if cfg.NMEAPort != "" {
+		if cfg.NMEAShipID == "" {
+			log.Fatalf("[API] NMEA_SHIP_ID is required when NMEA_PORT is set")
+		}
+		srv := &nmea.Server{
+			Addr:    ":" + cfg.NMEAPort,
+			ShipID:  cfg.NMEAShipID,
+			Batcher: batcher,
+		}
+		run("NMEA", srv.ListenAndServe)
+	}
+	if cfg.AISPort != "" {
+		srv := &ais.Server{
+			Addr:    ":" + cfg.AISPort,
+			Batcher: batcher,
+		}
+		run("AIS", srv.ListenAndServe)
+	}
+	if cfg.SparkplugBroker != "" {
+		srv := &sparkplug.Server{
+			Broker:   cfg.SparkplugBroker,
+			ClientID: cfg.SparkplugClientID,
+			Username: cfg.SparkplugUsername,
+			Password: cfg.SparkplugPassword,
+			Topic:    cfg.SparkplugTopic,
+			Batcher:  batcher,
+			Rebirth:  cfg.SparkplugRebirth,
+		}
+		run("Sparkplug", srv.ListenAndServe)
+	}
+
+	go func() {
+		listeners.Wait()
+		stopBatcher()
+	}()
+	return &wg
+}
@@ -1,26 +1,49 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"google.golang.org/grpc"
 
+	"go-ingest-service/internal/alerts"
+	"go-ingest-service/internal/anomaly"
 	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/compression"
 	"go-ingest-service/internal/config"
 	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/derived"
+	"go-ingest-service/internal/geofence"
+	"go-ingest-service/internal/heartbeat"
+	"go-ingest-service/internal/ingest/ais"
+	"go-ingest-service/internal/ingest/compat"
+	"go-ingest-service/internal/ingest/csvimport"
+	"go-ingest-service/internal/ingest/grpcingest"
+	"go-ingest-service/internal/ingest/nmea"
+	"go-ingest-service/internal/ingest/senml"
 	general_handler "go-ingest-service/internal/ingest/general"
 	mw "go-ingest-service/internal/middleware"
 	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/sink"
+	"go-ingest-service/internal/transform"
+	"go-ingest-service/internal/validation"
+	"go-ingest-service/internal/webhook"
 )
 
+// grpcStopTimeout bounds how long shutdown waits for open gRPC streams.
+const grpcStopTimeout = 10 * time.Second
+
 func main() {
 	if err := config.LoadConfig(); err != nil {
 		log.Fatalf("[API] Failed to load configuration: %v", err)
@@ -29,8 +52,16 @@ func main() {
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: customHTTPErrorHandler,
+		// Stream large bodies so the NDJSON and CSV endpoints can consume them
+		// incrementally. All other routes are buffered and capped by mw.BodyLimit.
+		StreamRequestBody: true,
 	})
 
+	streamingRoutes := map[string]bool{
+		"/api/v2/ingest/stream": true,
+		"/api/v2/import/csv":    true,
+	}
+
 	// --- Standard Middleware ---
 	app.Use(logger.New(logger.Config{
 		Format: "[API] ${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${error}\n",
@@ -38,11 +69,14 @@ func main() {
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     config.AppConfig.APP_URL,
-		AllowMethods:     "POST,GET,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,X-API-Key",
+		AllowMethods:     "POST,GET,PUT,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Content-Encoding,Accept,X-API-Key,DD-API-KEY,Prefer",
 		AllowCredentials: true,
 	}))
 	app.Use(compress.New())
+	app.Use(mw.BodyLimit(fiber.DefaultBodyLimit, func(c *fiber.Ctx) bool {
+		return c.Method() == fiber.MethodPost && streamingRoutes[c.Path()]
+	}))
 
 	// --- Connections ---
 	if err := db.ConnectDB(); err != nil {
@@ -54,6 +88,9 @@ func main() {
 	}
 	defer cache.CloseRedis()
 
+	// serverCtx is cancelled on shutdown to stop listeners and long-lived connections.
+	serverCtx, stopServer := context.WithCancel(context.Background())
+
 	// --- Health Check Route ---
 	app.Get("/healthz", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "healthy"})
@@ -65,10 +102,93 @@ func main() {
 	// Define the shared middleware chain for ingest routes.
 	ingestChain := []fiber.Handler{
 		mw.APIKeyAuth,
+		mw.RequestDecompression,
 	}
 	apiv1.Post("/ingest", append(ingestChain, general_handler.IngestData)...)
 	apiv1.Post("/ingest/batch", append(ingestChain, general_handler.IngestBatchData)...)
+	apiv1.Post("/ingest/stream", mw.APIKeyAuth, mw.StreamingBody(config.AppConfig.NDJSONMaxBodyBytes), mw.RequestDecompression, general_handler.IngestStreamData)
+	apiv1.Post("/ingest/nmea", append(ingestChain, nmea.IngestNMEA)...)
+	apiv1.Post("/ingest/ais", append(ingestChain, ais.IngestAIS)...)
+	apiv1.Post("/ingest/senml", append(ingestChain, senml.IngestSenML)...)
+	apiv1.Get("/ingest/ws", mw.APIKeyAuth, general_handler.RequireWebSocketUpgrade, general_handler.IngestWebSocket(serverCtx))
+	apiv1.Post("/import/csv", mw.APIKeyAuth, mw.StreamingBody(config.AppConfig.CSVImportMaxBodyBytes), mw.RequestDecompression, csvimport.ImportCSV)
+	apiv1.Get("/import/csv/:id", append(ingestChain, csvimport.GetImportJob)...)
+
+	// --- Alerting Routes ---
+	apiv1.Get("/alerts", mw.APIKeyAuth, alerts.ListAlerts)
+	apiv1.Get("/alerts/rules", mw.APIKeyAuth, alerts.ListAlertRules)
+	apiv1.Post("/alerts/rules", mw.APIKeyAuth, alerts.CreateAlertRule)
+	apiv1.Get("/alerts/rules/:id", mw.APIKeyAuth, alerts.GetAlertRule)
+	apiv1.Put("/alerts/rules/:id", mw.APIKeyAuth, alerts.UpdateAlertRule)
+	apiv1.Delete("/alerts/rules/:id", mw.APIKeyAuth, alerts.DeleteAlertRule)
+	apiv1.Get("/webhooks", mw.APIKeyAuth, webhook.ListWebhooks)
+	apiv1.Post("/webhooks", mw.APIKeyAuth, webhook.CreateWebhook)
+	apiv1.Delete("/webhooks/:id", mw.APIKeyAuth, webhook.DeleteWebhook)
+
+	// --- Ship Status Routes ---
+	apiv1.Get("/ships/:id/status", mw.APIKeyAuth, heartbeat.GetShipStatus)
+	apiv1.Put("/ships/:id/heartbeat", mw.APIKeyAuth, heartbeat.SetHeartbeatExpectations)
+
+	// --- Transform Routes ---
+	apiv1.Get("/transforms", mw.APIKeyAuth, transform.ListTransforms)
+	apiv1.Post("/transforms", mw.APIKeyAuth, transform.CreateTransform)
+	apiv1.Get("/transforms/versions/:version", mw.APIKeyAuth, transform.GetTransformVersion)
+	apiv1.Get("/transforms/:id", mw.APIKeyAuth, transform.GetTransform)
+	apiv1.Put("/transforms/:id", mw.APIKeyAuth, transform.UpdateTransform)
+	apiv1.Delete("/transforms/:id", mw.APIKeyAuth, transform.DeleteTransform)
+
+	// --- Validation Routes ---
+	apiv1.Get("/validation/rules", mw.APIKeyAuth, validation.ListValidationRules)
+	apiv1.Post("/validation/rules", mw.APIKeyAuth, validation.CreateValidationRule)
+	apiv1.Get("/validation/rules/:id", mw.APIKeyAuth, validation.GetValidationRule)
+	apiv1.Put("/validation/rules/:id", mw.APIKeyAuth, validation.UpdateValidationRule)
+	apiv1.Delete("/validation/rules/:id", mw.APIKeyAuth, validation.DeleteValidationRule)
+	apiv1.Get("/quarantine", mw.APIKeyAuth, validation.ListQuarantine)
+	apiv1.Post("/quarantine/release", mw.APIKeyAuth, validation.ReleaseQuarantine)
+	apiv1.Post("/quarantine/discard", mw.APIKeyAuth, validation.DiscardQuarantine)
+
+	// --- Derived Metric Routes ---
+	apiv1.Get("/derived", mw.APIKeyAuth, derived.ListDerivedMetrics)
+	apiv1.Post("/derived", mw.APIKeyAuth, derived.CreateDerivedMetric)
+	apiv1.Get("/derived/:id", mw.APIKeyAuth, derived.GetDerivedMetric)
+	apiv1.Put("/derived/:id", mw.APIKeyAuth, derived.UpdateDerivedMetric)
+	apiv1.Delete("/derived/:id", mw.APIKeyAuth, derived.DeleteDerivedMetric)
 
+	// --- Compression Routes ---
+	apiv1.Get("/compression", mw.APIKeyAuth, compression.ListCompressionRules)
+	apiv1.Post("/compression", mw.APIKeyAuth, compression.CreateCompressionRule)
+	apiv1.Get("/compression/:id", mw.APIKeyAuth, compression.GetCompressionRule)
+	apiv1.Put("/compression/:id", mw.APIKeyAuth, compression.UpdateCompressionRule)
+	apiv1.Delete("/compression/:id", mw.APIKeyAuth, compression.DeleteCompressionRule)
+
+	// --- Geofence Routes ---
+	apiv1.Get("/geofences", mw.APIKeyAuth, geofence.ListGeofences)
+	apiv1.Post("/geofences", mw.APIKeyAuth, geofence.CreateGeofence)
+	apiv1.Get("/geofences/events", mw.APIKeyAuth, geofence.ListGeofenceEvents)
+	apiv1.Get("/geofences/:id", mw.APIKeyAuth, geofence.GetGeofence)
+	apiv1.Put("/geofences/:id", mw.APIKeyAuth, geofence.UpdateGeofence)
+	apiv1.Delete("/geofences/:id", mw.APIKeyAuth, geofence.DeleteGeofence)
+
+	// --- Anomaly Routes ---
+	apiv1.Get("/anomaly/detectors", mw.APIKeyAuth, anomaly.ListAnomalyDetectors)
+	apiv1.Post("/anomaly/detectors", mw.APIKeyAuth, anomaly.CreateAnomalyDetector)
+	apiv1.Get("/anomaly/detectors/:id", mw.APIKeyAuth, anomaly.GetAnomalyDetector)
+	apiv1.Put("/anomaly/detectors/:id", mw.APIKeyAuth, anomaly.UpdateAnomalyDetector)
+	apiv1.Delete("/anomaly/detectors/:id", mw.APIKeyAuth, anomaly.DeleteAnomalyDetector)
+	apiv1.Get("/anomaly/events", mw.APIKeyAuth, anomaly.ListAnomalyEvents)
+
+	// --- Sink Routes ---
+	apiv1.Get("/sinks", mw.APIKeyAuth, sink.ListOutboundSinks)
+	apiv1.Post("/sinks", mw.APIKeyAuth, sink.CreateOutboundSink)
+	apiv1.Get("/sinks/:id", mw.APIKeyAuth, sink.GetOutboundSink)
+	apiv1.Put("/sinks/:id", mw.APIKeyAuth, sink.UpdateOutboundSink)
+	apiv1.Delete("/sinks/:id", mw.APIKeyAuth, sink.DeleteOutboundSink)
+	apiv1.Post("/sinks/:id/replay", mw.APIKeyAuth, sink.ReplayOutboundSink)
+
+	// --- Compatibility Routes ---
+	// OpenTSDB and Datadog agents send their own key headers (Datadog also allows ?api_key=).
+	app.Post("/api/put", mw.APIKeyAuthFrom("X-API-Key", "api_key"), mw.RequestDecompression, compat.OpenTSDBPut)
+	app.Post("/api/v1/series", mw.APIKeyAuthFrom("DD-API-KEY", "api_key"), mw.RequestDecompression, compat.DatadogSeries)
 
 	// --- Graceful Shutdown ---
 	sigChan := make(chan os.Signal, 1)
@@ -82,11 +202,48 @@ func main() {
 		}
 	}()
 
+	// --- Optional gRPC Listener ---
+	var grpcServer *grpc.Server
+	if config.AppConfig.GRPCPort != "" {
+		grpcAddr := ":" + config.AppConfig.GRPCPort
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("[API] Failed to listen for gRPC on %s: %v", grpcAddr, err)
+		}
+		grpcServer = grpcingest.NewServer()
+		go func() {
+			log.Printf("[API] Starting gRPC ingest service on %s", grpcAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("[API] gRPC listener failed: %v", err)
+			}
+		}()
+	}
+
+	// --- Optional Plaintext Protocol Listeners ---
+	listenersDone := startListeners(serverCtx)
+
 	sig := <-sigChan
 	log.Printf("[API] Received signal %v, initiating graceful shutdown...", sig)
+	if grpcServer != nil {
+		// A stream only ends when its client closes it, so GracefulStop could wait forever.
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(grpcStopTimeout):
+			log.Printf("[API] gRPC streams still open after %v, closing them.", grpcStopTimeout)
+			grpcServer.Stop()
+		}
+	}
+	stopServer()
+	listenersDone.Wait()
 	if err := app.Shutdown(); err != nil {
 		log.Printf("[API] Server shutdown failed: %v", err)
 	}
+	csvimport.Shutdown()
 	log.Println("[API] Server gracefully shut down.")
 }
 
@@ -100,7 +257,7 @@ func customHTTPErrorHandler(c *fiber.Ctx, err error) error {
 		message = e.Message
 	}
 
-	log.Printf("[API] ErrorHandler: URL=%s, Error=%v, Status=%d", c.OriginalURL(), err, code)
+	log.Printf("[API] ErrorHandler: Path=%s, Error=%v, Status=%d", c.Path(), err, code)
 
 	return c.Status(code).JSON(models.ErrorResponse{
 		StatusCode: code,
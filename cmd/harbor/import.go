@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/ingest/csvimport"
+)
+
+// runImport uploads a CSV file to the import endpoint and, unless -no-wait is
+// given, polls the job until it finishes and prints its report.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	apiURL := fs.String("url", getEnv("HARBOR_URL", "http://localhost:8000"), "Base URL of the ingest API (env HARBOR_URL)")
+	apiKey := fs.String("api-key", getEnv("HARBOR_API_KEY", ""), "API key (env HARBOR_API_KEY)")
+	timeColumn := fs.String("time-column", "time", "Name of the time column")
+	timeFormat := fs.String("time-format", "rfc3339", "Time format: rfc3339, unix, unix_ms or a Go time layout")
+	shipColumn := fs.String("ship-column", "", "Name of the ship_id column")
+	shipID := fs.String("ship-id", "", "Fixed ship_id for all rows (instead of -ship-column)")
+	cargoColumns := fs.String("cargo-columns", "", "Comma-separated columns to import as cargo_ids (default: all other columns)")
+	delimiter := fs.String("delimiter", ",", "Field delimiter")
+	noWait := fs.Bool("no-wait", false, "Return after the upload instead of waiting for the job to finish")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: harbor import [flags] <file.csv>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one CSV file is required")
+	}
+	if *apiKey == "" {
+		return fmt.Errorf("an API key is required (-api-key or HARBOR_API_KEY)")
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	query := url.Values{}
+	query.Set("time_column", *timeColumn)
+	query.Set("time_format", *timeFormat)
+	query.Set("delimiter", *delimiter)
+	if *shipColumn != "" {
+		query.Set("ship_column", *shipColumn)
+	}
+	if *shipID != "" {
+		query.Set("ship_id", *shipID)
+	}
+	if *cargoColumns != "" {
+		query.Set("cargo_columns", *cargoColumns)
+	}
+
+	base := strings.TrimRight(*apiURL, "/") + "/api/v2/import/csv"
+	req, err := http.NewRequest(http.MethodPost, base+"?"+query.Encode(), file)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("X-API-Key", *apiKey)
+
+	var started struct {
+		JobID string `json:"job_id"`
+	}
+	if err := doJSON(uploadClient, req, http.StatusAccepted, &started); err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	fmt.Printf("Import job %s started\n", started.JobID)
+	if *noWait {
+		return nil
+	}
+
+	for {
+		time.Sleep(time.Second)
+		req, err := http.NewRequest(http.MethodGet, base+"/"+started.JobID, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-API-Key", *apiKey)
+
+		var job csvimport.Job
+		if err := doJSON(pollClient, req, http.StatusOK, &job); err != nil {
+			return fmt.Errorf("failed to fetch job status: %w", err)
+		}
+		fmt.Printf("\r%s: %d rows, %d points queued, %d errors", job.Status, job.RowsProcessed, job.PointsQueued, job.ErrorCount)
+
+		if job.Status == csvimport.StatusCompleted || job.Status == csvimport.StatusFailed {
+			fmt.Println()
+			for _, e := range job.Errors {
+				fmt.Printf("  row %d %s: %s\n", e.Row, e.Column, e.Msg)
+			}
+			if job.ErrorCount > len(job.Errors) {
+				fmt.Printf("  ... and %d more\n", job.ErrorCount-len(job.Errors))
+			}
+			if job.Status == csvimport.StatusFailed {
+				return fmt.Errorf("import failed: %s", job.Error)
+			}
+			return nil
+		}
+	}
+}
+
+// uploadClient has no overall timeout, as large files can take long to send,
+// but gives up when the server does not answer once the upload is written.
+var uploadClient = &http.Client{Transport: &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+	TLSHandshakeTimeout:   30 * time.Second,
+	ResponseHeaderTimeout: 2 * time.Minute,
+}}
+
+// pollClient bounds each job status request so a stalled server cannot hang the CLI.
+var pollClient = &http.Client{Timeout: 30 * time.Second}
+
+// doJSON performs a request and decodes the JSON response, failing on an unexpected status.
+func doJSON(client *http.Client, req *http.Request, wantStatus int, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -9,15 +9,27 @@ import (
 	"os"
 	"os/signal"
 	"reflect"
+	"regexp"
 	"sync"
 	"syscall"
 	"time"
 
+	"go-ingest-service/internal/alerts"
+	"go-ingest-service/internal/anomaly"
 	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/compression"
 	"go-ingest-service/internal/config"
 	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/derived"
+	"go-ingest-service/internal/geofence"
+	"go-ingest-service/internal/heartbeat"
+	"go-ingest-service/internal/ingest/ais"
 	"go-ingest-service/internal/ingest/general"
 	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/sink"
+	"go-ingest-service/internal/transform"
+	"go-ingest-service/internal/validation"
+	"go-ingest-service/internal/webhook"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v4"
@@ -25,6 +37,7 @@ import (
 
 const maxRetries = 3
 const numWorkers = 10 // Number of concurrent DB workers
+const numWebhookSenders = 4
 
 // main sets up the application, health check server, and starts the worker pool.
 func main() {
@@ -70,10 +83,85 @@ func main() {
 	var wg sync.WaitGroup
 	jobChan := make(chan models.QueuedData, config.AppConfig.WorkerBatchSize)
 
+	// Alert rules are evaluated against every committed batch and periodically against stored data.
+	alertEngine := alerts.NewEngine(config.AppConfig.AlertLookback)
+	if err := alertEngine.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load alert rules: %v", err)
+	}
+	go alertEngine.Run(ctx, config.AppConfig.AlertEvalInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		webhook.NewDeliverer().Run(ctx, numWebhookSenders)
+	}()
+
+	// Transforms convert raw values before derived metrics are computed from them.
+	transforms := transform.NewEngine()
+	if err := transforms.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load transforms: %v", err)
+	}
+	go transforms.Run(ctx, config.AppConfig.TransformRefresh)
+
+	// Points failing validation rules are quarantined instead of written.
+	var shipPattern *regexp.Regexp
+	if config.AppConfig.ShipIDPattern != "" {
+		var err error
+		if shipPattern, err = regexp.Compile(config.AppConfig.ShipIDPattern); err != nil {
+			log.Fatalf("[Worker] Invalid SHIP_ID_PATTERN: %v", err)
+		}
+	}
+	validator := validation.NewEngine(shipPattern)
+	if err := validator.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load validation rules: %v", err)
+	}
+	go validator.Run(ctx, config.AppConfig.ValidationRefresh)
+
+	// Derived metrics are computed from every batch before it is written.
+	derivedEngine := derived.NewEngine(config.AppConfig.DerivedMaxHold)
+	if err := derivedEngine.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load derived metrics: %v", err)
+	}
+	go derivedEngine.Run(ctx, config.AppConfig.DerivedRefresh)
+
+	// Compression drops insignificant points just before they are written.
+	compressor := compression.NewEngine()
+	if err := compressor.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load compression rules: %v", err)
+	}
+	go compressor.Run(ctx, config.AppConfig.CompressionRefresh)
+
+	// Ship positions are checked against geofences after every committed batch.
+	geofences := geofence.NewEngine()
+	if err := geofences.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load geofences: %v", err)
+	}
+	go geofences.Run(ctx, config.AppConfig.GeofenceRefresh)
+
+	// Anomaly detectors check every committed batch against the statistics of its series.
+	anomalies := anomaly.NewEngine()
+	if err := anomalies.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load anomaly detectors: %v", err)
+	}
+	go anomalies.Run(ctx, config.AppConfig.AnomalyRefresh)
+
+	// Sinks forward committed points to other systems, each from a queue of its own.
+	sinks := sink.NewManager()
+	if err := sinks.Refresh(ctx); err != nil {
+		log.Printf("[Worker] Failed to load sinks: %v", err)
+	}
+	// Waited for on shutdown so that deliveries popped by the senders are stored back.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sinks.Run(ctx, config.AppConfig.SinkRefresh)
+	}()
+	go heartbeat.Run(ctx, config.AppConfig.HeartbeatInterval, config.AppConfig.HeartbeatExpected)
+
 	// Start the pool of database workers
+	ships := &shipLocks{}
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go dbWorker(ctx, &wg, i+1, jobChan)
+		go dbWorker(ctx, &wg, i+1, jobChan, ships, alertEngine, transforms, validator, derivedEngine, compressor, geofences, anomalies, sinks)
 	}
 
 	// Start the main loop to fetch from Redis and dispatch to workers
@@ -132,26 +220,83 @@ func dispatcherLoop(ctx context.Context, jobChan chan<- models.QueuedData) {
 }
 
 // dbWorker receives jobs and processes them.
-func dbWorker(ctx context.Context, wg *sync.WaitGroup, id int, jobChan <-chan models.QueuedData) {
+func dbWorker(ctx context.Context, wg *sync.WaitGroup, id int, jobChan <-chan models.QueuedData, ships *shipLocks, alertEngine *alerts.Engine, transforms *transform.Engine, validator *validation.Engine, derivedEngine *derived.Engine, compressor *compression.Engine, geofences *geofence.Engine, anomalies *anomaly.Engine, sinks *sink.Manager) {
 	defer wg.Done()
 	log.Printf("[DBWorker %d] Started.", id)
 	for item := range jobChan {
 		var finalErr error
+		var counts models.IngestAck
 		switch item.Type {
 		case "general":
 			batch, err := normalizeToBatch(item.Data)
 			if err != nil {
 				log.Printf("[DBWorker %d] Type mismatch for 'general' data, moving to DLQ: %v", id, err)
 				moveToDLQ(ctx, item)
+				sendAck(ctx, item, models.IngestAck{Status: "failed", Error: "invalid data for type 'general'"})
 				continue
 			}
 
-			if err := insertGeneralBatchWithCopy(ctx, batch); err != nil {
+			transformed := transforms.Apply(batch)
+			// Taken before validation, which checks rates against the latest stored point.
+			unlock := ships.lock(transformed)
+			points, violations := validator.Check(ctx, transformed)
+			// Only the points of the message itself count towards its acknowledgement.
+			counted := make(map[pointKey]bool, len(points))
+			for _, d := range points {
+				counted[keyOf(d)] = true
+			}
+			derivedPoints, saveDerived := derivedEngine.Derive(ctx, points)
+			points = append(derivedPoints, points...)
+			// Only the compressed points are written; everything after the insert sees them all.
+			stored, saveCompression := compressor.Compress(ctx, points)
+			// Quarantine first: a failure retries the whole batch, and re-quarantining is idempotent.
+			var n int64
+			if err := validation.Quarantine(ctx, violations); err != nil {
+				finalErr = fmt.Errorf("failed to quarantine points: %w", err)
+			} else if n, err = insertGeneralBatchWithCopy(ctx, stored, counted); err != nil {
 				finalErr = fmt.Errorf("failed to insert batch: %w", err)
+			} else {
+				if len(violations) > 0 {
+					log.Printf("[DBWorker %d] Quarantined %d point(s) that failed validation.", id, len(violations))
+				}
+				saveDerived(ctx)
+				saveCompression(ctx)
+				alertEngine.EvaluateBatch(ctx, points)
+				if err := heartbeat.Record(ctx, points); err != nil {
+					log.Printf("[DBWorker %d] Failed to record heartbeats: %v", id, err)
+				}
+				geofences.ProcessBatch(ctx, points)
+				// Still under the ship locks, like the derived and compression state.
+				if err := anomalies.ProcessBatch(ctx, points); err != nil {
+					log.Printf("[DBWorker %d] Failed to check for anomalies: %v", id, err)
+				}
+				if err := sinks.Enqueue(ctx, stored); err != nil {
+					log.Printf("[DBWorker %d] Failed to queue points for sinks: %v", id, err)
+				}
+			}
+			unlock()
+			counts.Inserted = n
+			counts.Quarantined = int64(len(violations))
+			counts.Dropped = int64(len(batch) - len(transformed))
+			counts.Compressed = compressedAway(counted, stored)
+			counts.Skipped = int64(len(batch)) - n - counts.Quarantined - counts.Dropped - counts.Compressed
+		case ais.QueueType:
+			records, err := normalizeToShipMetadata(item.Data)
+			if err != nil {
+				log.Printf("[DBWorker %d] Type mismatch for '%s' data, moving to DLQ: %v", id, item.Type, err)
+				moveToDLQ(ctx, item)
+				sendAck(ctx, item, models.IngestAck{Status: "failed", Error: "invalid data for type '" + item.Type + "'"})
+				continue
 			}
+
+			if err := upsertShipMetadata(ctx, records); err != nil {
+				finalErr = fmt.Errorf("failed to upsert ship metadata: %w", err)
+			}
+			counts.Inserted = int64(len(records))
 		default:
 			log.Printf("[DBWorker %d] Unknown data type '%s' in queue, moving to DLQ.", id, item.Type)
 			moveToDLQ(ctx, item)
+			sendAck(ctx, item, models.IngestAck{Status: "failed", Error: "unknown data type"})
 			continue
 		}
 
@@ -160,6 +305,8 @@ func dbWorker(ctx context.Context, wg *sync.WaitGroup, id int, jobChan <-chan mo
 			handleFailedItem(ctx, item)
 		} else {
 			log.Printf("[DBWorker %d] Successfully inserted batch (type: %s).", id, item.Type)
+			counts.Status = "committed"
+			sendAck(ctx, item, counts)
 		}
 	}
 	log.Printf("[DBWorker %d] Shutting down.", id)
@@ -171,6 +318,7 @@ func handleFailedItem(ctx context.Context, item models.QueuedData) {
 	if item.RetryCount > maxRetries {
 		log.Printf("[Worker] Item exceeded max retries (%d). Moving to DLQ. Type: %s", maxRetries, item.Type)
 		moveToDLQ(ctx, item)
+		sendAck(ctx, item, models.IngestAck{Status: "failed", Error: "exceeded max retries"})
 		return
 	}
 
@@ -186,6 +334,18 @@ func handleFailedItem(ctx context.Context, item models.QueuedData) {
 	}
 }
 
+// sendAck notifies a synchronous ingest request of the outcome of its message.
+// It is a no-op for messages that were queued without waiting for a commit.
+func sendAck(ctx context.Context, item models.QueuedData, ack models.IngestAck) {
+	if !item.AwaitAck || item.ID == "" {
+		return
+	}
+	ack.ID = item.ID
+	if err := general.PublishAck(ctx, ack); err != nil {
+		log.Printf("[Worker] Failed to publish ack for message %s: %v", item.ID, err)
+	}
+}
+
 // moveToDLQ sends a job that cannot be processed to the Dead Letter Queue.
 func moveToDLQ(ctx context.Context, item interface{}) {
 	dlqName := config.AppConfig.IngestQueueName + "_dlq"
@@ -259,16 +419,43 @@ func healthzHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 
+// pointKey identifies a row of cargo_data.
+type pointKey struct {
+	time            int64
+	shipID, cargoID string
+}
+
+func keyOf(d general.SensorData) pointKey {
+	return pointKey{d.Time.UnixNano(), d.ShipID, d.CargoID}
+}
+
+// compressedAway counts the points in counted that compression left out of
+// stored, including those it holds back for a later batch.
+func compressedAway(counted map[pointKey]bool, stored []general.SensorData) int64 {
+	kept := make(map[pointKey]bool, len(stored))
+	for _, d := range stored {
+		kept[keyOf(d)] = true
+	}
+	var n int64
+	for key := range counted {
+		if !kept[key] {
+			n++
+		}
+	}
+	return n
+}
+
 // insertGeneralBatchWithCopy uses a temporary table and the COPY protocol for efficient batch inserts.
-func insertGeneralBatchWithCopy(ctx context.Context, batch []general.SensorData) error {
+// It returns the number of rows actually inserted among those in counted; duplicates and null values are skipped.
+func insertGeneralBatchWithCopy(ctx context.Context, batch []general.SensorData, counted map[pointKey]bool) (int64, error) {
 	if len(batch) == 0 {
-		return nil
+		return 0, nil
 	}
 
 
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -278,25 +465,27 @@ func insertGeneralBatchWithCopy(ctx context.Context, batch []general.SensorData)
 			time TIMESTAMPTZ NOT NULL,
 			ship_id TEXT NOT NULL,
 			cargo_id TEXT NOT NULL,
-			value DOUBLE PRECISION
+			value DOUBLE PRECISION,
+			transform_version BIGINT,
+			counted BOOLEAN NOT NULL
 		) ON COMMIT DROP;`, tempTableName)
 
 	if _, err := tx.Exec(ctx, createTempTableSQL); err != nil {
-		return fmt.Errorf("failed to create temp table: %w", err)
+		return 0, fmt.Errorf("failed to create temp table: %w", err)
 	}
 
-	columns := []string{"time", "ship_id", "cargo_id", "value"}
+	columns := []string{"time", "ship_id", "cargo_id", "value", "transform_version", "counted"}
 	rows := make([][]interface{}, 0, len(batch))
 	for _, data := range batch {
 		if data.Value == nil {
 			continue
 		}
-		rows = append(rows, []interface{}{data.Time, data.ShipID, data.CargoID, *data.Value})
+		rows = append(rows, []interface{}{data.Time, data.ShipID, data.CargoID, *data.Value, data.TransformVersion, counted[keyOf(data)]})
 	}
 
 	if len(rows) == 0 {
 		// Commit transaction to ensure temp table is dropped.
-		return tx.Commit(ctx)
+		return 0, tx.Commit(ctx)
 	}
 
 	_, err = tx.CopyFrom(
@@ -306,19 +495,31 @@ func insertGeneralBatchWithCopy(ctx context.Context, batch []general.SensorData)
 		pgx.CopyFromRows(rows),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to copy data to temp table: %w", err)
+		return 0, fmt.Errorf("failed to copy data to temp table: %w", err)
 	}
 
 	insertFromTempSQL := fmt.Sprintf(`
-		INSERT INTO cargo_data (time, ship_id, cargo_id, value)
-		SELECT time, ship_id, cargo_id, value FROM %s
-		ON CONFLICT (time, ship_id, cargo_id) DO NOTHING;`,
+		WITH inserted AS (
+			INSERT INTO cargo_data (time, ship_id, cargo_id, value, transform_version)
+			SELECT time, ship_id, cargo_id, value, transform_version FROM %[1]s
+			ON CONFLICT (time, ship_id, cargo_id) DO NOTHING
+			RETURNING time, ship_id, cargo_id
+		)
+		SELECT count(*) FROM inserted i
+		WHERE EXISTS (
+			SELECT 1 FROM %[1]s t
+			WHERE t.counted AND t.time = i.time AND t.ship_id = i.ship_id AND t.cargo_id = i.cargo_id
+		);`,
 		pgx.Identifier{tempTableName}.Sanitize(),
 	)
 
-	if _, err := tx.Exec(ctx, insertFromTempSQL); err != nil {
-		return fmt.Errorf("failed to insert from temp table: %w", err)
+	var n int64
+	if err := tx.QueryRow(ctx, insertFromTempSQL).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to insert from temp table: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return n, nil
 }
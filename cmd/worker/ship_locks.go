@@ -0,0 +1,43 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"go-ingest-service/internal/ingest/general"
+)
+
+// shipLockStripes is the number of mutexes ship IDs are spread over.
+const shipLockStripes = 256
+
+// shipLocks serializes the batches of each ship across the DB workers.
+// Engines that keep state per series in Redis load it, advance it and store
+// it back once the batch is committed; two workers doing that for the same
+// series at once would start from the same state and the later store would
+// discard what the other one advanced. Likewise, validation measures rates
+// against the latest point of a series, which must already be written.
+type shipLocks [shipLockStripes]sync.Mutex
+
+// lock locks the stripes of every ship in a batch and returns a function that
+// unlocks them. Stripes are locked in index order so that batches sharing
+// ships cannot deadlock.
+func (l *shipLocks) lock(batch []general.SensorData) func() {
+	var seen [shipLockStripes]bool
+	for _, d := range batch {
+		h := fnv.New32a()
+		h.Write([]byte(d.ShipID))
+		seen[h.Sum32()%shipLockStripes] = true
+	}
+	var stripes []int
+	for i, ok := range seen {
+		if ok {
+			stripes = append(stripes, i)
+			l[i].Lock()
+		}
+	}
+	return func() {
+		for _, i := range stripes {
+			l[i].Unlock()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/ingest/ais"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// upsertShipMetadataSQL merges a record into ship_metadata, keeping stored
+// values for fields the new record does not carry.
+const upsertShipMetadataSQL = `
+	INSERT INTO ship_metadata (ship_id, name, call_sign, imo, ship_type, length, beam, draught, destination, eta, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT (ship_id) DO UPDATE SET
+		name        = COALESCE(EXCLUDED.name, ship_metadata.name),
+		call_sign   = COALESCE(EXCLUDED.call_sign, ship_metadata.call_sign),
+		imo         = COALESCE(EXCLUDED.imo, ship_metadata.imo),
+		ship_type   = COALESCE(EXCLUDED.ship_type, ship_metadata.ship_type),
+		length      = COALESCE(EXCLUDED.length, ship_metadata.length),
+		beam        = COALESCE(EXCLUDED.beam, ship_metadata.beam),
+		draught     = COALESCE(EXCLUDED.draught, ship_metadata.draught),
+		destination = COALESCE(EXCLUDED.destination, ship_metadata.destination),
+		eta         = COALESCE(EXCLUDED.eta, ship_metadata.eta),
+		updated_at  = GREATEST(EXCLUDED.updated_at, ship_metadata.updated_at);`
+
+// normalizeToShipMetadata converts the queued payload back into metadata records.
+func normalizeToShipMetadata(data interface{}) ([]ais.ShipMetadata, error) {
+	var records []ais.ShipMetadata
+	dataBytes, _ := json.Marshal(data)
+	if err := json.Unmarshal(dataBytes, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// upsertShipMetadata writes all records in a single transaction.
+func upsertShipMetadata(ctx context.Context, records []ais.ShipMetadata) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, m := range records {
+		batch.Queue(upsertShipMetadataSQL, m.ShipID, m.Name, m.CallSign, m.IMO, m.ShipType,
+			m.Length, m.Beam, m.Draught, m.Destination, m.ETA, m.UpdatedAt)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	results := tx.SendBatch(ctx, batch)
+	for range records {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
+			return fmt.Errorf("failed to upsert ship metadata: %w", err)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -31,3 +31,274 @@ ALTER TABLE cargo_data SET (
 SELECT add_compression_policy('cargo_data', INTERVAL '7 days');
 
 
+
+-- Static and voyage data for ships, e.g. decoded from AIS messages.
+-- Columns are nullable so partial updates can be merged into existing rows.
+CREATE TABLE IF NOT EXISTS ship_metadata (
+    ship_id TEXT PRIMARY KEY,
+    name TEXT,
+    call_sign TEXT,
+    imo BIGINT,
+    ship_type INTEGER,
+    length INTEGER,
+    beam INTEGER,
+    draught DOUBLE PRECISION,
+    destination TEXT,
+    eta TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+
+-- Webhook receivers for events such as alerts. event_types holds glob
+-- patterns like 'alert.*'; an empty array subscribes to every event.
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Threshold alert rules. ship_id and cargo_id are glob patterns; condition
+-- looks like '> 8 for 5m'.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL,
+    condition TEXT NOT NULL,
+    severity TEXT NOT NULL DEFAULT 'warning',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Evaluation state of every rule/series pair: 'ok', 'pending' or 'firing'.
+CREATE TABLE IF NOT EXISTS alert_states (
+    rule_id BIGINT NOT NULL REFERENCES alert_rules (id) ON DELETE CASCADE,
+    ship_id TEXT NOT NULL,
+    cargo_id TEXT NOT NULL,
+    state TEXT NOT NULL,
+    since TIMESTAMPTZ,
+    fired_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    last_value DOUBLE PRECISION,
+    last_time TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (rule_id, ship_id, cargo_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_states_state ON alert_states (state);
+
+-- Heartbeat of every ship: when it last delivered a batch and whether it is
+-- 'online' or 'offline'. A NULL expected_interval_sec uses the default from
+-- HEARTBEAT_DEFAULT_INTERVAL_SEC.
+CREATE TABLE IF NOT EXISTS ship_status (
+    ship_id TEXT PRIMARY KEY,
+    last_seen TIMESTAMPTZ,
+    last_data_time TIMESTAMPTZ,
+    status TEXT NOT NULL,
+    status_changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expected_interval_sec INT
+);
+
+-- Heartbeat of every cargo series. Series are only flagged offline if they
+-- have an expected_interval_sec.
+CREATE TABLE IF NOT EXISTS series_status (
+    ship_id TEXT NOT NULL,
+    cargo_id TEXT NOT NULL,
+    last_seen TIMESTAMPTZ,
+    last_data_time TIMESTAMPTZ,
+    status TEXT NOT NULL,
+    status_changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expected_interval_sec INT,
+    PRIMARY KEY (ship_id, cargo_id)
+);
+
+-- Geofence zones. geometry is a GeoJSON Polygon, MultiPolygon or Point (a
+-- circle of radius_m metres); ship_id is a glob pattern.
+CREATE TABLE IF NOT EXISTS geofence_zones (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'area',
+    ship_id TEXT NOT NULL DEFAULT '*',
+    geometry JSONB NOT NULL,
+    radius_m DOUBLE PRECISION,
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Whether each ship is inside each zone; inside is NULL until its first fix.
+CREATE TABLE IF NOT EXISTS geofence_states (
+    zone_id BIGINT NOT NULL REFERENCES geofence_zones (id) ON DELETE CASCADE,
+    ship_id TEXT NOT NULL,
+    inside BOOLEAN,
+    since TIMESTAMPTZ,
+    last_time TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (zone_id, ship_id)
+);
+
+-- Ships entering ('enter') and leaving ('exit') zones. Events outlive their
+-- zone, so the zone name and kind are copied.
+CREATE TABLE IF NOT EXISTS geofence_events (
+    id BIGSERIAL PRIMARY KEY,
+    zone_id BIGINT NOT NULL,
+    zone_name TEXT NOT NULL,
+    zone_kind TEXT NOT NULL,
+    ship_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    time TIMESTAMPTZ NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_events_ship ON geofence_events (ship_id, time DESC);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_zone ON geofence_events (zone_id, time DESC);
+
+-- Derived metrics: cargo_id is computed from expression, which references
+-- other cargo IDs of the same ship. ship_id is a glob pattern.
+CREATE TABLE IF NOT EXISTS derived_metrics (
+    id BIGSERIAL PRIMARY KEY,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL,
+    expression TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Transforms applied to raw values before they are written. ship_id and
+-- cargo_id are glob patterns; steps is a JSON array such as
+-- [{"type": "linear", "scale": 0.01, "offset": -40}].
+CREATE TABLE IF NOT EXISTS transform_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    steps JSONB NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Every change to transform_rules stores a snapshot of the enabled rules as
+-- a new version. Transformed points reference it in cargo_data.transform_version.
+CREATE TABLE IF NOT EXISTS transform_versions (
+    version BIGSERIAL PRIMARY KEY,
+    rules JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE cargo_data ADD COLUMN IF NOT EXISTS transform_version BIGINT;
+
+-- Plausibility rules checked in the worker. ship_id and cargo_id are glob
+-- patterns; max_rate is the largest plausible change per second.
+CREATE TABLE IF NOT EXISTS validation_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL,
+    min_value DOUBLE PRECISION,
+    max_value DOUBLE PRECISION,
+    max_rate DOUBLE PRECISION,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Points that failed validation, kept for inspection or release into
+-- cargo_data. value may be NaN or infinite.
+CREATE TABLE IF NOT EXISTS quarantine (
+    time TIMESTAMPTZ NOT NULL,
+    ship_id TEXT NOT NULL,
+    cargo_id TEXT NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    transform_version BIGINT,
+    reasons TEXT[] NOT NULL,
+    rule_ids BIGINT[] NOT NULL DEFAULT '{}',
+    quarantined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (time, ship_id, cargo_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantine_quarantined_at ON quarantine (quarantined_at DESC);
+
+-- Lossy compression of series in the worker. ship_id and cargo_id are glob
+-- patterns; the first enabled rule by priority, then id, applies. The
+-- threshold is either deviation or deviation_pct percent of the last written
+-- value; max_gap_sec (0 for none) forces a keep-alive point.
+CREATE TABLE IF NOT EXISTS compression_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL,
+    priority INT NOT NULL DEFAULT 0,
+    method TEXT NOT NULL CHECK (method IN ('deadband', 'swinging_door', 'none')),
+    deviation DOUBLE PRECISION,
+    deviation_pct DOUBLE PRECISION,
+    max_gap_sec INT NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Opt-in anomaly detectors. ship_id and cargo_id are glob patterns; the first
+-- enabled detector by priority, then id, applies to a series. With a
+-- period_sec the period is split into buckets with statistics of their own.
+CREATE TABLE IF NOT EXISTS anomaly_detectors (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL,
+    priority INT NOT NULL DEFAULT 0,
+    alpha DOUBLE PRECISION NOT NULL DEFAULT 0.05,
+    sigma DOUBLE PRECISION NOT NULL DEFAULT 3,
+    warmup INT NOT NULL DEFAULT 30,
+    min_std_dev DOUBLE PRECISION NOT NULL DEFAULT 0,
+    period_sec INT NOT NULL DEFAULT 0,
+    buckets INT NOT NULL DEFAULT 24,
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Anomalous points. Events outlive their detector, so its name is copied;
+-- score is the distance from the mean in standard deviations.
+CREATE TABLE IF NOT EXISTS anomaly_events (
+    id BIGSERIAL PRIMARY KEY,
+    detector_id BIGINT NOT NULL,
+    detector_name TEXT NOT NULL,
+    ship_id TEXT NOT NULL,
+    cargo_id TEXT NOT NULL,
+    time TIMESTAMPTZ NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    mean DOUBLE PRECISION NOT NULL,
+    std_dev DOUBLE PRECISION NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_events_series ON anomaly_events (ship_id, cargo_id, time DESC);
+CREATE INDEX IF NOT EXISTS idx_anomaly_events_time ON anomaly_events (time DESC);
+
+-- Outbound sinks the worker forwards committed points to. ship_id and
+-- cargo_id are glob patterns; url and topic depend on the kind.
+CREATE TABLE IF NOT EXISTS sinks (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('http', 'mqtt', 'kafka')),
+    url TEXT NOT NULL,
+    topic TEXT NOT NULL DEFAULT '',
+    secret TEXT,
+    ship_id TEXT NOT NULL DEFAULT '*',
+    cargo_id TEXT NOT NULL DEFAULT '*',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
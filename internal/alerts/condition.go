@@ -0,0 +1,61 @@
+// Package alerts evaluates threshold rules against incoming data. Rules and
+// the state of every rule/series pair live in Postgres, so several workers can
+// evaluate concurrently; firing and resolved transitions are sent as webhook events.
+package alerts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// conditionPattern matches conditions like "> 8", "<= -2.5 for 5m".
+var conditionPattern = regexp.MustCompile(`^\s*(>=|<=|==|!=|>|<)\s*(\S+?)\s*(?:\s+for\s+(\S+))?\s*$`)
+
+// Condition is a parsed rule condition: the value compared against a
+// threshold, which must hold for at least For before the alert fires.
+type Condition struct {
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseCondition parses `<op> <threshold> [for <duration>]`, where op is one
+// of >, >=, <, <=, == or != and duration uses Go syntax, e.g. 30s, 5m or 1h.
+func ParseCondition(s string) (Condition, error) {
+	m := conditionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Condition{}, fmt.Errorf("condition must look like '> 8' or '> 8 for 5m'")
+	}
+	threshold, err := strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid threshold %q", m[2])
+	}
+	c := Condition{Op: m[1], Threshold: threshold}
+	if m[3] != "" {
+		if c.For, err = time.ParseDuration(m[3]); err != nil || c.For < 0 {
+			return Condition{}, fmt.Errorf("invalid duration %q", m[3])
+		}
+	}
+	return c, nil
+}
+
+// Matches reports whether v satisfies the comparison.
+func (c Condition) Matches(v float64) bool {
+	switch c.Op {
+	case ">":
+		return v > c.Threshold
+	case ">=":
+		return v >= c.Threshold
+	case "<":
+		return v < c.Threshold
+	case "<=":
+		return v <= c.Threshold
+	case "==":
+		return v == c.Threshold
+	case "!=":
+		return v != c.Threshold
+	}
+	return false
+}
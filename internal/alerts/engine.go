@@ -0,0 +1,362 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+	"go-ingest-service/internal/webhook"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Alert states. A series is pending while its condition holds for less than
+// the rule's `for` duration, and firing after that until the condition clears.
+const (
+	StateOK      = "ok"
+	StatePending = "pending"
+	StateFiring  = "firing"
+	// StateResolved only appears in notifications; the series returns to StateOK.
+	StateResolved = "resolved"
+)
+
+// Webhook event types sent on state changes.
+const (
+	EventFiring   = "alert.firing"
+	EventResolved = "alert.resolved"
+)
+
+// Notification is the data of an alert webhook event.
+type Notification struct {
+	RuleID    int64     `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Severity  string    `json:"severity"`
+	Condition string    `json:"condition"`
+	ShipID    string    `json:"ship_id"`
+	CargoID   string    `json:"cargo_id"`
+	State     string    `json:"state"`
+	Value     float64   `json:"value"`
+	Since     time.Time `json:"since"`
+	Time      time.Time `json:"time"`
+}
+
+type sample struct {
+	time  time.Time
+	value float64
+}
+
+type seriesKey struct {
+	rule    *Rule
+	shipID  string
+	cargoID string
+}
+
+// Engine evaluates the enabled rules. Rules are cached in memory and reloaded
+// on every periodic evaluation.
+type Engine struct {
+	*ruleset.Cache[[]Rule]
+	lookback time.Duration
+}
+
+// NewEngine creates an engine; lookback bounds how old the latest value of a
+// series may be for the periodic evaluation to consider it.
+func NewEngine(lookback time.Duration) *Engine {
+	return &Engine{
+		Cache: ruleset.NewCache("Alerts", "rules", func(ctx context.Context) ([]Rule, error) {
+			return ListRules(ctx, true)
+		}),
+		lookback: lookback,
+	}
+}
+
+// Run reloads the rules and evaluates the latest stored values every interval
+// until ctx is cancelled. This fires `for` conditions once their duration has
+// elapsed even if the series sends no further data.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Refresh(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[Alerts] Failed to load rules: %v", err)
+		} else {
+			e.evaluateStored(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateBatch evaluates a committed batch against the rules. `for`
+// durations are measured between point times, so backfilled data is judged by
+// when it was recorded; the periodic evaluation carries them on to the
+// current time. All series of the batch are updated in one transaction.
+func (e *Engine) EvaluateBatch(ctx context.Context, batch []general.SensorData) {
+	rules := e.Current()
+	if len(rules) == 0 {
+		return
+	}
+
+	series := make(map[seriesKey][]sample)
+	for _, d := range batch {
+		if d.Value == nil {
+			continue
+		}
+		for i := range rules {
+			if rules[i].Selects(d.ShipID, d.CargoID) {
+				key := seriesKey{rule: &rules[i], shipID: d.ShipID, cargoID: d.CargoID}
+				series[key] = append(series[key], sample{time: d.Time, value: *d.Value})
+			}
+		}
+	}
+	if len(series) == 0 {
+		return
+	}
+
+	for _, samples := range series {
+		sort.Slice(samples, func(i, j int) bool { return samples[i].time.Before(samples[j].time) })
+	}
+	if err := e.apply(ctx, series, time.Time{}); err != nil {
+		log.Printf("[Alerts] Failed to evaluate %d series: %v", len(series), err)
+	}
+}
+
+// evaluateStored carries pending series on to now. Only pending series can
+// change without new data, so they are read from alert_states instead of
+// querying the latest values of every selected series. Series whose last value
+// is older than the lookback window stay pending.
+func (e *Engine) evaluateStored(ctx context.Context) {
+	rules := e.Current()
+	if len(rules) == 0 {
+		return
+	}
+	byID := make(map[int64]*Rule, len(rules))
+	for i := range rules {
+		byID[rules[i].ID] = &rules[i]
+	}
+
+	now := time.Now()
+	rows, err := db.Pool.Query(ctx, `
+		SELECT rule_id, ship_id, cargo_id FROM alert_states
+		WHERE state = $1 AND last_time > $2`,
+		StatePending, now.Add(-e.lookback))
+	if err != nil {
+		log.Printf("[Alerts] Failed to query pending series: %v", err)
+		return
+	}
+	pending := make(map[seriesKey][]sample)
+	for rows.Next() {
+		var ruleID int64
+		var key seriesKey
+		if err := rows.Scan(&ruleID, &key.shipID, &key.cargoID); err != nil {
+			log.Printf("[Alerts] Failed to read pending series: %v", err)
+			break
+		}
+		// States of rules disabled since are left alone.
+		if key.rule = byID[ruleID]; key.rule != nil {
+			pending[key] = nil
+		}
+	}
+	rows.Close()
+
+	if len(pending) == 0 {
+		return
+	}
+	if err := e.apply(ctx, pending, now); err != nil {
+		log.Printf("[Alerts] Failed to evaluate %d pending series: %v", len(pending), err)
+	}
+}
+
+// seriesState is the stored alert state of one rule/series pair.
+type seriesState struct {
+	state      string
+	since      *time.Time
+	firedAt    *time.Time
+	resolvedAt *time.Time
+	lastValue  *float64
+	lastTime   *time.Time
+}
+
+// apply advances the state of each rule/series pair with its samples (in time
+// order) and, unless now is zero, with the current time. The rows are locked
+// in key order for the update so concurrent workers cannot both send the same
+// transition or deadlock; notifications are published only after the commit.
+func (e *Engine) apply(ctx context.Context, series map[seriesKey][]sample, now time.Time) error {
+	keys := make([]seriesKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.rule.ID != b.rule.ID {
+			return a.rule.ID < b.rule.ID
+		}
+		if a.shipID != b.shipID {
+			return a.shipID < b.shipID
+		}
+		return a.cargoID < b.cargoID
+	})
+	ruleIDs := make([]int64, len(keys))
+	shipIDs := make([]string, len(keys))
+	cargoIDs := make([]string, len(keys))
+	for i, key := range keys {
+		ruleIDs[i], shipIDs[i], cargoIDs[i] = key.rule.ID, key.shipID, key.cargoID
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Rules deleted since they were loaded are skipped rather than failing
+	// the foreign key and with it the whole batch.
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO alert_states (rule_id, ship_id, cargo_id, state)
+		SELECT k.rule_id, k.ship_id, k.cargo_id, 'ok'
+		FROM unnest($1::bigint[], $2::text[], $3::text[]) WITH ORDINALITY AS k (rule_id, ship_id, cargo_id, n)
+		WHERE EXISTS (SELECT 1 FROM alert_rules r WHERE r.id = k.rule_id)
+		ORDER BY k.n
+		ON CONFLICT DO NOTHING`,
+		ruleIDs, shipIDs, cargoIDs); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT s.rule_id, s.ship_id, s.cargo_id, s.state, s.since, s.fired_at, s.resolved_at, s.last_value, s.last_time
+		FROM alert_states s
+		JOIN unnest($1::bigint[], $2::text[], $3::text[]) AS k (rule_id, ship_id, cargo_id)
+			ON s.rule_id = k.rule_id AND s.ship_id = k.ship_id AND s.cargo_id = k.cargo_id
+		ORDER BY s.rule_id, s.ship_id, s.cargo_id
+		FOR UPDATE OF s`,
+		ruleIDs, shipIDs, cargoIDs)
+	if err != nil {
+		return err
+	}
+	type stateKey struct {
+		ruleID          int64
+		shipID, cargoID string
+	}
+	states := make(map[stateKey]*seriesState, len(keys))
+	for rows.Next() {
+		var k stateKey
+		st := &seriesState{}
+		if err := rows.Scan(&k.ruleID, &k.shipID, &k.cargoID, &st.state, &st.since, &st.firedAt, &st.resolvedAt, &st.lastValue, &st.lastTime); err != nil {
+			rows.Close()
+			return err
+		}
+		states[k] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var notifications []Notification
+	updates := &pgx.Batch{}
+	for _, key := range keys {
+		st, ok := states[stateKey{key.rule.ID, key.shipID, key.cargoID}]
+		if !ok {
+			continue // The rule was deleted meanwhile.
+		}
+		changed, notes := advance(key, st, series[key], now)
+		if !changed {
+			continue
+		}
+		notifications = append(notifications, notes...)
+		updates.Queue(`
+			UPDATE alert_states SET
+				state = $4, since = $5, fired_at = $6, resolved_at = $7, last_value = $8, last_time = $9, updated_at = now()
+			WHERE rule_id = $1 AND ship_id = $2 AND cargo_id = $3`,
+			key.rule.ID, key.shipID, key.cargoID, st.state, st.since, st.firedAt, st.resolvedAt, st.lastValue, st.lastTime)
+	}
+	if updates.Len() == 0 {
+		return nil
+	}
+	if err := tx.SendBatch(ctx, updates).Close(); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		eventType := EventFiring
+		if n.State != StateFiring {
+			eventType = EventResolved
+		}
+		log.Printf("[Alerts] Rule %d (%s) %s for %s/%s, value %v", n.RuleID, n.RuleName, n.State, n.ShipID, n.CargoID, n.Value)
+		if err := webhook.Publish(ctx, eventType, n); err != nil {
+			log.Printf("[Alerts] Failed to queue %s notification for rule %d: %v", eventType, n.RuleID, err)
+		}
+	}
+	return nil
+}
+
+// advance applies samples (in time order) to the state of one series and,
+// unless now is zero, carries a pending condition on to now. It reports
+// whether the state changed and the notifications the transitions produce.
+func advance(key seriesKey, st *seriesState, samples []sample, now time.Time) (bool, []Notification) {
+	cond := key.rule.cond
+	var notifications []Notification
+	notify := func(state string, value float64, at time.Time) {
+		if state == StateFiring {
+			st.firedAt = &at
+		} else {
+			st.resolvedAt = &at
+		}
+		n := Notification{
+			RuleID: key.rule.ID, RuleName: key.rule.Name, Severity: key.rule.Severity, Condition: key.rule.Condition,
+			ShipID: key.shipID, CargoID: key.cargoID, State: state, Value: value, Time: at,
+		}
+		if st.since != nil {
+			n.Since = *st.since
+		}
+		notifications = append(notifications, n)
+	}
+	promote := func(value float64, at time.Time) {
+		if st.state == StatePending && st.since != nil && at.Sub(*st.since) >= cond.For {
+			st.state = StateFiring
+			notify(StateFiring, value, at)
+		}
+	}
+
+	changed := false
+	for _, s := range samples {
+		if st.lastTime != nil && !s.time.After(*st.lastTime) {
+			continue // Already evaluated, or arrived out of order.
+		}
+		changed = true
+		if cond.Matches(s.value) {
+			if st.state == StateOK {
+				since := s.time
+				st.state, st.since = StatePending, &since
+			}
+			promote(s.value, s.time)
+		} else {
+			if st.state == StateFiring {
+				notify(StateResolved, s.value, s.time)
+			}
+			st.state, st.since = StateOK, nil
+		}
+		s := s
+		st.lastValue, st.lastTime = &s.value, &s.time
+	}
+
+	// The condition still holds as long as no newer value has cleared it.
+	if !now.IsZero() && st.state == StatePending && st.lastValue != nil {
+		promote(*st.lastValue, now)
+		changed = changed || st.state != StatePending
+	}
+	return changed, notifications
+}
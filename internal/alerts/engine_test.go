@@ -0,0 +1,86 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvance(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+	samples := func(values ...float64) []sample {
+		out := make([]sample, len(values))
+		for i, v := range values {
+			out[i] = sample{time: at(i), value: v}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		samples   []sample
+		now       time.Time
+		wantState string
+		wantNotes []string
+	}{
+		{"below threshold", "> 8", samples(1, 2), time.Time{}, StateOK, nil},
+		{"fires immediately", "> 8", samples(1, 9), time.Time{}, StateFiring, []string{StateFiring}},
+		{"fires and resolves", "> 8", samples(9, 1), time.Time{}, StateOK, []string{StateFiring, StateResolved}},
+		// Batch evaluation passes no current time, so old data is judged by point time only.
+		{"pending until for elapses", "> 8 for 5m", samples(9, 9, 9), time.Time{}, StatePending, nil},
+		{"fires by point time", "> 8 for 2m", samples(9, 9, 9), time.Time{}, StateFiring, []string{StateFiring}},
+		{"carried on to now", "> 8 for 5m", samples(9, 9), at(10), StateFiring, []string{StateFiring}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, err := ParseCondition(tt.condition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule := &Rule{ID: 1, cond: cond}
+			st := &seriesState{state: StateOK}
+			_, notes := advance(seriesKey{rule: rule, shipID: "s", cargoID: "c"}, st, tt.samples, tt.now)
+			if st.state != tt.wantState {
+				t.Errorf("state = %s, want %s", st.state, tt.wantState)
+			}
+			if len(notes) != len(tt.wantNotes) {
+				t.Fatalf("got %d notifications, want %d", len(notes), len(tt.wantNotes))
+			}
+			for i, n := range notes {
+				if n.State != tt.wantNotes[i] {
+					t.Errorf("notification %d = %s, want %s", i, n.State, tt.wantNotes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAdvanceCarriesPendingToNow(t *testing.T) {
+	cond, _ := ParseCondition("> 8 for 5m")
+	rule := &Rule{ID: 1, cond: cond}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	st := &seriesState{state: StateOK}
+	key := seriesKey{rule: rule, shipID: "s", cargoID: "c"}
+
+	advance(key, st, []sample{{time: start, value: 9}}, time.Time{})
+	if st.state != StatePending {
+		t.Fatalf("state = %s, want pending", st.state)
+	}
+	changed, notes := advance(key, st, nil, start.Add(6*time.Minute))
+	if !changed || st.state != StateFiring || len(notes) != 1 {
+		t.Errorf("after 6m: changed=%v state=%s notes=%d, want firing with one notification", changed, st.state, len(notes))
+	}
+}
+
+func TestAdvanceSkipsEvaluatedSamples(t *testing.T) {
+	cond, _ := ParseCondition("> 8")
+	key := seriesKey{rule: &Rule{ID: 1, cond: cond}, shipID: "s", cargoID: "c"}
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	st := &seriesState{state: StateOK, lastTime: &last}
+
+	changed, _ := advance(key, st, []sample{{time: last, value: 9}, {time: last.Add(-time.Minute), value: 9}}, time.Time{})
+	if changed || st.state != StateOK {
+		t.Errorf("old samples changed the state to %s", st.state)
+	}
+}
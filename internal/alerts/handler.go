@@ -0,0 +1,189 @@
+package alerts
+
+import (
+	"net/http"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ruleRequest is the body of POST and PUT /alerts/rules.
+type ruleRequest struct {
+	Name      string `json:"name"      validate:"required,max=200"`
+	ShipID    string `json:"ship_id"   validate:"max=100"`
+	CargoID   string `json:"cargo_id"  validate:"required,max=100"`
+	Condition string `json:"condition" validate:"required,max=100"`
+	Severity  string `json:"severity"  validate:"omitempty,oneof=info warning critical"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// AlertState is the state of one rule/series pair, as listed by GET /alerts.
+type AlertState struct {
+	RuleID     int64      `json:"rule_id"`
+	RuleName   string     `json:"rule_name"`
+	Severity   string     `json:"severity"`
+	ShipID     string     `json:"ship_id"`
+	CargoID    string     `json:"cargo_id"`
+	State      string     `json:"state"`
+	Since      *time.Time `json:"since"`
+	FiredAt    *time.Time `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	LastValue  *float64   `json:"last_value"`
+	LastTime   *time.Time `json:"last_time"`
+}
+
+// parseRuleRequest validates a rule body; ship_id defaults to every ship and
+// severity to "warning". If the rule is nil the error response has already
+// been written and the returned error is the one to pass back to Fiber.
+func parseRuleRequest(c *fiber.Ctx) (*Rule, error) {
+	var req ruleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	if req.Severity == "" {
+		req.Severity = "warning"
+	}
+	for _, f := range []struct{ field, pattern string }{{"ShipID", req.ShipID}, {"CargoID", req.CargoID}} {
+		if err := glob.Validate(f.pattern); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{f.field}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+		}
+	}
+	cond, err := ParseCondition(req.Condition)
+	if err != nil && req.Condition != "" {
+		errs = append(errs, models.ErrorDetail{Loc: []string{"Condition"}, Msg: err.Error(), Type: "validation_error.condition"})
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	return &Rule{
+		Name:      req.Name,
+		ShipID:    req.ShipID,
+		CargoID:   req.CargoID,
+		Condition: req.Condition,
+		Severity:  req.Severity,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		cond:      cond,
+	}, nil
+}
+
+// ListAlertRules returns all alert rules.
+func ListAlertRules(c *fiber.Ctx) error {
+	rules, err := ListRules(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load alert rules")
+	}
+	return c.JSON(rules)
+}
+
+// GetAlertRule returns one alert rule.
+func GetAlertRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	rule, err := GetRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load alert rule")
+	}
+	if rule == nil {
+		return fiber.NewError(http.StatusNotFound, "Alert rule not found")
+	}
+	return c.JSON(rule)
+}
+
+// CreateAlertRule stores a new rule. Workers pick it up on their next
+// evaluation (ALERT_EVAL_INTERVAL_SEC).
+func CreateAlertRule(c *fiber.Ctx) error {
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	if err := CreateRule(c.Context(), rule); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create alert rule")
+	}
+	return c.Status(http.StatusCreated).JSON(rule)
+}
+
+// UpdateAlertRule replaces a rule and resets the state of its series.
+func UpdateAlertRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	rule.ID = int64(id)
+	found, err := UpdateRule(c.Context(), rule)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update alert rule")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Alert rule not found")
+	}
+	return c.JSON(rule)
+}
+
+// DeleteAlertRule removes a rule and its state.
+func DeleteAlertRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	found, err := DeleteRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete alert rule")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Alert rule not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ListAlerts returns the pending and firing alerts, or those in the state
+// given by `?state=`.
+func ListAlerts(c *fiber.Ctx) error {
+	states := []string{StatePending, StateFiring}
+	if state := c.Query("state"); state != "" {
+		if state != StateOK && state != StatePending && state != StateFiring {
+			return fiber.NewError(http.StatusBadRequest, "state must be ok, pending or firing")
+		}
+		states = []string{state}
+	}
+
+	rows, err := db.Pool.Query(c.Context(), `
+		SELECT s.rule_id, r.name, r.severity, s.ship_id, s.cargo_id, s.state,
+		       s.since, s.fired_at, s.resolved_at, s.last_value, s.last_time
+		FROM alert_states s JOIN alert_rules r ON r.id = s.rule_id
+		WHERE s.state = ANY($1)
+		ORDER BY s.since DESC NULLS LAST`, states)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load alerts")
+	}
+	defer rows.Close()
+
+	alerts := []AlertState{}
+	for rows.Next() {
+		var a AlertState
+		if err := rows.Scan(&a.RuleID, &a.RuleName, &a.Severity, &a.ShipID, &a.CargoID, &a.State,
+			&a.Since, &a.FiredAt, &a.ResolvedAt, &a.LastValue, &a.LastTime); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to load alerts")
+		}
+		alerts = append(alerts, a)
+	}
+	if rows.Err() != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load alerts")
+	}
+	return c.JSON(alerts)
+}
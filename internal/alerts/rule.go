@@ -0,0 +1,122 @@
+package alerts
+
+import (
+	"context"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Rule is an alert rule. ShipID and CargoID are glob patterns selecting the
+// series it applies to, e.g. ship "reefer_*" and cargo "temperature".
+type Rule struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ShipID    string    `json:"ship_id"`
+	CargoID   string    `json:"cargo_id"`
+	Condition string    `json:"condition"`
+	Severity  string    `json:"severity"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	cond Condition
+}
+
+// Selects reports whether the rule applies to a series.
+func (r *Rule) Selects(shipID, cargoID string) bool {
+	return glob.Match(r.ShipID, shipID) && glob.Match(r.CargoID, cargoID)
+}
+
+const ruleColumns = `id, name, ship_id, cargo_id, condition, severity, enabled, created_at, updated_at`
+
+func scanRule(row pgx.Row) (Rule, error) {
+	var r Rule
+	err := row.Scan(&r.ID, &r.Name, &r.ShipID, &r.CargoID, &r.Condition, &r.Severity, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	if err == nil {
+		// Conditions are validated before they are stored.
+		r.cond, _ = ParseCondition(r.Condition)
+	}
+	return r, err
+}
+
+// ListRules returns all rules, or only the enabled ones.
+func ListRules(ctx context.Context, enabledOnly bool) ([]Rule, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+ruleColumns+` FROM alert_rules WHERE enabled OR NOT $1 ORDER BY id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule loads one rule; a nil rule means it does not exist.
+func GetRule(ctx context.Context, id int64) (*Rule, error) {
+	r, err := scanRule(db.Pool.QueryRow(ctx, `SELECT `+ruleColumns+` FROM alert_rules WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRule stores a new rule and fills in its ID and timestamps.
+func CreateRule(ctx context.Context, r *Rule) error {
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO alert_rules (name, ship_id, cargo_id, condition, severity, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`,
+		r.Name, r.ShipID, r.CargoID, r.Condition, r.Severity, r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// UpdateRule replaces a rule, reporting whether it existed. The state of its
+// series is reset, as it may no longer match the new condition.
+func UpdateRule(ctx context.Context, r *Rule) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE alert_rules
+		SET name = $2, ship_id = $3, cargo_id = $4, condition = $5, severity = $6, enabled = $7, updated_at = now()
+		WHERE id = $1
+		RETURNING created_at, updated_at`,
+		r.ID, r.Name, r.ShipID, r.CargoID, r.Condition, r.Severity, r.Enabled,
+	).Scan(&r.CreatedAt, &r.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM alert_states WHERE rule_id = $1`, r.ID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// DeleteRule removes a rule and its state, reporting whether it existed.
+func DeleteRule(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
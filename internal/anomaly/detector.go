@@ -0,0 +1,154 @@
+// Package anomaly flags points that stray from the recent behaviour of their
+// series. Opt-in detectors keep an exponentially weighted moving mean and
+// variance per series, optionally one per slot of a seasonal period such as
+// the hour of the day, and record points more than a number of standard
+// deviations away as anomaly events.
+package anomaly
+
+import (
+	"context"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Detector watches the series whose ship and cargo IDs match its glob
+// patterns. Only the first matching enabled detector applies, in priority
+// order (lowest first) and then by ID.
+//
+// Alpha is the weight of each new point in the moving statistics; a point is
+// anomalous once Warmup points have been seen and it is more than Sigma
+// standard deviations from the mean. MinStdDev is a floor for the standard
+// deviation, so near-constant series are not flagged for tiny changes. With a
+// PeriodSec the period is split into Buckets slots with statistics of their
+// own.
+type Detector struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ShipID    string    `json:"ship_id"`
+	CargoID   string    `json:"cargo_id"`
+	Priority  int       `json:"priority"`
+	Alpha     float64   `json:"alpha"`
+	Sigma     float64   `json:"sigma"`
+	Warmup    int       `json:"warmup"`
+	MinStdDev float64   `json:"min_std_dev"`
+	PeriodSec int       `json:"period_sec"`
+	Buckets   int       `json:"buckets"`
+	Notify    bool      `json:"notify"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Selects reports whether the detector applies to a series.
+func (d *Detector) Selects(shipID, cargoID string) bool {
+	return glob.Match(d.ShipID, shipID) && glob.Match(d.CargoID, cargoID)
+}
+
+// bucket returns the seasonal slot of a time, or 0 without a period.
+func (d *Detector) bucket(t time.Time) int {
+	if d.PeriodSec <= 0 || d.Buckets <= 1 {
+		return 0
+	}
+	period := int64(d.PeriodSec) * int64(time.Second)
+	offset := t.UnixNano() % period
+	if offset < 0 {
+		offset += period
+	}
+	return int(offset * int64(d.Buckets) / period)
+}
+
+// slots returns the number of sets of statistics kept per series.
+func (d *Detector) slots() int {
+	if d.PeriodSec <= 0 || d.Buckets <= 1 {
+		return 1
+	}
+	return d.Buckets
+}
+
+const detectorColumns = `id, name, ship_id, cargo_id, priority, alpha, sigma, warmup, min_std_dev, period_sec, buckets, notify, enabled, created_at, updated_at`
+
+func scanDetector(row pgx.Row) (Detector, error) {
+	var d Detector
+	err := row.Scan(&d.ID, &d.Name, &d.ShipID, &d.CargoID, &d.Priority, &d.Alpha, &d.Sigma, &d.Warmup, &d.MinStdDev,
+		&d.PeriodSec, &d.Buckets, &d.Notify, &d.Enabled, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
+
+// ListDetectors returns all detectors, or only the enabled ones, in the order
+// they are matched.
+func ListDetectors(ctx context.Context, enabledOnly bool) ([]Detector, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+detectorColumns+` FROM anomaly_detectors WHERE enabled OR NOT $1 ORDER BY priority, id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	detectors := []Detector{}
+	for rows.Next() {
+		d, err := scanDetector(rows)
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, d)
+	}
+	return detectors, rows.Err()
+}
+
+// GetDetector loads one detector; a nil detector means it does not exist.
+func GetDetector(ctx context.Context, id int64) (*Detector, error) {
+	d, err := scanDetector(db.Pool.QueryRow(ctx, `SELECT `+detectorColumns+` FROM anomaly_detectors WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// CreateDetector stores a new detector and fills in its ID and timestamps.
+func CreateDetector(ctx context.Context, d *Detector) error {
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO anomaly_detectors (name, ship_id, cargo_id, priority, alpha, sigma, warmup, min_std_dev, period_sec, buckets, notify, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`,
+		d.Name, d.ShipID, d.CargoID, d.Priority, d.Alpha, d.Sigma, d.Warmup, d.MinStdDev, d.PeriodSec, d.Buckets, d.Notify, d.Enabled,
+	).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+}
+
+// UpdateDetector replaces a detector, reporting whether it existed. The
+// statistics of its series are reset.
+func UpdateDetector(ctx context.Context, d *Detector) (bool, error) {
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE anomaly_detectors
+		SET name = $2, ship_id = $3, cargo_id = $4, priority = $5, alpha = $6, sigma = $7, warmup = $8, min_std_dev = $9,
+		    period_sec = $10, buckets = $11, notify = $12, enabled = $13, updated_at = now()
+		WHERE id = $1
+		RETURNING created_at, updated_at`,
+		d.ID, d.Name, d.ShipID, d.CargoID, d.Priority, d.Alpha, d.Sigma, d.Warmup, d.MinStdDev, d.PeriodSec, d.Buckets, d.Notify, d.Enabled,
+	).Scan(&d.CreatedAt, &d.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, states.Reset(ctx, d.ID)
+}
+
+// DeleteDetector removes a detector and its statistics, reporting whether it
+// existed. Its events are kept.
+func DeleteDetector(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM anomaly_detectors WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, states.Reset(ctx, id)
+}
@@ -0,0 +1,228 @@
+package anomaly
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+	"go-ingest-service/internal/webhook"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// EventDetected is the webhook event type sent for detectors with notify set.
+const EventDetected = "anomaly.detected"
+
+// states holds the statistics of each detector per series; they expire for
+// series that stopped reporting.
+var states = ruleset.NewStateStore[stateRef, seriesState]("Anomaly", "_anomaly:", 30*24*time.Hour,
+	func(ref stateRef) ruleset.StateKey {
+		return ruleset.StateKey{RuleID: ref.detector.ID, Series: ruleset.Series(ref.shipID, ref.cargoID)}
+	})
+
+// Event is an anomalous point; it is also the data of the webhook events.
+// Score is the distance from the mean in standard deviations, negative below
+// it.
+type Event struct {
+	ID           int64     `json:"id"`
+	DetectorID   int64     `json:"detector_id"`
+	DetectorName string    `json:"detector_name"`
+	ShipID       string    `json:"ship_id"`
+	CargoID      string    `json:"cargo_id"`
+	Time         time.Time `json:"time"`
+	Value        float64   `json:"value"`
+	Mean         float64   `json:"mean"`
+	StdDev       float64   `json:"std_dev"`
+	Score        float64   `json:"score"`
+}
+
+// stats are the moving statistics of one series, or of one seasonal slot.
+type stats struct {
+	Count    int64   `json:"n"`
+	Mean     float64 `json:"m"`
+	Variance float64 `json:"v"`
+}
+
+// add folds a value into the statistics.
+func (s *stats) add(alpha, x float64) {
+	if s.Count == 0 {
+		s.Mean, s.Variance = x, 0
+	} else {
+		diff := x - s.Mean
+		incr := alpha * diff
+		s.Mean += incr
+		s.Variance = (1 - alpha) * (s.Variance + diff*incr)
+	}
+	s.Count++
+}
+
+// seriesState is the state of one detector for one series.
+type seriesState struct {
+	Slots    []stats   `json:"slots"`
+	LastTime time.Time `json:"last_time"`
+}
+
+// Engine applies the enabled detectors. Detectors are cached in memory and
+// reloaded periodically; series statistics live in Redis so they survive
+// restarts.
+type Engine struct {
+	*ruleset.Cache[[]Detector]
+}
+
+// NewEngine creates an engine without detectors; call Refresh to load them.
+func NewEngine() *Engine {
+	return &Engine{ruleset.NewCache("Anomaly", "detectors", func(ctx context.Context) ([]Detector, error) {
+		return ListDetectors(ctx, true)
+	})}
+}
+
+type stateRef struct {
+	detector        *Detector
+	shipID, cargoID string
+}
+
+// ProcessBatch checks the points of a committed batch against the statistics
+// of their series, stores the anomalies found and then advances the
+// statistics. Points at or before the last one seen are ignored. Batches of
+// the same series must not be processed concurrently, or one would advance
+// the statistics from a copy the other has already advanced.
+func (e *Engine) ProcessBatch(ctx context.Context, batch []general.SensorData) error {
+	detectors := e.Current()
+	if len(detectors) == 0 {
+		return nil
+	}
+
+	series := make(map[stateRef][]general.SensorData)
+	var refs []stateRef
+	matched := make(map[[2]string]*Detector)
+	for _, d := range batch {
+		if d.Value == nil || math.IsNaN(*d.Value) || math.IsInf(*d.Value, 0) {
+			continue
+		}
+		key := [2]string{d.ShipID, d.CargoID}
+		det, ok := matched[key]
+		if !ok {
+			for i := range detectors {
+				if detectors[i].Selects(d.ShipID, d.CargoID) {
+					det = &detectors[i]
+					break
+				}
+			}
+			matched[key] = det
+		}
+		if det == nil {
+			continue
+		}
+		ref := stateRef{det, d.ShipID, d.CargoID}
+		if _, ok := series[ref]; !ok {
+			refs = append(refs, ref)
+		}
+		series[ref] = append(series[ref], d)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	loaded, err := states.Load(ctx, refs)
+	if err != nil {
+		return err
+	}
+
+	var events []Event
+	changed := make(map[stateRef]*seriesState)
+	for _, ref := range refs {
+		points := series[ref]
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		st := loaded[ref]
+		if len(st.Slots) != ref.detector.slots() {
+			st.Slots = make([]stats, ref.detector.slots())
+		}
+		for _, d := range points {
+			if !d.Time.After(st.LastTime) {
+				continue
+			}
+			if ev, ok := ref.check(st, d); ok {
+				events = append(events, ev)
+			}
+			st.Slots[ref.detector.bucket(d.Time)].add(ref.detector.Alpha, *d.Value)
+			st.LastTime = d.Time
+			changed[ref] = st
+		}
+	}
+
+	if err := storeEvents(ctx, events); err != nil {
+		return err
+	}
+	if err := states.Save(ctx, changed); err != nil {
+		log.Printf("[Anomaly] Failed to save series statistics: %v", err)
+	}
+
+	for _, ev := range events {
+		log.Printf("[Anomaly] %s/%s value %v at %s is %.1f sigma from the mean %v (detector %d)",
+			ev.ShipID, ev.CargoID, ev.Value, ev.Time.Format(time.RFC3339), ev.Score, ev.Mean, ev.DetectorID)
+	}
+	notify := make(map[int64]bool, len(detectors))
+	for _, det := range detectors {
+		notify[det.ID] = det.Notify
+	}
+	for _, ev := range events {
+		if !notify[ev.DetectorID] {
+			continue
+		}
+		if err := webhook.Publish(ctx, EventDetected, ev); err != nil {
+			log.Printf("[Anomaly] Failed to queue %s notification for detector %d: %v", EventDetected, ev.DetectorID, err)
+		}
+	}
+	return nil
+}
+
+// check compares a point with the statistics of its slot before they include
+// it. Series whose standard deviation is zero are only checked with a
+// MinStdDev.
+func (ref stateRef) check(st *seriesState, d general.SensorData) (Event, bool) {
+	det := ref.detector
+	s := st.Slots[det.bucket(d.Time)]
+	if s.Count == 0 || s.Count < int64(det.Warmup) {
+		return Event{}, false
+	}
+	std := math.Max(math.Sqrt(s.Variance), det.MinStdDev)
+	if std == 0 {
+		return Event{}, false
+	}
+	score := (*d.Value - s.Mean) / std
+	if math.Abs(score) <= det.Sigma {
+		return Event{}, false
+	}
+	return Event{
+		DetectorID: det.ID, DetectorName: det.Name, ShipID: d.ShipID, CargoID: d.CargoID,
+		Time: d.Time, Value: *d.Value, Mean: s.Mean, StdDev: std, Score: score,
+	}, true
+}
+
+// storeEvents inserts anomaly events and fills in their IDs.
+func storeEvents(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	b := &pgx.Batch{}
+	for _, ev := range events {
+		b.Queue(`
+			INSERT INTO anomaly_events (detector_id, detector_name, ship_id, cargo_id, time, value, mean, std_dev, score)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id`,
+			ev.DetectorID, ev.DetectorName, ev.ShipID, ev.CargoID, ev.Time, ev.Value, ev.Mean, ev.StdDev, ev.Score)
+	}
+	results := db.Pool.SendBatch(ctx, b)
+	defer results.Close()
+	for i := range events {
+		if err := results.QueryRow().Scan(&events[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+)
+
+func TestStatsAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		alpha    float64
+		values   []float64
+		mean     float64
+		variance float64
+	}{
+		{"first value", 0.1, []float64{42}, 42, 0},
+		{"exponential weighting", 0.5, []float64{10, 20, 10}, 12.5, 18.75},
+		{"constant series", 0.2, []float64{7, 7, 7, 7}, 7, 0},
+		{"alpha 1 keeps only the latest value", 1, []float64{1, 100, -5}, -5, 0},
+	}
+	for _, tt := range tests {
+		var s stats
+		for _, v := range tt.values {
+			s.add(tt.alpha, v)
+		}
+		if s.Count != int64(len(tt.values)) || math.Abs(s.Mean-tt.mean) > 1e-9 || math.Abs(s.Variance-tt.variance) > 1e-9 {
+			t.Errorf("%s: got n=%d mean=%v variance=%v, want n=%d mean=%v variance=%v",
+				tt.name, s.Count, s.Mean, s.Variance, len(tt.values), tt.mean, tt.variance)
+		}
+	}
+}
+
+// TestStatsConverge checks that the moving statistics of a long alternating
+// series settle near its true mean and variance.
+func TestStatsConverge(t *testing.T) {
+	var s stats
+	for i := 0; i < 2000; i++ {
+		s.add(0.01, 10+float64(i%2*2-1)) // 9, 11, 9, 11, ...
+	}
+	if math.Abs(s.Mean-10) > 0.05 || math.Abs(s.Variance-1) > 0.05 {
+		t.Errorf("mean=%v variance=%v, want about 10 and 1", s.Mean, s.Variance)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	daily := &Detector{PeriodSec: 86400, Buckets: 24}
+	weekly := &Detector{PeriodSec: 7 * 86400, Buckets: 7}
+	tests := []struct {
+		name string
+		d    *Detector
+		t    time.Time
+		want int
+	}{
+		{"no period", &Detector{}, time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC), 0},
+		{"one bucket", &Detector{PeriodSec: 86400, Buckets: 1}, time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC), 0},
+		{"hour of day", daily, time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC), 13},
+		{"midnight", daily, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), 0},
+		{"last instant of the day", daily, time.Date(2024, 5, 1, 23, 59, 59, 999999999, time.UTC), 23},
+		{"time zone does not matter", daily, time.Date(2024, 5, 1, 15, 30, 0, 0, time.FixedZone("CEST", 2*3600)), 13},
+		{"before 1970", daily, time.Date(1969, 12, 31, 22, 15, 0, 0, time.UTC), 22},
+		// 1970-01-01 was a Thursday, so weeks of the Unix epoch start on Thursdays.
+		{"day of week", weekly, time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC), 2},
+	}
+	for _, tt := range tests {
+		if got := tt.d.bucket(tt.t); got != tt.want {
+			t.Errorf("%s: bucket = %d, want %d", tt.name, got, tt.want)
+		}
+		if got := tt.d.bucket(tt.t); got < 0 || got >= tt.d.slots() {
+			t.Errorf("%s: bucket %d outside the %d slots", tt.name, got, tt.d.slots())
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	warm := stats{Count: 50, Mean: 100, Variance: 4} // Standard deviation 2.
+	tests := []struct {
+		name  string
+		det   Detector
+		stats stats
+		value float64
+		score float64 // 0 if the point is not anomalous
+	}{
+		{"within sigma", Detector{Sigma: 3}, warm, 105, 0},
+		{"at sigma", Detector{Sigma: 3}, warm, 106, 0},
+		{"above", Detector{Sigma: 3}, warm, 107, 3.5},
+		{"below", Detector{Sigma: 3}, warm, 90, -5},
+		{"warming up", Detector{Sigma: 3, Warmup: 51}, warm, 200, 0},
+		{"warmed up", Detector{Sigma: 3, Warmup: 50}, warm, 200, 50},
+		{"no statistics", Detector{Sigma: 3}, stats{}, 200, 0},
+		{"constant series", Detector{Sigma: 3}, stats{Count: 50, Mean: 100}, 101, 0},
+		{"constant series with a floor", Detector{Sigma: 3, MinStdDev: 0.25}, stats{Count: 50, Mean: 100}, 101, 4},
+		{"floor below the deviation", Detector{Sigma: 3, MinStdDev: 1}, warm, 107, 3.5},
+	}
+	for _, tt := range tests {
+		det := tt.det
+		ref := stateRef{detector: &det, shipID: "vessel_1", cargoID: "engine_temp"}
+		st := &seriesState{Slots: []stats{tt.stats}}
+		v := tt.value
+		ev, ok := ref.check(st, general.SensorData{Time: at, ShipID: "vessel_1", CargoID: "engine_temp", Value: &v})
+		if ok != (tt.score != 0) {
+			t.Errorf("%s: anomalous = %v, want %v", tt.name, ok, tt.score != 0)
+			continue
+		}
+		if ok && (math.Abs(ev.Score-tt.score) > 1e-9 || ev.Mean != tt.stats.Mean || ev.Value != v || !ev.Time.Equal(at)) {
+			t.Errorf("%s: event %+v, want score %v", tt.name, ev, tt.score)
+		}
+	}
+}
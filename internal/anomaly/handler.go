@@ -0,0 +1,205 @@
+package anomaly
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// detectorRequest is the body of POST and PUT /anomaly/detectors.
+type detectorRequest struct {
+	Name      string   `json:"name"        validate:"required,max=200"`
+	ShipID    string   `json:"ship_id"     validate:"max=100"`
+	CargoID   string   `json:"cargo_id"    validate:"required,max=100"`
+	Priority  int      `json:"priority"`
+	Alpha     *float64 `json:"alpha"       validate:"omitempty,gt=0,lte=1"`
+	Sigma     *float64 `json:"sigma"       validate:"omitempty,gt=0"`
+	Warmup    *int     `json:"warmup"      validate:"omitempty,min=0"`
+	MinStdDev float64  `json:"min_std_dev" validate:"min=0"`
+	PeriodSec int      `json:"period_sec"  validate:"min=0"`
+	Buckets   *int     `json:"buckets"     validate:"omitempty,min=1,max=1440"`
+	Notify    bool     `json:"notify"`
+	Enabled   *bool    `json:"enabled"`
+}
+
+// parseDetectorRequest validates a detector body and fills in the defaults:
+// every ship, alpha 0.05, 3 sigma, a warmup of 30 points and 24 buckets. If
+// the detector is nil the error response has already been written and the
+// returned error is the one to pass back to Fiber.
+func parseDetectorRequest(c *fiber.Ctx) (*Detector, error) {
+	var req detectorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	for _, f := range []struct{ field, pattern string }{{"ShipID", req.ShipID}, {"CargoID", req.CargoID}} {
+		if err := glob.Validate(f.pattern); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{f.field}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	d := &Detector{
+		Name:      req.Name,
+		ShipID:    req.ShipID,
+		CargoID:   req.CargoID,
+		Priority:  req.Priority,
+		Alpha:     0.05,
+		Sigma:     3,
+		Warmup:    30,
+		MinStdDev: req.MinStdDev,
+		PeriodSec: req.PeriodSec,
+		Buckets:   24,
+		Notify:    req.Notify,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if req.Alpha != nil {
+		d.Alpha = *req.Alpha
+	}
+	if req.Sigma != nil {
+		d.Sigma = *req.Sigma
+	}
+	if req.Warmup != nil {
+		d.Warmup = *req.Warmup
+	}
+	if req.Buckets != nil {
+		d.Buckets = *req.Buckets
+	}
+	return d, nil
+}
+
+// ListAnomalyDetectors returns all detectors in the order they are matched.
+func ListAnomalyDetectors(c *fiber.Ctx) error {
+	detectors, err := ListDetectors(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load anomaly detectors")
+	}
+	return c.JSON(detectors)
+}
+
+// GetAnomalyDetector returns one detector.
+func GetAnomalyDetector(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid detector ID")
+	}
+	d, err := GetDetector(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load anomaly detector")
+	}
+	if d == nil {
+		return fiber.NewError(http.StatusNotFound, "Anomaly detector not found")
+	}
+	return c.JSON(d)
+}
+
+// CreateAnomalyDetector stores a new detector. Workers pick it up within
+// ANOMALY_REFRESH_SEC.
+func CreateAnomalyDetector(c *fiber.Ctx) error {
+	d, err := parseDetectorRequest(c)
+	if d == nil {
+		return err
+	}
+	if err := CreateDetector(c.Context(), d); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create anomaly detector")
+	}
+	return c.Status(http.StatusCreated).JSON(d)
+}
+
+// UpdateAnomalyDetector replaces a detector and resets the statistics of its
+// series.
+func UpdateAnomalyDetector(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid detector ID")
+	}
+	d, err := parseDetectorRequest(c)
+	if d == nil {
+		return err
+	}
+	d.ID = int64(id)
+	found, err := UpdateDetector(c.Context(), d)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update anomaly detector")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Anomaly detector not found")
+	}
+	return c.JSON(d)
+}
+
+// DeleteAnomalyDetector removes a detector.
+func DeleteAnomalyDetector(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid detector ID")
+	}
+	found, err := DeleteDetector(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete anomaly detector")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Anomaly detector not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ListAnomalyEvents returns the newest anomalies, optionally filtered by
+// `?ship_id=`, `?cargo_id=` and `?detector_id=`. `?limit=` defaults to 100.
+func ListAnomalyEvents(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		return fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 1000")
+	}
+	var detectorID *int64
+	if s := c.Query("detector_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "Invalid detector_id")
+		}
+		detectorID = &id
+	}
+	var shipID, cargoID *string
+	if s := c.Query("ship_id"); s != "" {
+		shipID = &s
+	}
+	if s := c.Query("cargo_id"); s != "" {
+		cargoID = &s
+	}
+
+	rows, err := db.Pool.Query(c.Context(), `
+		SELECT id, detector_id, detector_name, ship_id, cargo_id, time, value, mean, std_dev, score
+		FROM anomaly_events
+		WHERE ($1::text IS NULL OR ship_id = $1) AND ($2::text IS NULL OR cargo_id = $2)
+		  AND ($3::bigint IS NULL OR detector_id = $3)
+		ORDER BY time DESC, id DESC
+		LIMIT $4`, shipID, cargoID, detectorID, limit)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load anomaly events")
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var ev Event
+		if err := rows.Scan(&ev.ID, &ev.DetectorID, &ev.DetectorName, &ev.ShipID, &ev.CargoID, &ev.Time, &ev.Value, &ev.Mean, &ev.StdDev, &ev.Score); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to load anomaly events")
+		}
+		events = append(events, ev)
+	}
+	if rows.Err() != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load anomaly events")
+	}
+	return c.JSON(events)
+}
@@ -0,0 +1,223 @@
+package compression
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+)
+
+// states holds the state of each rule per series; it expires for series that
+// stopped reporting.
+var states = ruleset.NewStateStore[stateRef, seriesState]("Compression", "_compression:", 7*24*time.Hour,
+	func(ref stateRef) ruleset.StateKey {
+		return ruleset.StateKey{RuleID: ref.rule.ID, Series: ruleset.Series(ref.shipID, ref.cargoID)}
+	})
+
+// sample is a point of a compressed series.
+type sample struct {
+	Time             time.Time `json:"t"`
+	Value            float64   `json:"v"`
+	TransformVersion *int64    `json:"tv,omitempty"`
+}
+
+func sampleOf(d general.SensorData) sample {
+	return sample{Time: d.Time, Value: *d.Value, TransformVersion: d.TransformVersion}
+}
+
+// seriesState is the state of one rule for one series: the last written
+// point, the last point seen and, for swinging-door, the held point not yet
+// written and the slopes of the doors opened from the last written point.
+type seriesState struct {
+	Written *sample `json:"written,omitempty"`
+	Last    *sample `json:"last,omitempty"`
+	Held    *sample `json:"held,omitempty"`
+	Upper   float64 `json:"upper,omitempty"`
+	Lower   float64 `json:"lower,omitempty"`
+}
+
+// Engine applies the enabled rules. Rules are cached in memory and reloaded
+// periodically; series state lives in Redis so it survives restarts.
+type Engine struct {
+	*ruleset.Cache[[]Rule]
+}
+
+// NewEngine creates an engine without rules; call Refresh to load them.
+func NewEngine() *Engine {
+	return &Engine{ruleset.NewCache("Compression", "rules", func(ctx context.Context) ([]Rule, error) {
+		return ListRules(ctx, true)
+	})}
+}
+
+type stateRef struct {
+	rule            *Rule
+	shipID, cargoID string
+}
+
+// Compress returns the points of a batch to write. Series without a rule are
+// written in full. The series state it advanced is only stored by calling
+// save, which should happen once the points are committed so that a retried
+// batch compresses the same way again. Batches of the same series must not
+// be compressed concurrently, or one would advance the state from a copy the
+// other has already advanced.
+//
+// Swinging-door holds back the latest point of a series until a later point
+// shows whether it is needed, so the result can contain points of earlier
+// batches. Points that arrive out of order, at or before the last point seen,
+// are written uncompressed. If the state cannot be loaded the whole batch is
+// written.
+func (e *Engine) Compress(ctx context.Context, batch []general.SensorData) ([]general.SensorData, func(context.Context)) {
+	rules := e.Current()
+	noop := func(context.Context) {}
+	if len(rules) == 0 {
+		return batch, noop
+	}
+
+	kept := make([]general.SensorData, 0, len(batch))
+	series := make(map[stateRef][]general.SensorData)
+	var refs []stateRef
+	matched := make(map[[2]string]*Rule)
+	for _, d := range batch {
+		if d.Value == nil {
+			kept = append(kept, d)
+			continue
+		}
+		key := [2]string{d.ShipID, d.CargoID}
+		rule, ok := matched[key]
+		if !ok {
+			for i := range rules {
+				if rules[i].Selects(d.ShipID, d.CargoID) {
+					rule = &rules[i]
+					break
+				}
+			}
+			matched[key] = rule
+		}
+		if rule == nil || rule.Method == MethodNone {
+			kept = append(kept, d)
+			continue
+		}
+		ref := stateRef{rule, d.ShipID, d.CargoID}
+		if _, ok := series[ref]; !ok {
+			refs = append(refs, ref)
+		}
+		series[ref] = append(series[ref], d)
+	}
+	if len(refs) == 0 {
+		return batch, noop
+	}
+
+	loaded, err := states.Load(ctx, refs)
+	if err != nil {
+		log.Printf("[Compression] Failed to load series state, writing the batch uncompressed: %v", err)
+		return batch, noop
+	}
+
+	changed := make(map[stateRef]*seriesState)
+	for _, ref := range refs {
+		points := series[ref]
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		st := loaded[ref]
+		advanced := false
+		for _, d := range points {
+			if st.Last != nil && !d.Time.After(st.Last.Time) {
+				kept = append(kept, d)
+				continue
+			}
+			s := sampleOf(d)
+			var written []sample
+			if ref.rule.Method == MethodSwingingDoor {
+				written = st.swingingDoor(ref.rule, s)
+			} else {
+				written = st.deadband(ref.rule, s)
+			}
+			st.Last = &s
+			advanced = true
+			for _, w := range written {
+				kept = append(kept, ref.point(w))
+			}
+		}
+		if advanced {
+			changed[ref] = st
+		}
+	}
+
+	return kept, func(ctx context.Context) {
+		if err := states.Save(ctx, changed); err != nil {
+			log.Printf("[Compression] Failed to save series state: %v", err)
+		}
+	}
+}
+
+func (ref stateRef) point(s sample) general.SensorData {
+	v := s.Value
+	return general.SensorData{Time: s.Time, ShipID: ref.shipID, CargoID: ref.cargoID, Value: &v, TransformVersion: s.TransformVersion}
+}
+
+// gapReached reports whether the keep-alive interval has passed since the
+// last written point.
+func (st *seriesState) gapReached(rule *Rule, t time.Time) bool {
+	return rule.MaxGapSec > 0 && t.Sub(st.Written.Time) >= rule.maxGap()
+}
+
+// deadband writes a point if it differs from the last written value by more
+// than the threshold, or once the keep-alive interval has passed.
+func (st *seriesState) deadband(rule *Rule, s sample) []sample {
+	if st.Written == nil || math.Abs(s.Value-st.Written.Value) > rule.threshold(st.Written.Value) || st.gapReached(rule, s.Time) {
+		st.Written = &s
+		return []sample{s}
+	}
+	return nil
+}
+
+// swingingDoor holds each point until the next one. Two doors pivot on the
+// last written point, at the threshold above and below it, and close in on
+// every point held since. When a new point lies outside the doors the held
+// point is written and becomes the new pivot, so the series between written
+// points stays within the threshold of a straight line. Once the keep-alive
+// interval has passed the new point is written as well.
+func (st *seriesState) swingingDoor(rule *Rule, s sample) []sample {
+	if st.Written == nil {
+		st.Written, st.Held = &s, nil
+		return []sample{s}
+	}
+
+	var written []sample
+	if st.Held == nil {
+		st.open(rule, s)
+	} else {
+		upper, lower := st.slopes(rule, s)
+		upper, lower = math.Min(st.Upper, upper), math.Max(st.Lower, lower)
+		if lower > upper {
+			written = append(written, *st.Held)
+			st.Written = st.Held
+			st.open(rule, s)
+		} else {
+			st.Upper, st.Lower, st.Held = upper, lower, &s
+		}
+	}
+
+	if st.gapReached(rule, s.Time) {
+		written = append(written, s)
+		st.Written, st.Held = &s, nil
+	}
+	return written
+}
+
+// slopes returns the slopes from the last written point to the threshold
+// above and below a point.
+func (st *seriesState) slopes(rule *Rule, s sample) (float64, float64) {
+	dev := rule.threshold(st.Written.Value)
+	dt := s.Time.Sub(st.Written.Time).Seconds()
+	return (s.Value + dev - st.Written.Value) / dt, (s.Value - dev - st.Written.Value) / dt
+}
+
+// open starts the doors from the last written point with a new held point.
+func (st *seriesState) open(rule *Rule, s sample) {
+	st.Upper, st.Lower = st.slopes(rule, s)
+	st.Held = &s
+}
@@ -0,0 +1,95 @@
+package compression
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompressSeries(t *testing.T) {
+	one, ten := 1.0, 10.0
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		rule   Rule
+		values []float64 // One per second from t0.
+		want   []int     // Seconds of the points written.
+	}{
+		{
+			name:   "dead-band",
+			rule:   Rule{Method: MethodDeadband, Deviation: &one},
+			values: []float64{0, 0.5, 1, 1.5, 2.2, 2.2, -1},
+			want:   []int{0, 3, 6},
+		},
+		{
+			name:   "dead-band percentage of the last written value",
+			rule:   Rule{Method: MethodDeadband, DeviationPct: &ten},
+			values: []float64{100, 105, 111, 120, 99},
+			want:   []int{0, 2, 4},
+		},
+		{
+			name:   "dead-band keep-alive",
+			rule:   Rule{Method: MethodDeadband, Deviation: &one, MaxGapSec: 3},
+			values: []float64{5, 5, 5, 5, 5, 5, 5, 5},
+			want:   []int{0, 3, 6},
+		},
+		{
+			name:   "swinging-door holds points on a line",
+			rule:   Rule{Method: MethodSwingingDoor, Deviation: &one},
+			values: []float64{0, 1, 2, 3, 10, 10, 10},
+			want:   []int{0, 3, 4},
+		},
+		{
+			name:   "swinging-door tolerates noise within the threshold",
+			rule:   Rule{Method: MethodSwingingDoor, Deviation: &one},
+			values: []float64{0, 0.8, -0.8, 0.5, -0.5, 0},
+			want:   []int{0},
+		},
+		{
+			name:   "swinging-door writes every corner of a zigzag",
+			rule:   Rule{Method: MethodSwingingDoor, Deviation: &one},
+			values: []float64{0, 5, 0, 5, 0},
+			want:   []int{0, 1, 2, 3},
+		},
+		{
+			name:   "swinging-door keep-alive",
+			rule:   Rule{Method: MethodSwingingDoor, Deviation: &one, MaxGapSec: 2},
+			values: []float64{5, 5, 5, 5, 5},
+			want:   []int{0, 2, 4},
+		},
+	}
+	for _, tt := range tests {
+		for _, persist := range []bool{false, true} {
+			st := &seriesState{}
+			var got []int
+			for i, v := range tt.values {
+				if persist {
+					// Every point in a batch of its own, with the state stored in between.
+					raw, err := json.Marshal(st)
+					if err != nil {
+						t.Fatal(err)
+					}
+					st = &seriesState{}
+					if err := json.Unmarshal(raw, st); err != nil {
+						t.Fatal(err)
+					}
+				}
+				s := sample{Time: t0.Add(time.Duration(i) * time.Second), Value: v}
+				var written []sample
+				if tt.rule.Method == MethodSwingingDoor {
+					written = st.swingingDoor(&tt.rule, s)
+				} else {
+					written = st.deadband(&tt.rule, s)
+				}
+				st.Last = &s
+				for _, w := range written {
+					got = append(got, int(w.Time.Sub(t0)/time.Second))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s (persisted %v): wrote points at %v, want %v", tt.name, persist, got, tt.want)
+			}
+		}
+	}
+}
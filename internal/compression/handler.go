@@ -0,0 +1,138 @@
+package compression
+
+import (
+	"net/http"
+
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ruleRequest is the body of POST and PUT /compression.
+type ruleRequest struct {
+	Name         string   `json:"name"          validate:"required,max=200"`
+	ShipID       string   `json:"ship_id"       validate:"max=100"`
+	CargoID      string   `json:"cargo_id"      validate:"required,max=100"`
+	Priority     int      `json:"priority"`
+	Method       string   `json:"method"        validate:"required"`
+	Deviation    *float64 `json:"deviation"`
+	DeviationPct *float64 `json:"deviation_pct"`
+	MaxGapSec    int      `json:"max_gap_sec"   validate:"min=0"`
+	Enabled      *bool    `json:"enabled"`
+}
+
+// parseRuleRequest validates a rule body; ship_id defaults to every ship. If
+// the rule is nil the error response has already been written and the
+// returned error is the one to pass back to Fiber.
+func parseRuleRequest(c *fiber.Ctx) (*Rule, error) {
+	var req ruleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	for _, f := range []struct{ field, pattern string }{{"ShipID", req.ShipID}, {"CargoID", req.CargoID}} {
+		if err := glob.Validate(f.pattern); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{f.field}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+		}
+	}
+	rule := &Rule{
+		Name:         req.Name,
+		ShipID:       req.ShipID,
+		CargoID:      req.CargoID,
+		Priority:     req.Priority,
+		Method:       req.Method,
+		Deviation:    req.Deviation,
+		DeviationPct: req.DeviationPct,
+		MaxGapSec:    req.MaxGapSec,
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	if req.Method != "" {
+		if err := rule.validate(); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{"Method"}, Msg: err.Error(), Type: "validation_error.method"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+	return rule, nil
+}
+
+// ListCompressionRules returns all rules in the order they are matched.
+func ListCompressionRules(c *fiber.Ctx) error {
+	rules, err := ListRules(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load compression rules")
+	}
+	return c.JSON(rules)
+}
+
+// GetCompressionRule returns one rule.
+func GetCompressionRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	rule, err := GetRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load compression rule")
+	}
+	if rule == nil {
+		return fiber.NewError(http.StatusNotFound, "Compression rule not found")
+	}
+	return c.JSON(rule)
+}
+
+// CreateCompressionRule stores a new rule. Workers pick it up within
+// COMPRESSION_REFRESH_SEC.
+func CreateCompressionRule(c *fiber.Ctx) error {
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	if err := CreateRule(c.Context(), rule); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create compression rule")
+	}
+	return c.Status(http.StatusCreated).JSON(rule)
+}
+
+// UpdateCompressionRule replaces a rule and resets the state of its series.
+func UpdateCompressionRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	rule.ID = int64(id)
+	found, err := UpdateRule(c.Context(), rule)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update compression rule")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Compression rule not found")
+	}
+	return c.JSON(rule)
+}
+
+// DeleteCompressionRule removes a rule.
+func DeleteCompressionRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	found, err := DeleteRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete compression rule")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Compression rule not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
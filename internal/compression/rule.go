@@ -0,0 +1,167 @@
+// Package compression thins out series in the worker before they are written,
+// keeping only points that carry significant change. Dead-band writes a point
+// when it moves beyond a threshold from the last written one; swinging-door
+// trending writes the points needed to reconstruct the series by linear
+// interpolation within the threshold. Both write a keep-alive point once the
+// maximum gap since the last written point is reached.
+package compression
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Compression methods. MethodNone exempts the series matched by a rule from
+// broader rules with a lower priority.
+const (
+	MethodDeadband     = "deadband"
+	MethodSwingingDoor = "swinging_door"
+	MethodNone         = "none"
+)
+
+// Rule compresses the series whose ship and cargo IDs match its glob
+// patterns. Only the first matching enabled rule applies, in priority order
+// (lowest first) and then by ID. The threshold is either Deviation, in the
+// units of the series, or DeviationPct percent of the last written value.
+type Rule struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	ShipID       string    `json:"ship_id"`
+	CargoID      string    `json:"cargo_id"`
+	Priority     int       `json:"priority"`
+	Method       string    `json:"method"`
+	Deviation    *float64  `json:"deviation"`
+	DeviationPct *float64  `json:"deviation_pct"`
+	MaxGapSec    int       `json:"max_gap_sec"`
+	Enabled      bool      `json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Selects reports whether the rule applies to a series.
+func (r *Rule) Selects(shipID, cargoID string) bool {
+	return glob.Match(r.ShipID, shipID) && glob.Match(r.CargoID, cargoID)
+}
+
+// threshold returns the allowed deviation from a reference value.
+func (r *Rule) threshold(ref float64) float64 {
+	if r.Deviation != nil {
+		return *r.Deviation
+	}
+	if r.DeviationPct != nil {
+		return math.Abs(ref) * *r.DeviationPct / 100
+	}
+	return 0
+}
+
+// maxGap returns the keep-alive interval, or 0 if there is none.
+func (r *Rule) maxGap() time.Duration {
+	return time.Duration(r.MaxGapSec) * time.Second
+}
+
+// validate checks the method and threshold of a rule.
+func (r *Rule) validate() error {
+	switch r.Method {
+	case MethodNone:
+		return nil
+	case MethodDeadband, MethodSwingingDoor:
+	default:
+		return fmt.Errorf("method must be %s, %s or %s", MethodDeadband, MethodSwingingDoor, MethodNone)
+	}
+	if (r.Deviation == nil) == (r.DeviationPct == nil) {
+		return fmt.Errorf("set exactly one of deviation and deviation_pct")
+	}
+	if (r.Deviation != nil && *r.Deviation < 0) || (r.DeviationPct != nil && *r.DeviationPct < 0) {
+		return fmt.Errorf("the deviation must not be negative")
+	}
+	return nil
+}
+
+const ruleColumns = `id, name, ship_id, cargo_id, priority, method, deviation, deviation_pct, max_gap_sec, enabled, created_at, updated_at`
+
+func scanRule(row pgx.Row) (Rule, error) {
+	var r Rule
+	err := row.Scan(&r.ID, &r.Name, &r.ShipID, &r.CargoID, &r.Priority, &r.Method, &r.Deviation, &r.DeviationPct, &r.MaxGapSec, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// ListRules returns all rules, or only the enabled ones, in the order they
+// are matched.
+func ListRules(ctx context.Context, enabledOnly bool) ([]Rule, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+ruleColumns+` FROM compression_rules WHERE enabled OR NOT $1 ORDER BY priority, id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule loads one rule; a nil rule means it does not exist.
+func GetRule(ctx context.Context, id int64) (*Rule, error) {
+	r, err := scanRule(db.Pool.QueryRow(ctx, `SELECT `+ruleColumns+` FROM compression_rules WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRule stores a new rule and fills in its ID and timestamps.
+func CreateRule(ctx context.Context, r *Rule) error {
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO compression_rules (name, ship_id, cargo_id, priority, method, deviation, deviation_pct, max_gap_sec, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`,
+		r.Name, r.ShipID, r.CargoID, r.Priority, r.Method, r.Deviation, r.DeviationPct, r.MaxGapSec, r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// UpdateRule replaces a rule, reporting whether it existed. The state of its
+// series is reset.
+func UpdateRule(ctx context.Context, r *Rule) (bool, error) {
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE compression_rules
+		SET name = $2, ship_id = $3, cargo_id = $4, priority = $5, method = $6, deviation = $7, deviation_pct = $8,
+		    max_gap_sec = $9, enabled = $10, updated_at = now()
+		WHERE id = $1
+		RETURNING created_at, updated_at`,
+		r.ID, r.Name, r.ShipID, r.CargoID, r.Priority, r.Method, r.Deviation, r.DeviationPct, r.MaxGapSec, r.Enabled,
+	).Scan(&r.CreatedAt, &r.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, states.Reset(ctx, r.ID)
+}
+
+// DeleteRule removes a rule and its state, reporting whether it existed.
+func DeleteRule(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM compression_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, states.Reset(ctx, id)
+}
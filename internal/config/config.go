@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"runtime"
 	"strconv"
 	"time"
 
@@ -25,6 +27,57 @@ type Config struct {
 	WorkerBatchSize       int
 	WorkerPollInterval    time.Duration
 	APP_URL            string
+	SyncIngestTimeout  time.Duration
+	SyncIngestMaxWaiters int
+	NDJSONChunkSize    int
+	NDJSONMaxBodyBytes int64
+	NDJSONMaxLineBytes int
+	CSVImportChunkSize int
+	CSVImportMaxBodyBytes int64
+	CSVImportBackpressureThreshold int64
+	MaxDecompressedBodyBytes int64
+	GRPCPort           string
+	GRPCStreamChunkSize int
+	WSBackpressureThreshold int64
+	StatsDPort             string
+	StatsDFlushInterval    time.Duration
+	GraphitePort           string
+	MetricTemplates        string
+	ListenerDefaultShipID  string
+	ListenerBatchSize      int
+	ListenerFlushInterval  time.Duration
+	NMEAPort               string
+	NMEAShipID             string
+	AISPort                string
+	CompatShipTag          string
+	SparkplugBroker        string
+	SparkplugClientID      string
+	SparkplugUsername      string
+	SparkplugPassword      string
+	SparkplugTopic         string
+	SparkplugRebirth       bool
+	TimestampMaxFuture     time.Duration
+	TimestampMaxAge        time.Duration
+	WebhookTimeout         time.Duration
+	WebhookMaxAttempts     int
+	WebhookRetryBase       time.Duration
+	AlertEvalInterval      time.Duration
+	AlertLookback          time.Duration
+	HeartbeatInterval      time.Duration
+	HeartbeatExpected      time.Duration
+	GeofenceRefresh        time.Duration
+	DerivedRefresh         time.Duration
+	DerivedMaxHold         time.Duration
+	TransformRefresh       time.Duration
+	ValidationRefresh      time.Duration
+	ShipIDPattern          string
+	CompressionRefresh     time.Duration
+	AnomalyRefresh         time.Duration
+	SinkRefresh            time.Duration
+	SinkTimeout            time.Duration
+	SinkMaxAttempts        int
+	SinkRetryBase          time.Duration
+	SinkQueueMax           int
 }
 
 var AppConfig *Config
@@ -54,12 +107,126 @@ func LoadConfig() error {
 		WorkerBatchSize:    getEnvAsInt("WORKER_BATCH_SIZE", 1000),
 		WorkerPollInterval: time.Duration(getEnvAsInt("WORKER_POLL_INTERVAL_MS", 1000)) * time.Millisecond,
 		APP_URL: 			getEnv("APP_URL", "http://localhost:8001"),
+		SyncIngestTimeout:  time.Duration(getEnvAsInt("SYNC_INGEST_TIMEOUT_MS", 10000)) * time.Millisecond,
+		// Half of go-redis' default pool of 10 connections per CPU.
+		SyncIngestMaxWaiters: getEnvAsInt("SYNC_INGEST_MAX_WAITERS", 5*runtime.GOMAXPROCS(0)),
+		NDJSONChunkSize:    getEnvAsInt("NDJSON_CHUNK_SIZE", 5000),
+		NDJSONMaxBodyBytes: int64(getEnvAsInt("NDJSON_MAX_BODY_MB", 4096)) << 20,
+		NDJSONMaxLineBytes: getEnvAsInt("NDJSON_MAX_LINE_KB", 1024) << 10,
+		CSVImportChunkSize: getEnvAsInt("CSV_IMPORT_CHUNK_SIZE", 5000),
+		CSVImportMaxBodyBytes: int64(getEnvAsInt("CSV_IMPORT_MAX_MB", 1024)) << 20,
+		CSVImportBackpressureThreshold: int64(getEnvAsInt("CSV_IMPORT_BACKPRESSURE_THRESHOLD", 50000)),
+		MaxDecompressedBodyBytes: int64(getEnvAsInt("MAX_DECOMPRESSED_BODY_MB", 64)) << 20,
+		GRPCPort:           getEnv("GRPC_PORT", ""),
+		GRPCStreamChunkSize: getEnvAsInt("GRPC_STREAM_CHUNK_SIZE", 5000),
+		WSBackpressureThreshold: int64(getEnvAsInt("WS_BACKPRESSURE_THRESHOLD", 100000)),
+		StatsDPort:            getEnv("STATSD_PORT", ""),
+		StatsDFlushInterval:   time.Duration(getEnvAsInt("STATSD_FLUSH_INTERVAL_MS", 10000)) * time.Millisecond,
+		GraphitePort:          getEnv("GRAPHITE_PORT", ""),
+		MetricTemplates:       getEnv("METRIC_TEMPLATES", "ship.cargo*"),
+		ListenerDefaultShipID: getEnv("LISTENER_DEFAULT_SHIP_ID", ""),
+		ListenerBatchSize:     getEnvAsInt("LISTENER_BATCH_SIZE", 1000),
+		ListenerFlushInterval: time.Duration(getEnvAsInt("LISTENER_FLUSH_INTERVAL_MS", 1000)) * time.Millisecond,
+		NMEAPort:              getEnv("NMEA_PORT", ""),
+		NMEAShipID:            getEnv("NMEA_SHIP_ID", ""),
+		AISPort:               getEnv("AIS_PORT", ""),
+		CompatShipTag:         getEnv("COMPAT_SHIP_TAG", "ship"),
+		SparkplugBroker:       getEnv("SPARKPLUG_MQTT_BROKER", ""),
+		SparkplugClientID:     getEnv("SPARKPLUG_MQTT_CLIENT_ID", "harbor-ingest"),
+		SparkplugUsername:     getEnv("SPARKPLUG_MQTT_USERNAME", ""),
+		SparkplugPassword:     getEnv("SPARKPLUG_MQTT_PASSWORD", ""),
+		SparkplugTopic:        getEnv("SPARKPLUG_TOPIC", "spBv1.0/#"),
+		SparkplugRebirth:      getEnv("SPARKPLUG_REBIRTH", "true") == "true",
+		TimestampMaxFuture:    time.Duration(getEnvAsInt("TIMESTAMP_MAX_FUTURE_SEC", 300)) * time.Second,
+		TimestampMaxAge:       time.Duration(getEnvAsInt("TIMESTAMP_MAX_AGE_DAYS", 0)) * 24 * time.Hour,
+		WebhookTimeout:        time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT_SEC", 10)) * time.Second,
+		WebhookMaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookRetryBase:      time.Duration(getEnvAsInt("WEBHOOK_RETRY_BASE_SEC", 10)) * time.Second,
+		AlertEvalInterval:     time.Duration(getEnvAsInt("ALERT_EVAL_INTERVAL_SEC", 30)) * time.Second,
+		AlertLookback:         time.Duration(getEnvAsInt("ALERT_LOOKBACK_MIN", 15)) * time.Minute,
+		HeartbeatInterval:     time.Duration(getEnvAsInt("HEARTBEAT_CHECK_INTERVAL_SEC", 30)) * time.Second,
+		HeartbeatExpected:     time.Duration(getEnvAsInt("HEARTBEAT_DEFAULT_INTERVAL_SEC", 600)) * time.Second,
+		GeofenceRefresh:       time.Duration(getEnvAsInt("GEOFENCE_REFRESH_SEC", 30)) * time.Second,
+		DerivedRefresh:        time.Duration(getEnvAsInt("DERIVED_REFRESH_SEC", 30)) * time.Second,
+		DerivedMaxHold:        time.Duration(getEnvAsInt("DERIVED_MAX_HOLD_SEC", 300)) * time.Second,
+		TransformRefresh:      time.Duration(getEnvAsInt("TRANSFORM_REFRESH_SEC", 30)) * time.Second,
+		ValidationRefresh:     time.Duration(getEnvAsInt("VALIDATION_REFRESH_SEC", 30)) * time.Second,
+		ShipIDPattern:         getEnv("SHIP_ID_PATTERN", ""),
+		CompressionRefresh:    time.Duration(getEnvAsInt("COMPRESSION_REFRESH_SEC", 30)) * time.Second,
+		AnomalyRefresh:        time.Duration(getEnvAsInt("ANOMALY_REFRESH_SEC", 30)) * time.Second,
+		SinkRefresh:           time.Duration(getEnvAsInt("SINK_REFRESH_SEC", 30)) * time.Second,
+		SinkTimeout:           time.Duration(getEnvAsInt("SINK_TIMEOUT_SEC", 10)) * time.Second,
+		SinkMaxAttempts:       getEnvAsInt("SINK_MAX_ATTEMPTS", 8),
+		SinkRetryBase:         time.Duration(getEnvAsInt("SINK_RETRY_BASE_SEC", 5)) * time.Second,
+		SinkQueueMax:          getEnvAsInt("SINK_QUEUE_MAX", 10000),
 
 	}
+	if err := AppConfig.validate(); err != nil {
+		return err
+	}
 	log.Println("[Config] Configuration loaded successfully.")
 	return nil
 }
 
+// validate rejects settings that would otherwise fail at runtime, such as
+// non-positive sizes passed to make() or intervals passed to time.NewTicker,
+// or that would block forever, like a zero BLPOP timeout.
+func (c *Config) validate() error {
+	sizes := []struct {
+		name  string
+		value int
+	}{
+		{"NDJSON_CHUNK_SIZE", c.NDJSONChunkSize},
+		{"NDJSON_MAX_LINE_KB", c.NDJSONMaxLineBytes >> 10},
+		{"CSV_IMPORT_CHUNK_SIZE", c.CSVImportChunkSize},
+		{"GRPC_STREAM_CHUNK_SIZE", c.GRPCStreamChunkSize},
+		{"LISTENER_BATCH_SIZE", c.ListenerBatchSize},
+		{"SYNC_INGEST_MAX_WAITERS", c.SyncIngestMaxWaiters},
+		{"WEBHOOK_MAX_ATTEMPTS", c.WebhookMaxAttempts},
+		{"SINK_MAX_ATTEMPTS", c.SinkMaxAttempts},
+	}
+	for _, s := range sizes {
+		if s.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", s.name, s.value)
+		}
+	}
+
+	// These intervals drive tickers, which panic on non-positive durations,
+	// or bound waits that a zero duration would make unbounded.
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SYNC_INGEST_TIMEOUT_MS", c.SyncIngestTimeout},
+		{"WEBHOOK_TIMEOUT_SEC", c.WebhookTimeout},
+		{"WEBHOOK_RETRY_BASE_SEC", c.WebhookRetryBase},
+		{"SINK_TIMEOUT_SEC", c.SinkTimeout},
+		{"SINK_RETRY_BASE_SEC", c.SinkRetryBase},
+		{"STATSD_FLUSH_INTERVAL_MS", c.StatsDFlushInterval},
+		{"LISTENER_FLUSH_INTERVAL_MS", c.ListenerFlushInterval},
+		{"ALERT_EVAL_INTERVAL_SEC", c.AlertEvalInterval},
+		{"HEARTBEAT_CHECK_INTERVAL_SEC", c.HeartbeatInterval},
+		{"GEOFENCE_REFRESH_SEC", c.GeofenceRefresh},
+		{"DERIVED_REFRESH_SEC", c.DerivedRefresh},
+		{"TRANSFORM_REFRESH_SEC", c.TransformRefresh},
+		{"VALIDATION_REFRESH_SEC", c.ValidationRefresh},
+		{"COMPRESSION_REFRESH_SEC", c.CompressionRefresh},
+		{"ANOMALY_REFRESH_SEC", c.AnomalyRefresh},
+		{"SINK_REFRESH_SEC", c.SinkRefresh},
+	}
+	for _, i := range intervals {
+		if i.value <= 0 {
+			return fmt.Errorf("%s must be positive", i.name)
+		}
+	}
+
+	// Zero disables the limit.
+	if c.SinkQueueMax < 0 {
+		return fmt.Errorf("SINK_QUEUE_MAX must not be negative, got %d", c.SinkQueueMax)
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
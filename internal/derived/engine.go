@@ -0,0 +1,187 @@
+package derived
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+)
+
+// states holds the state of each rule per ship; it expires for ships that
+// stopped reporting.
+var states = ruleset.NewStateStore[stateRef, seriesState]("Derived", "_derived:", 7*24*time.Hour,
+	func(ref stateRef) ruleset.StateKey {
+		return ruleset.StateKey{RuleID: ref.rule.ID, Series: ruleset.Series(ref.shipID)}
+	})
+
+// held is the latest value of an input cargo series.
+type held struct {
+	Value float64   `json:"v"`
+	Time  time.Time `json:"t"`
+}
+
+// seriesState is the state of one rule for one ship: the latest value of each
+// input, the state of its stateful functions and the last time evaluated.
+type seriesState struct {
+	Inputs   map[string]held `json:"inputs"`
+	Slots    []slotState     `json:"slots,omitempty"`
+	LastTime time.Time       `json:"last_time"`
+}
+
+// Engine evaluates the enabled rules. Rules are cached in memory and reloaded
+// periodically; series state lives in Redis so it survives restarts.
+type Engine struct {
+	*ruleset.Cache[[]Rule]
+	maxHold time.Duration
+}
+
+// NewEngine creates an engine without rules; call Refresh to load them.
+// maxHold is how long an input's latest value is used for (0 is forever).
+func NewEngine(maxHold time.Duration) *Engine {
+	return &Engine{
+		Cache: ruleset.NewCache("Derived", "rules", func(ctx context.Context) ([]Rule, error) {
+			return ListRules(ctx, true)
+		}),
+		maxHold: maxHold,
+	}
+}
+
+type stateRef struct {
+	rule   *Rule
+	shipID string
+}
+
+// Derive computes the derived points of a batch. The series state it advanced
+// is only stored by calling save, which should happen once the batch is
+// committed so that a retried batch derives the same points again. Batches of
+// the same ship must not be derived concurrently, or one would advance the
+// state from a copy the other has already advanced.
+//
+// A rule is evaluated at every timestamp where one of its inputs has a point,
+// using the latest value of the other inputs if it is at most maxHold old.
+// Rules run in ID order and can use the results of earlier rules.
+func (e *Engine) Derive(ctx context.Context, batch []general.SensorData) ([]general.SensorData, func(context.Context)) {
+	rules := e.Current()
+	noop := func(context.Context) {}
+	if len(rules) == 0 {
+		return nil, noop
+	}
+
+	byShip := make(map[string][]general.SensorData)
+	for _, d := range batch {
+		if d.Value != nil {
+			byShip[d.ShipID] = append(byShip[d.ShipID], d)
+		}
+	}
+
+	var refs []stateRef
+	for shipID := range byShip {
+		for i := range rules {
+			if rules[i].expr != nil && rules[i].Selects(shipID) {
+				refs = append(refs, stateRef{&rules[i], shipID})
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil, noop
+	}
+	loaded, err := states.Load(ctx, refs)
+	if err != nil {
+		log.Printf("[Derived] Failed to load series state, skipping derived metrics: %v", err)
+		return nil, noop
+	}
+
+	var derived []general.SensorData
+	changed := make(map[stateRef]*seriesState)
+	for shipID, points := range byShip {
+		for i := range rules {
+			rule := &rules[i]
+			ref := stateRef{rule, shipID}
+			st, ok := loaded[ref]
+			if !ok {
+				continue
+			}
+			out, advanced := e.evaluate(rule, shipID, points, st)
+			if advanced {
+				changed[ref] = st
+			}
+			derived = append(derived, out...)
+			points = append(points, out...)
+		}
+	}
+
+	return derived, func(ctx context.Context) {
+		if err := states.Save(ctx, changed); err != nil {
+			log.Printf("[Derived] Failed to save series state: %v", err)
+		}
+	}
+}
+
+// evaluate runs one rule over the points of one ship, reporting whether its
+// state advanced.
+func (e *Engine) evaluate(rule *Rule, shipID string, points []general.SensorData, st *seriesState) ([]general.SensorData, bool) {
+	inputs := make(map[string]bool, len(rule.Inputs))
+	for _, id := range rule.Inputs {
+		inputs[id] = true
+	}
+	var relevant []general.SensorData
+	for _, d := range points {
+		if inputs[d.CargoID] {
+			relevant = append(relevant, d)
+		}
+	}
+	if len(relevant) == 0 {
+		return nil, false
+	}
+	sort.SliceStable(relevant, func(i, j int) bool { return relevant[i].Time.Before(relevant[j].Time) })
+
+	if st.Inputs == nil {
+		st.Inputs = make(map[string]held)
+	}
+	if len(st.Slots) != rule.expr.slots {
+		st.Slots = make([]slotState, rule.expr.slots)
+	}
+
+	var out []general.SensorData
+	advanced := false
+	for i := 0; i < len(relevant); {
+		t := relevant[i].Time
+		j := i
+		for j < len(relevant) && relevant[j].Time.Equal(t) {
+			j++
+		}
+		group := relevant[i:j]
+		i = j
+		if !t.After(st.LastTime) {
+			continue // Already evaluated, or arrived out of order.
+		}
+		for _, d := range group {
+			st.Inputs[d.CargoID] = held{Value: *d.Value, Time: t}
+		}
+		st.LastTime = t
+		advanced = true
+
+		values := make(map[string]float64, len(rule.Inputs))
+		for _, id := range rule.Inputs {
+			h, ok := st.Inputs[id]
+			if !ok || (e.maxHold > 0 && t.Sub(h.Time) > e.maxHold) {
+				break
+			}
+			values[id] = h.Value
+		}
+		if len(values) < len(rule.Inputs) {
+			continue
+		}
+
+		v := rule.expr.eval(&env{values: values, time: float64(t.UnixNano()) / 1e9, slots: st.Slots})
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		out = append(out, general.SensorData{Time: t, ShipID: shipID, CargoID: rule.CargoID, Value: &v})
+	}
+	return out, advanced
+}
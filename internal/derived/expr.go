@@ -0,0 +1,443 @@
+package derived
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxWindow bounds the sample count of avg().
+const maxWindow = 1000
+
+// TimeIdent is the identifier for the point time in seconds, e.g. in
+// delta(fuel_total) / delta(t). A cargo ID named "t" must be quoted.
+const TimeIdent = "t"
+
+// Expression is a compiled derived-metric expression.
+type Expression struct {
+	root   node
+	inputs []string
+	slots  int
+}
+
+// Inputs returns the cargo IDs the expression reads, sorted.
+func (e *Expression) Inputs() []string {
+	return e.inputs
+}
+
+// slotState is the state of one stateful function call for one ship.
+type slotState struct {
+	Prev     *float64  `json:"p,omitempty"`
+	PrevTime float64   `json:"pt,omitempty"`
+	Window   []float64 `json:"w,omitempty"`
+}
+
+// env holds the inputs of one evaluation and the state of its series.
+type env struct {
+	values map[string]float64
+	time   float64
+	slots  []slotState
+}
+
+// eval evaluates the expression. A NaN or infinite result (e.g. the first
+// delta of a series, or a division by zero) means there is no value.
+func (e *Expression) eval(env *env) float64 {
+	return e.root.eval(env)
+}
+
+type node interface {
+	eval(env *env) float64
+}
+
+type numNode float64
+
+func (n numNode) eval(*env) float64 { return float64(n) }
+
+type refNode string
+
+func (n refNode) eval(env *env) float64 {
+	if v, ok := env.values[string(n)]; ok {
+		return v
+	}
+	return math.NaN()
+}
+
+type timeNode struct{}
+
+func (timeNode) eval(env *env) float64 { return env.time }
+
+type negNode struct{ x node }
+
+func (n negNode) eval(env *env) float64 { return -n.x.eval(env) }
+
+type binNode struct {
+	op   byte
+	l, r node
+}
+
+func (n binNode) eval(env *env) float64 {
+	l, r := n.l.eval(env), n.r.eval(env)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		return l / r
+	default:
+		return math.Pow(l, r)
+	}
+}
+
+// callNode is a function call. Stateful functions (delta, rate, avg) keep
+// their state in env.slots[slot].
+type callNode struct {
+	fn     string
+	args   []node
+	slot   int
+	window int
+}
+
+func (n callNode) eval(env *env) float64 {
+	// Every argument is evaluated so that nested stateful calls always advance.
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.eval(env)
+	}
+
+	switch n.fn {
+	case "abs":
+		return math.Abs(args[0])
+	case "sqrt":
+		return math.Sqrt(args[0])
+	case "exp":
+		return math.Exp(args[0])
+	case "ln":
+		return math.Log(args[0])
+	case "log10":
+		return math.Log10(args[0])
+	case "pow":
+		return math.Pow(args[0], args[1])
+	case "min", "max":
+		v := args[0]
+		for _, a := range args[1:] {
+			if (n.fn == "min" && a < v) || (n.fn == "max" && a > v) {
+				v = a
+			}
+		}
+		return v
+	}
+
+	st := &env.slots[n.slot]
+	v := args[0]
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return math.NaN()
+	}
+	out := math.NaN()
+	switch n.fn {
+	case "delta":
+		if st.Prev != nil {
+			out = v - *st.Prev
+		}
+	case "rate":
+		if st.Prev != nil && env.time > st.PrevTime {
+			out = (v - *st.Prev) / (env.time - st.PrevTime)
+		}
+	case "avg":
+		st.Window = append(st.Window, v)
+		if len(st.Window) > n.window {
+			st.Window = st.Window[len(st.Window)-n.window:]
+		}
+		sum := 0.0
+		for _, w := range st.Window {
+			sum += w
+		}
+		return sum / float64(len(st.Window))
+	}
+	st.Prev, st.PrevTime = &v, env.time
+	return out
+}
+
+// functions maps function names to their argument count; -1 is variadic.
+var functions = map[string]int{
+	"abs": 1, "sqrt": 1, "exp": 1, "ln": 1, "log10": 1, "pow": 2, "min": -1, "max": -1,
+	"delta": 1, "rate": 1, "avg": 2,
+}
+
+func stateful(fn string) bool {
+	return fn == "delta" || fn == "rate" || fn == "avg"
+}
+
+// Parse compiles an expression over cargo IDs of one ship. It supports
+// numbers, + - * / ^, parentheses, cargo IDs (bare, or double-quoted if they
+// contain other characters), t for the point time in seconds, the functions
+// abs, sqrt, exp, ln, log10, pow, min and max, and the stateful functions
+// delta(x), rate(x) (per second) and avg(x, n) (moving average of the last n
+// values).
+func Parse(s string) (*Expression, error) {
+	p := &parser{src: s}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+	}
+	if len(p.inputs) == 0 {
+		return nil, fmt.Errorf("expression must reference at least one cargo ID")
+	}
+
+	inputs := make([]string, 0, len(p.inputs))
+	for id := range p.inputs {
+		inputs = append(inputs, id)
+	}
+	sort.Strings(inputs)
+	return &Expression{root: root, inputs: inputs, slots: p.slots}, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokIdent
+	tokQuoted
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src    string
+	toks   []token
+	i      int
+	inputs map[string]bool
+	slots  int
+}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/^(),", c):
+			p.toks = append(p.toks, token{tokOp, string(c), i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return fmt.Errorf("unterminated quote at position %d", i+1)
+			}
+			p.toks = append(p.toks, token{tokQuoted, s[i+1 : i+1+end], i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				(j > i && (s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			p.toks = append(p.toks, token{tokNum, s[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || s[j] >= '0' && s[j] <= '9' || unicode.IsLetter(rune(s[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, token{tokIdent, s[i:j], i})
+			i = j
+		default:
+			return fmt.Errorf("unexpected %q at position %d", c, i+1)
+		}
+	}
+	p.toks = append(p.toks, token{kind: tokEOF, text: "end of expression", pos: len(s)})
+	return nil
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	tok := p.toks[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// expr := term (('+' | '-') term)*
+func (p *parser) expr() (node, error) {
+	l, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op.kind != tokOp || (op.text != "+" && op.text != "-") {
+			return l, nil
+		}
+		p.next()
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: op.text[0], l: l, r: r}
+	}
+}
+
+// term := unary (('*' | '/') unary)*
+func (p *parser) term() (node, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op.kind != tokOp || (op.text != "*" && op.text != "/") {
+			return l, nil
+		}
+		p.next()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: op.text[0], l: l, r: r}
+	}
+}
+
+// unary := '-' unary | power
+func (p *parser) unary() (node, error) {
+	if p.accept("-") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x}, nil
+	}
+	return p.power()
+}
+
+// power := primary ('^' unary)?
+func (p *parser) power() (node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("^") {
+		return base, nil
+	}
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return binNode{op: '^', l: base, r: exp}, nil
+}
+
+// primary := number | cargo | 't' | call | '(' expr ')'
+func (p *parser) primary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNum:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos+1)
+		}
+		return numNode(v), nil
+	case tokQuoted:
+		return p.ref(tok.text, tok.pos)
+	case tokIdent:
+		if p.accept("(") {
+			return p.call(tok)
+		}
+		if tok.text == TimeIdent {
+			return timeNode{}, nil
+		}
+		return p.ref(tok.text, tok.pos)
+	case tokOp:
+		if tok.text == "(" {
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("missing ) at position %d", p.peek().pos+1)
+			}
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
+
+func (p *parser) ref(cargoID string, pos int) (node, error) {
+	if cargoID == "" || len(cargoID) > 100 {
+		return nil, fmt.Errorf("invalid cargo ID at position %d", pos+1)
+	}
+	if p.inputs == nil {
+		p.inputs = make(map[string]bool)
+	}
+	p.inputs[cargoID] = true
+	return refNode(cargoID), nil
+}
+
+func (p *parser) call(name token) (node, error) {
+	arity, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos+1)
+	}
+	var args []node
+	if !p.accept(")") {
+		for {
+			a, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("expected , or ) at position %d", p.peek().pos+1)
+			}
+		}
+	}
+	if arity < 0 && len(args) == 0 {
+		return nil, fmt.Errorf("%s() needs at least one argument at position %d", name.text, name.pos+1)
+	}
+	if arity >= 0 && len(args) != arity {
+		return nil, fmt.Errorf("%s() takes %d argument(s) at position %d", name.text, arity, name.pos+1)
+	}
+
+	n := callNode{fn: name.text, args: args, slot: -1}
+	if name.text == "avg" {
+		w, ok := args[1].(numNode)
+		if !ok || float64(w) != math.Trunc(float64(w)) || w < 1 || w > maxWindow {
+			return nil, fmt.Errorf("avg() window must be a whole number from 1 to %d", maxWindow)
+		}
+		n.window, n.args = int(w), args[:1]
+	}
+	if stateful(name.text) {
+		n.slot = p.slots
+		p.slots++
+	}
+	return n, nil
+}
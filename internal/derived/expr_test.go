@@ -0,0 +1,152 @@
+package derived
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src     string
+		inputs  []string
+		slots   int
+		wantErr bool
+	}{
+		{src: "engine_rpm", inputs: []string{"engine_rpm"}},
+		{src: "fuel_in - fuel_out", inputs: []string{"fuel_in", "fuel_out"}},
+		{src: `"temp-1" * 1.8 + 32`, inputs: []string{"temp-1"}},
+		{src: "a + a * b", inputs: []string{"a", "b"}},
+		{src: "sensor.v2 / 1e3", inputs: []string{"sensor.v2"}},
+		{src: `"t" * t`, inputs: []string{"t"}},
+		{src: "delta(fuel_total) / delta(t)", inputs: []string{"fuel_total"}, slots: 2},
+		{src: "avg(rate(odometer), 10)", inputs: []string{"odometer"}, slots: 2},
+		{src: "max(a, b, 0) + min(c)", inputs: []string{"a", "b", "c"}},
+		{src: "", wantErr: true},
+		{src: "42", wantErr: true},
+		{src: "t * 2", wantErr: true},
+		{src: "a +", wantErr: true},
+		{src: "(a", wantErr: true},
+		{src: "a)", wantErr: true},
+		{src: "a b", wantErr: true},
+		{src: `"a`, wantErr: true},
+		{src: `""`, wantErr: true},
+		{src: "a % 2", wantErr: true},
+		{src: "foo(a)", wantErr: true},
+		{src: "pow(a)", wantErr: true},
+		{src: "min()", wantErr: true},
+		{src: "avg(a, n)", wantErr: true},
+		{src: "avg(a, 0)", wantErr: true},
+		{src: "avg(a, 2.5)", wantErr: true},
+		{src: "avg(a, 1001)", wantErr: true},
+		{src: "abs(a, b)", wantErr: true},
+		{src: "1.2.3 * a", wantErr: true},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", tt.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) = %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(e.Inputs(), tt.inputs) || e.slots != tt.slots {
+			t.Errorf("Parse(%q) reads %v with %d slots, want %v with %d", tt.src, e.Inputs(), e.slots, tt.inputs, tt.slots)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	values := map[string]float64{"a": 3, "b": 4, "neg": -2, "zero": 0, "t": 7}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"a + b * 2", 11},
+		{"(a + b) * 2", 14},
+		{"a - b - 1", -2},
+		{"b / a / 2", 4.0 / 3 / 2},
+		{"-a ^ 2", -9},
+		{"(-a) ^ 2", 9},
+		{"2 ^ 3 ^ 2 * a", 1536},
+		{"a ^ -1", 1.0 / 3},
+		{"sqrt(a^2 + b^2)", 5},
+		{"abs(neg) + pow(a, 2)", 11},
+		{"min(a, b, neg) + max(a, b)", 2},
+		{"ln(exp(a)) + log10(100 * a / a)", 5},
+		{`"t" + t`, 7 + 1700000000},
+		{"1.5e2 + a", 153},
+		{"a / zero", math.Inf(1)},
+		{"sqrt(neg)", math.NaN()},
+		{"a + missing", math.NaN()},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q) = %v", tt.src, err)
+			continue
+		}
+		got := e.eval(&env{values: values, time: 1700000000, slots: make([]slotState, e.slots)})
+		if !(got == tt.want || math.IsNaN(got) && math.IsNaN(tt.want) || math.Abs(got-tt.want) < 1e-9) {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestStatefulFunctions evaluates an expression over a series, carrying its
+// slots from one point to the next like the engine does.
+func TestStatefulFunctions(t *testing.T) {
+	type step struct {
+		time, x float64
+	}
+	tests := []struct {
+		src   string
+		steps []step
+		want  []float64 // NaN where there is no value
+	}{
+		{
+			src:   "delta(x)",
+			steps: []step{{0, 10}, {1, 12}, {2, 11}},
+			want:  []float64{math.NaN(), 2, -1},
+		},
+		{
+			src:   "rate(x)",
+			steps: []step{{0, 10}, {10, 30}, {10, 40}, {15, 50}},
+			want:  []float64{math.NaN(), 2, math.NaN(), 2},
+		},
+		{
+			src:   "delta(x) / delta(t)",
+			steps: []step{{100, 5}, {104, 13}, {110, 13}},
+			want:  []float64{math.NaN(), 2, 0},
+		},
+		{
+			src:   "avg(x, 3)",
+			steps: []step{{0, 3}, {1, 6}, {2, 9}, {3, 12}, {4, 0}},
+			want:  []float64{3, 4.5, 6, 9, 7},
+		},
+		{
+			// The inner delta advances even when the outer call has no value yet.
+			src:   "delta(delta(x))",
+			steps: []step{{0, 1}, {1, 2}, {2, 4}, {3, 7}},
+			want:  []float64{math.NaN(), math.NaN(), 1, 1},
+		},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Fatalf("Parse(%q) = %v", tt.src, err)
+		}
+		slots := make([]slotState, e.slots)
+		for i, s := range tt.steps {
+			got := e.eval(&env{values: map[string]float64{"x": s.x}, time: s.time, slots: slots})
+			want := tt.want[i]
+			if !(got == want || math.IsNaN(got) && math.IsNaN(want)) {
+				t.Errorf("%s at step %d = %v, want %v", tt.src, i, got, want)
+			}
+		}
+	}
+}
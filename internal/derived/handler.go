@@ -0,0 +1,136 @@
+package derived
+
+import (
+	"net/http"
+
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ruleRequest is the body of POST and PUT /derived.
+type ruleRequest struct {
+	ShipID     string `json:"ship_id"    validate:"max=100"`
+	CargoID    string `json:"cargo_id"   validate:"required,min=1,max=100"`
+	Expression string `json:"expression" validate:"required,max=1000"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+// parseRuleRequest validates a rule body; ship_id defaults to every ship. If
+// the rule is nil the error response has already been written and the
+// returned error is the one to pass back to Fiber.
+func parseRuleRequest(c *fiber.Ctx) (*Rule, error) {
+	var req ruleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	if err := glob.Validate(req.ShipID); err != nil {
+		errs = append(errs, models.ErrorDetail{Loc: []string{"ShipID"}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+	}
+	var expr *Expression
+	if req.Expression != "" {
+		var err error
+		if expr, err = Parse(req.Expression); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{"Expression"}, Msg: err.Error(), Type: "validation_error.expression"})
+		} else {
+			for _, id := range expr.Inputs() {
+				if id == req.CargoID {
+					errs = append(errs, models.ErrorDetail{Loc: []string{"Expression"}, Msg: "Expression cannot reference its own cargo_id", Type: "validation_error.expression"})
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	return &Rule{
+		ShipID:     req.ShipID,
+		CargoID:    req.CargoID,
+		Expression: req.Expression,
+		Inputs:     expr.Inputs(),
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		expr:       expr,
+	}, nil
+}
+
+// ListDerivedMetrics returns all derived metric rules.
+func ListDerivedMetrics(c *fiber.Ctx) error {
+	rules, err := ListRules(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load derived metrics")
+	}
+	return c.JSON(rules)
+}
+
+// GetDerivedMetric returns one derived metric rule.
+func GetDerivedMetric(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid derived metric ID")
+	}
+	rule, err := GetRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load derived metric")
+	}
+	if rule == nil {
+		return fiber.NewError(http.StatusNotFound, "Derived metric not found")
+	}
+	return c.JSON(rule)
+}
+
+// CreateDerivedMetric stores a new rule. Workers pick it up within
+// DERIVED_REFRESH_SEC.
+func CreateDerivedMetric(c *fiber.Ctx) error {
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	if err := CreateRule(c.Context(), rule); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create derived metric")
+	}
+	return c.Status(http.StatusCreated).JSON(rule)
+}
+
+// UpdateDerivedMetric replaces a rule and resets the state of its series.
+func UpdateDerivedMetric(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid derived metric ID")
+	}
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	rule.ID = int64(id)
+	found, err := UpdateRule(c.Context(), rule)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update derived metric")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Derived metric not found")
+	}
+	return c.JSON(rule)
+}
+
+// DeleteDerivedMetric removes a rule and its state.
+func DeleteDerivedMetric(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid derived metric ID")
+	}
+	found, err := DeleteRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete derived metric")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Derived metric not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
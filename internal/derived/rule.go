@@ -0,0 +1,121 @@
+// Package derived computes virtual cargo series, such as a heat index or a
+// fuel rate, from expressions over other cargo IDs of the same ship. Results
+// are written to cargo_data alongside the points they were computed from.
+package derived
+
+import (
+	"context"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Rule defines the cargo series CargoID as Expression, for the ships matching
+// the ShipID glob pattern.
+type Rule struct {
+	ID         int64     `json:"id"`
+	ShipID     string    `json:"ship_id"`
+	CargoID    string    `json:"cargo_id"`
+	Expression string    `json:"expression"`
+	Inputs     []string  `json:"inputs"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	expr *Expression
+}
+
+// Selects reports whether the rule applies to a ship.
+func (r *Rule) Selects(shipID string) bool {
+	return glob.Match(r.ShipID, shipID)
+}
+
+const ruleColumns = `id, ship_id, cargo_id, expression, enabled, created_at, updated_at`
+
+func scanRule(row pgx.Row) (Rule, error) {
+	var r Rule
+	err := row.Scan(&r.ID, &r.ShipID, &r.CargoID, &r.Expression, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	if err == nil {
+		// Expressions are validated before they are stored.
+		if r.expr, _ = Parse(r.Expression); r.expr != nil {
+			r.Inputs = r.expr.Inputs()
+		}
+	}
+	return r, err
+}
+
+// ListRules returns all rules, or only the enabled ones, in ID order.
+func ListRules(ctx context.Context, enabledOnly bool) ([]Rule, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+ruleColumns+` FROM derived_metrics WHERE enabled OR NOT $1 ORDER BY id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule loads one rule; a nil rule means it does not exist.
+func GetRule(ctx context.Context, id int64) (*Rule, error) {
+	r, err := scanRule(db.Pool.QueryRow(ctx, `SELECT `+ruleColumns+` FROM derived_metrics WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRule stores a new rule and fills in its ID and timestamps.
+func CreateRule(ctx context.Context, r *Rule) error {
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO derived_metrics (ship_id, cargo_id, expression, enabled)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		r.ShipID, r.CargoID, r.Expression, r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// UpdateRule replaces a rule, reporting whether it existed. The state of its
+// series is reset.
+func UpdateRule(ctx context.Context, r *Rule) (bool, error) {
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE derived_metrics
+		SET ship_id = $2, cargo_id = $3, expression = $4, enabled = $5, updated_at = now()
+		WHERE id = $1
+		RETURNING created_at, updated_at`,
+		r.ID, r.ShipID, r.CargoID, r.Expression, r.Enabled,
+	).Scan(&r.CreatedAt, &r.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, states.Reset(ctx, r.ID)
+}
+
+// DeleteRule removes a rule and its state, reporting whether it existed.
+// Values it already wrote are kept.
+func DeleteRule(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM derived_metrics WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, states.Reset(ctx, id)
+}
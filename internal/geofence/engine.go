@@ -0,0 +1,318 @@
+package geofence
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+	"go-ingest-service/internal/webhook"
+)
+
+// Cargo IDs that make up a position, as written by the NMEA and AIS decoders.
+const (
+	LatitudeCargoID  = "latitude"
+	LongitudeCargoID = "longitude"
+)
+
+// Geofence event kinds, as stored in geofence_events.
+const (
+	Enter = "enter"
+	Exit  = "exit"
+)
+
+// Webhook event types sent for zones with notify set.
+const (
+	EventEnter = "geofence.enter"
+	EventExit  = "geofence.exit"
+)
+
+// Event is a ship entering or leaving a zone; it is also the data of the
+// webhook events.
+type Event struct {
+	ID        int64     `json:"id"`
+	ZoneID    int64     `json:"zone_id"`
+	ZoneName  string    `json:"zone_name"`
+	ZoneKind  string    `json:"zone_kind"`
+	ShipID    string    `json:"ship_id"`
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+}
+
+// fix is a position of a ship at one timestamp.
+type fix struct {
+	time     time.Time
+	lat, lon float64
+}
+
+// positionKey identifies a timestamp of a ship. Times are kept as Unix
+// nanoseconds so that equal instants in different locations match.
+type positionKey struct {
+	shipID string
+	nanos  int64
+}
+
+// halfFix collects the latitude and longitude points of one timestamp.
+type halfFix struct {
+	time     time.Time
+	lat, lon *float64
+}
+
+// Engine checks ship positions against the enabled zones. Zones are cached in
+// memory and reloaded periodically.
+type Engine struct {
+	*ruleset.Cache[[]Zone]
+}
+
+// NewEngine creates an engine without zones; call Refresh to load them.
+func NewEngine() *Engine {
+	return &Engine{ruleset.NewCache("Geofence", "zones", func(ctx context.Context) ([]Zone, error) {
+		return ListZones(ctx, true)
+	})}
+}
+
+// ProcessBatch pairs the latitude and longitude points of a committed batch
+// per ship and timestamp and records the zones each ship entered or left.
+func (e *Engine) ProcessBatch(ctx context.Context, batch []general.SensorData) {
+	zones := e.Current()
+	if len(zones) == 0 {
+		return
+	}
+
+	fixes, err := pairPositions(ctx, batch)
+	if err != nil {
+		log.Printf("[Geofence] Failed to pair positions: %v", err)
+	}
+	for shipID, shipFixes := range fixes {
+		var selected []*Zone
+		for i := range zones {
+			if zones[i].Selects(shipID) {
+				selected = append(selected, &zones[i])
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		sort.Slice(shipFixes, func(i, j int) bool { return shipFixes[i].time.Before(shipFixes[j].time) })
+		if err := apply(ctx, shipID, selected, shipFixes); err != nil {
+			log.Printf("[Geofence] Failed to update zones for %s: %v", shipID, err)
+		}
+	}
+}
+
+// pairPositions returns the complete positions in a batch per ship. A
+// latitude without its longitude (or the reverse) is completed from
+// cargo_data, as the two may have been sent in separate requests.
+func pairPositions(ctx context.Context, batch []general.SensorData) (map[string][]fix, error) {
+	halves := make(map[positionKey]*halfFix)
+	for i := range batch {
+		d := &batch[i]
+		if d.Value == nil || (d.CargoID != LatitudeCargoID && d.CargoID != LongitudeCargoID) {
+			continue
+		}
+		key := positionKey{d.ShipID, d.Time.UnixNano()}
+		h := halves[key]
+		if h == nil {
+			h = &halfFix{time: d.Time}
+			halves[key] = h
+		}
+		if d.CargoID == LatitudeCargoID {
+			h.lat = d.Value
+		} else {
+			h.lon = d.Value
+		}
+	}
+
+	var ships, cargos []string
+	var times []time.Time
+	for key, h := range halves {
+		if h.lat == nil || h.lon == nil {
+			missing := LatitudeCargoID
+			if h.lon == nil {
+				missing = LongitudeCargoID
+			}
+			ships = append(ships, key.shipID)
+			times = append(times, h.time)
+			cargos = append(cargos, missing)
+		}
+	}
+
+	var lookupErr error
+	if len(ships) > 0 {
+		lookupErr = completeHalves(ctx, halves, ships, times, cargos)
+	}
+
+	fixes := make(map[string][]fix)
+	for key, h := range halves {
+		if h.lat == nil || h.lon == nil || !validLatLon(*h.lat, *h.lon) {
+			continue
+		}
+		fixes[key.shipID] = append(fixes[key.shipID], fix{time: h.time, lat: *h.lat, lon: *h.lon})
+	}
+	return fixes, lookupErr
+}
+
+func completeHalves(ctx context.Context, halves map[positionKey]*halfFix, ships []string, times []time.Time, cargos []string) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT c.ship_id, c.time, c.cargo_id, c.value
+		FROM cargo_data c
+		JOIN unnest($1::text[], $2::timestamptz[], $3::text[]) AS m (ship_id, time, cargo_id)
+		  ON c.ship_id = m.ship_id AND c.time = m.time AND c.cargo_id = m.cargo_id`,
+		ships, times, cargos)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var shipID, cargoID string
+		var t time.Time
+		var value *float64
+		if err := rows.Scan(&shipID, &t, &cargoID, &value); err != nil {
+			return err
+		}
+		h := halves[positionKey{shipID, t.UnixNano()}]
+		if h == nil || value == nil {
+			continue
+		}
+		if cargoID == LatitudeCargoID {
+			h.lat = value
+		} else {
+			h.lon = value
+		}
+	}
+	return rows.Err()
+}
+
+// apply advances the inside/outside state of one ship for the selected zones
+// with its fixes (in time order). The state rows are locked for the update so
+// concurrent workers cannot both record the same crossing; notifications are
+// published only after the commit. A ship's first fix in a zone only sets its
+// state, as there is no crossing to report.
+func apply(ctx context.Context, shipID string, zones []*Zone, fixes []fix) error {
+	ids := make([]int64, len(zones))
+	for i, z := range zones {
+		ids[i] = z.ID
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO geofence_states (zone_id, ship_id)
+		SELECT id, $2 FROM geofence_zones WHERE id = ANY($1)
+		ON CONFLICT DO NOTHING`, ids, shipID); err != nil {
+		return err
+	}
+
+	type state struct {
+		inside   *bool
+		since    *time.Time
+		lastTime *time.Time
+		changed  bool
+	}
+	states := make(map[int64]*state)
+	rows, err := tx.Query(ctx, `
+		SELECT zone_id, inside, since, last_time FROM geofence_states
+		WHERE ship_id = $1 AND zone_id = ANY($2)
+		ORDER BY zone_id
+		FOR UPDATE`, shipID, ids)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		st := &state{}
+		if err := rows.Scan(&id, &st.inside, &st.since, &st.lastTime); err != nil {
+			rows.Close()
+			return err
+		}
+		states[id] = st
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	var events []Event
+	for _, z := range zones {
+		st := states[z.ID]
+		if st == nil {
+			continue // The zone was deleted meanwhile.
+		}
+		for _, f := range fixes {
+			if st.lastTime != nil && !f.time.After(*st.lastTime) {
+				continue // Already evaluated, or arrived out of order.
+			}
+			inside := z.Contains(f.lat, f.lon)
+			if st.inside == nil || *st.inside != inside {
+				if st.inside != nil {
+					kind := Exit
+					if inside {
+						kind = Enter
+					}
+					events = append(events, Event{
+						ZoneID: z.ID, ZoneName: z.Name, ZoneKind: z.Kind, ShipID: shipID,
+						Event: kind, Time: f.time, Latitude: f.lat, Longitude: f.lon,
+					})
+				}
+				since := f.time
+				st.since = &since
+			}
+			t := f.time
+			st.inside, st.lastTime, st.changed = &inside, &t, true
+		}
+	}
+
+	for id, st := range states {
+		if !st.changed {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE geofence_states SET inside = $3, since = $4, last_time = $5, updated_at = now()
+			WHERE zone_id = $1 AND ship_id = $2`,
+			id, shipID, st.inside, st.since, st.lastTime); err != nil {
+			return err
+		}
+	}
+	for i := range events {
+		ev := &events[i]
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO geofence_events (zone_id, zone_name, zone_kind, ship_id, event, time, latitude, longitude)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id`,
+			ev.ZoneID, ev.ZoneName, ev.ZoneKind, ev.ShipID, ev.Event, ev.Time, ev.Latitude, ev.Longitude,
+		).Scan(&ev.ID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	notify := make(map[int64]bool, len(zones))
+	for _, z := range zones {
+		notify[z.ID] = z.Notify
+	}
+	for _, ev := range events {
+		log.Printf("[Geofence] %s %s zone %d (%s) at %s", ev.ShipID, ev.Event, ev.ZoneID, ev.ZoneName, ev.Time.Format(time.RFC3339))
+		if !notify[ev.ZoneID] {
+			continue
+		}
+		eventType := EventEnter
+		if ev.Event == Exit {
+			eventType = EventExit
+		}
+		if err := webhook.Publish(ctx, eventType, ev); err != nil {
+			log.Printf("[Geofence] Failed to queue %s notification for zone %d: %v", eventType, ev.ZoneID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// earthRadius is the mean Earth radius in metres.
+const earthRadius = 6371008.8
+
+// shape is a parsed zone geometry.
+type shape interface {
+	contains(lat, lon float64) bool
+}
+
+// ring is a closed GeoJSON linear ring of [longitude, latitude] positions.
+type ring [][2]float64
+
+// polygon is an outer ring followed by its holes.
+type polygon []ring
+
+// contains uses the even-odd rule over all rings, so points in a hole are
+// outside. Coordinates are treated as planar, which is accurate enough for
+// zones that do not cross the antimeridian.
+func (p polygon) contains(lat, lon float64) bool {
+	inside := false
+	for _, r := range p {
+		for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+			xi, yi := r[i][0], r[i][1]
+			xj, yj := r[j][0], r[j][1]
+			if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+type multiPolygon []polygon
+
+func (m multiPolygon) contains(lat, lon float64) bool {
+	for _, p := range m {
+		if p.contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// circle is a GeoJSON Point with a radius.
+type circle struct {
+	lat, lon, radius float64
+}
+
+func (c circle) contains(lat, lon float64) bool {
+	return distance(c.lat, c.lon, lat, lon) <= c.radius
+}
+
+// distance returns the great-circle distance in metres.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1, φ2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dφ := φ2 - φ1
+	dλ := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dφ/2)*math.Sin(dφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(dλ/2)*math.Sin(dλ/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// parseGeometry parses a GeoJSON Polygon, MultiPolygon or Point geometry. A
+// Point is a circle and needs a positive radius in metres.
+func parseGeometry(raw json.RawMessage, radius *float64) (shape, error) {
+	var g struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, errors.New("geometry must be a GeoJSON object")
+	}
+	if len(g.Coordinates) == 0 {
+		return nil, errors.New("geometry has no coordinates")
+	}
+
+	switch g.Type {
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+			return nil, errors.New("Point coordinates must be a position")
+		}
+		p, err := position(pos)
+		if err != nil {
+			return nil, err
+		}
+		if radius == nil || *radius <= 0 {
+			return nil, errors.New("a Point zone needs a positive radius_m")
+		}
+		return circle{lat: p[1], lon: p[0], radius: *radius}, nil
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, errors.New("Polygon coordinates must be an array of rings")
+		}
+		return parsePolygon(coords)
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, errors.New("MultiPolygon coordinates must be an array of polygons")
+		}
+		if len(coords) == 0 {
+			return nil, errors.New("MultiPolygon has no polygons")
+		}
+		m := make(multiPolygon, 0, len(coords))
+		for i, c := range coords {
+			p, err := parsePolygon(c)
+			if err != nil {
+				return nil, fmt.Errorf("polygon %d: %w", i, err)
+			}
+			m = append(m, p)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q (use Polygon, MultiPolygon or Point)", g.Type)
+	}
+}
+
+func parsePolygon(coords [][][]float64) (polygon, error) {
+	if len(coords) == 0 {
+		return nil, errors.New("polygon has no rings")
+	}
+	p := make(polygon, 0, len(coords))
+	for i, c := range coords {
+		if len(c) < 4 {
+			return nil, fmt.Errorf("ring %d needs at least 4 positions", i)
+		}
+		r := make(ring, 0, len(c))
+		for _, pos := range c {
+			pt, err := position(pos)
+			if err != nil {
+				return nil, fmt.Errorf("ring %d: %w", i, err)
+			}
+			r = append(r, pt)
+		}
+		if r[0] != r[len(r)-1] {
+			return nil, fmt.Errorf("ring %d is not closed", i)
+		}
+		p = append(p, r)
+	}
+	return p, nil
+}
+
+func position(pos []float64) ([2]float64, error) {
+	if len(pos) < 2 {
+		return [2]float64{}, errors.New("positions need a longitude and a latitude")
+	}
+	if !validLatLon(pos[1], pos[0]) {
+		return [2]float64{}, fmt.Errorf("position [%v, %v] is out of range", pos[0], pos[1])
+	}
+	return [2]float64{pos[0], pos[1]}, nil
+}
+
+func validLatLon(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
@@ -0,0 +1,110 @@
+package geofence
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// harbour is a square from 4°E to 5°E and 51°N to 52°N with a square hole
+// from 4.4°E to 4.6°E and 51.4°N to 51.6°N.
+const harbour = `{"type": "Polygon", "coordinates": [
+	[[4, 51], [5, 51], [5, 52], [4, 52], [4, 51]],
+	[[4.4, 51.4], [4.6, 51.4], [4.6, 51.6], [4.4, 51.6], [4.4, 51.4]]
+]}`
+
+func TestContains(t *testing.T) {
+	radius := 1000.0
+	tests := []struct {
+		name     string
+		geometry string
+		lat, lon float64
+		want     bool
+	}{
+		{"inside polygon", harbour, 51.2, 4.2, true},
+		{"inside hole", harbour, 51.5, 4.5, false},
+		{"between hole and edge", harbour, 51.5, 4.8, true},
+		{"west of polygon", harbour, 51.5, 3.9, false},
+		{"north of polygon", harbour, 52.1, 4.5, false},
+		{"ring order does not matter", `{"type": "Polygon", "coordinates": [[[4, 51], [4, 52], [5, 52], [5, 51], [4, 51]]]}`, 51.5, 4.5, true},
+		{
+			"inside the notch of a concave polygon",
+			`{"type": "Polygon", "coordinates": [[[0, 0], [4, 0], [4, 4], [2, 2], [0, 4], [0, 0]]]}`,
+			3.5, 2, false,
+		},
+		{
+			"below the notch of a concave polygon",
+			`{"type": "Polygon", "coordinates": [[[0, 0], [4, 0], [4, 4], [2, 2], [0, 4], [0, 0]]]}`,
+			1, 2, true,
+		},
+		{
+			"second polygon of a multipolygon",
+			`{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]], [[[10, 10], [11, 10], [11, 11], [10, 11], [10, 10]]]]}`,
+			10.5, 10.5, true,
+		},
+		{
+			"between the polygons of a multipolygon",
+			`{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]], [[[10, 10], [11, 10], [11, 11], [10, 11], [10, 10]]]]}`,
+			5, 5, false,
+		},
+		{"circle centre", `{"type": "Point", "coordinates": [4.5, 51.5]}`, 51.5, 4.5, true},
+		// 0.008° of latitude is about 890 m, and 0.01° about 1112 m.
+		{"inside circle", `{"type": "Point", "coordinates": [4.5, 51.5]}`, 51.508, 4.5, true},
+		{"outside circle", `{"type": "Point", "coordinates": [4.5, 51.5]}`, 51.51, 4.5, false},
+	}
+	for _, tt := range tests {
+		s, err := parseGeometry(json.RawMessage(tt.geometry), &radius)
+		if err != nil {
+			t.Fatalf("%s: parseGeometry = %v", tt.name, err)
+		}
+		if got := s.contains(tt.lat, tt.lon); got != tt.want {
+			t.Errorf("%s: contains(%v, %v) = %v, want %v", tt.name, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		lat1, lon1, lat2, lon2 float64
+		want                   float64 // metres
+	}{
+		{51.5, 4.5, 51.5, 4.5, 0},
+		{0, 0, 1, 0, 111195},
+		{0, 0, 0, 1, 111195},
+		{60, 0, 60, 1, 55597},
+		{0, 179.5, 0, -179.5, 111195},
+		{90, 0, -90, 0, math.Pi * earthRadius},
+	}
+	for _, tt := range tests {
+		got := distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if math.Abs(got-tt.want) > 1 {
+			t.Errorf("distance(%v, %v, %v, %v) = %.0f, want %.0f", tt.lat1, tt.lon1, tt.lat2, tt.lon2, got, tt.want)
+		}
+	}
+}
+
+func TestParseGeometryErrors(t *testing.T) {
+	zero := 0.0
+	tests := []struct {
+		geometry string
+		radius   *float64
+	}{
+		{`[]`, nil},
+		{`{"type": "Polygon"}`, nil},
+		{`{"type": "LineString", "coordinates": [[0, 0], [1, 1]]}`, nil},
+		{`{"type": "Polygon", "coordinates": []}`, nil},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 0]]]}`, nil},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}`, nil},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 91], [0, 0]]]}`, nil},
+		{`{"type": "Polygon", "coordinates": [[[0], [1, 0], [1, 1], [0]]]}`, nil},
+		{`{"type": "MultiPolygon", "coordinates": []}`, nil},
+		{`{"type": "Point", "coordinates": [4.5, 51.5]}`, nil},
+		{`{"type": "Point", "coordinates": [4.5, 51.5]}`, &zero},
+		{`{"type": "Point", "coordinates": [181, 51.5]}`, nil},
+	}
+	for _, tt := range tests {
+		if _, err := parseGeometry(json.RawMessage(tt.geometry), tt.radius); err == nil {
+			t.Errorf("parseGeometry(%s) succeeded, want error", tt.geometry)
+		}
+	}
+}
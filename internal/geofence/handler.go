@@ -0,0 +1,186 @@
+package geofence
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// zoneRequest is the body of POST and PUT /geofences.
+type zoneRequest struct {
+	Name     string          `json:"name"     validate:"required,max=200"`
+	Kind     string          `json:"kind"     validate:"omitempty,oneof=area port"`
+	ShipID   string          `json:"ship_id"  validate:"max=100"`
+	Geometry json.RawMessage `json:"geometry" validate:"required"`
+	RadiusM  *float64        `json:"radius_m"`
+	Notify   bool            `json:"notify"`
+	Enabled  *bool           `json:"enabled"`
+}
+
+// parseZoneRequest validates a zone body; kind defaults to "area" and ship_id
+// to every ship. If the zone is nil the error response has already been
+// written and the returned error is the one to pass back to Fiber.
+func parseZoneRequest(c *fiber.Ctx) (*Zone, error) {
+	var req zoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	if req.Kind == "" {
+		req.Kind = KindArea
+	}
+	if err := glob.Validate(req.ShipID); err != nil {
+		errs = append(errs, models.ErrorDetail{Loc: []string{"ShipID"}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+	}
+	var s shape
+	if len(req.Geometry) > 0 {
+		var err error
+		if s, err = parseGeometry(req.Geometry, req.RadiusM); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{"Geometry"}, Msg: err.Error(), Type: "validation_error.geometry"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	return &Zone{
+		Name:     req.Name,
+		Kind:     req.Kind,
+		ShipID:   req.ShipID,
+		Geometry: req.Geometry,
+		RadiusM:  req.RadiusM,
+		Notify:   req.Notify,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+		shape:    s,
+	}, nil
+}
+
+// ListGeofences returns all zones.
+func ListGeofences(c *fiber.Ctx) error {
+	zones, err := ListZones(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load geofences")
+	}
+	return c.JSON(zones)
+}
+
+// GetGeofence returns one zone.
+func GetGeofence(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid geofence ID")
+	}
+	zone, err := GetZone(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load geofence")
+	}
+	if zone == nil {
+		return fiber.NewError(http.StatusNotFound, "Geofence not found")
+	}
+	return c.JSON(zone)
+}
+
+// CreateGeofence stores a new zone. Workers pick it up within
+// GEOFENCE_REFRESH_SEC.
+func CreateGeofence(c *fiber.Ctx) error {
+	zone, err := parseZoneRequest(c)
+	if zone == nil {
+		return err
+	}
+	if err := CreateZone(c.Context(), zone); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create geofence")
+	}
+	return c.Status(http.StatusCreated).JSON(zone)
+}
+
+// UpdateGeofence replaces a zone and forgets which ships are inside it.
+func UpdateGeofence(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid geofence ID")
+	}
+	zone, err := parseZoneRequest(c)
+	if zone == nil {
+		return err
+	}
+	zone.ID = int64(id)
+	found, err := UpdateZone(c.Context(), zone)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update geofence")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Geofence not found")
+	}
+	return c.JSON(zone)
+}
+
+// DeleteGeofence removes a zone and its state.
+func DeleteGeofence(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid geofence ID")
+	}
+	found, err := DeleteZone(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete geofence")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Geofence not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ListGeofenceEvents returns the newest enter and exit events, optionally
+// filtered by `?ship_id=` and `?zone_id=`. `?limit=` defaults to 100.
+func ListGeofenceEvents(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		return fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 1000")
+	}
+	var zoneID *int64
+	if s := c.Query("zone_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "Invalid zone_id")
+		}
+		zoneID = &id
+	}
+	var shipID *string
+	if s := c.Query("ship_id"); s != "" {
+		shipID = &s
+	}
+
+	rows, err := db.Pool.Query(c.Context(), `
+		SELECT id, zone_id, zone_name, zone_kind, ship_id, event, time, latitude, longitude
+		FROM geofence_events
+		WHERE ($1::text IS NULL OR ship_id = $1) AND ($2::bigint IS NULL OR zone_id = $2)
+		ORDER BY time DESC, id DESC
+		LIMIT $3`, shipID, zoneID, limit)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load geofence events")
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var ev Event
+		if err := rows.Scan(&ev.ID, &ev.ZoneID, &ev.ZoneName, &ev.ZoneKind, &ev.ShipID, &ev.Event, &ev.Time, &ev.Latitude, &ev.Longitude); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to load geofence events")
+		}
+		events = append(events, ev)
+	}
+	if rows.Err() != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load geofence events")
+	}
+	return c.JSON(events)
+}
@@ -0,0 +1,141 @@
+// Package geofence detects ships entering and leaving zones, using the
+// "latitude" and "longitude" cargo IDs that make up a position.
+package geofence
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Zone kinds. Ports are where arrivals and departures happen; areas are any
+// other zone.
+const (
+	KindArea = "area"
+	KindPort = "port"
+)
+
+// Zone is a geofence. Geometry is a GeoJSON Polygon, MultiPolygon or, with
+// RadiusM, a Point describing a circle. ShipID is a glob pattern selecting
+// the ships it applies to.
+type Zone struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Kind      string          `json:"kind"`
+	ShipID    string          `json:"ship_id"`
+	Geometry  json.RawMessage `json:"geometry"`
+	RadiusM   *float64        `json:"radius_m,omitempty"`
+	Notify    bool            `json:"notify"`
+	Enabled   bool            `json:"enabled"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+
+	shape shape
+}
+
+// Selects reports whether the zone applies to a ship.
+func (z *Zone) Selects(shipID string) bool {
+	return glob.Match(z.ShipID, shipID)
+}
+
+// Contains reports whether a position is inside the zone.
+func (z *Zone) Contains(lat, lon float64) bool {
+	return z.shape != nil && z.shape.contains(lat, lon)
+}
+
+const zoneColumns = `id, name, kind, ship_id, geometry, radius_m, notify, enabled, created_at, updated_at`
+
+func scanZone(row pgx.Row) (Zone, error) {
+	var z Zone
+	err := row.Scan(&z.ID, &z.Name, &z.Kind, &z.ShipID, &z.Geometry, &z.RadiusM, &z.Notify, &z.Enabled, &z.CreatedAt, &z.UpdatedAt)
+	if err == nil {
+		// Geometries are validated before they are stored.
+		z.shape, _ = parseGeometry(z.Geometry, z.RadiusM)
+	}
+	return z, err
+}
+
+// ListZones returns all zones, or only the enabled ones.
+func ListZones(ctx context.Context, enabledOnly bool) ([]Zone, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+zoneColumns+` FROM geofence_zones WHERE enabled OR NOT $1 ORDER BY id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []Zone{}
+	for rows.Next() {
+		z, err := scanZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return zones, rows.Err()
+}
+
+// GetZone loads one zone; a nil zone means it does not exist.
+func GetZone(ctx context.Context, id int64) (*Zone, error) {
+	z, err := scanZone(db.Pool.QueryRow(ctx, `SELECT `+zoneColumns+` FROM geofence_zones WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+// CreateZone stores a new zone and fills in its ID and timestamps.
+func CreateZone(ctx context.Context, z *Zone) error {
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO geofence_zones (name, kind, ship_id, geometry, radius_m, notify, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		z.Name, z.Kind, z.ShipID, z.Geometry, z.RadiusM, z.Notify, z.Enabled,
+	).Scan(&z.ID, &z.CreatedAt, &z.UpdatedAt)
+}
+
+// UpdateZone replaces a zone, reporting whether it existed. Which ships are
+// inside is forgotten, as the new geometry may differ; the next position of
+// each ship sets it again without an event.
+func UpdateZone(ctx context.Context, z *Zone) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE geofence_zones
+		SET name = $2, kind = $3, ship_id = $4, geometry = $5, radius_m = $6, notify = $7, enabled = $8, updated_at = now()
+		WHERE id = $1
+		RETURNING created_at, updated_at`,
+		z.ID, z.Name, z.Kind, z.ShipID, z.Geometry, z.RadiusM, z.Notify, z.Enabled,
+	).Scan(&z.CreatedAt, &z.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM geofence_states WHERE zone_id = $1`, z.ID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// DeleteZone removes a zone and its state, reporting whether it existed. Its
+// events are kept.
+func DeleteZone(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM geofence_zones WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
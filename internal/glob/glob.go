@@ -0,0 +1,116 @@
+// Package glob matches the ship and cargo ID patterns used by rules, zones,
+// detectors and sinks.
+//
+// The syntax is that of path.Match ('*', '?', '[...]' and '\' escapes), but
+// '/' is an ordinary character: IDs such as Sparkplug's "node/device" are not
+// paths, so "*" must select them too.
+package glob
+
+import (
+	"path"
+	"unicode/utf8"
+)
+
+// Validate reports whether pattern is well formed.
+func Validate(pattern string) error {
+	// The syntax is the same as path.Match's, which checks the whole pattern.
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// Match reports whether name matches pattern. Malformed patterns match nothing
+// beyond the point where they become invalid; use Validate before storing one.
+func Match(pattern, name string) bool {
+	p, n := 0, 0
+	// Position of the last '*' and of the name byte it is currently matched up to.
+	starP, starN := -1, 0
+	for n < len(name) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starP, starN = p, n
+				p++
+				continue
+			case '?':
+				_, w := utf8.DecodeRuneInString(name[n:])
+				p, n = p+1, n+w
+				continue
+			case '[':
+				r, w := utf8.DecodeRuneInString(name[n:])
+				if end, ok := matchClass(pattern, p+1, r); ok {
+					p, n = end, n+w
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == name[n] {
+					p, n = p+2, n+1
+					continue
+				}
+			default:
+				if pattern[p] == name[n] {
+					p, n = p+1, n+1
+					continue
+				}
+			}
+		}
+		// Mismatch: let the last '*' absorb one more character and retry.
+		if starP < 0 {
+			return false
+		}
+		_, w := utf8.DecodeRuneInString(name[starN:])
+		starN += w
+		p, n = starP+1, starN
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches r against the character class starting at pattern[i],
+// just after its '['. It returns the index after the closing ']' and whether
+// r is in the class.
+func matchClass(pattern string, i int, r rune) (int, bool) {
+	negated := i < len(pattern) && pattern[i] == '^'
+	if negated {
+		i++
+	}
+	matched := false
+	for first := true; ; first = false {
+		if i >= len(pattern) {
+			return 0, false
+		}
+		if pattern[i] == ']' && !first {
+			return i + 1, matched != negated
+		}
+		lo, next, ok := classChar(pattern, i)
+		if !ok {
+			return 0, false
+		}
+		hi := lo
+		if next < len(pattern) && pattern[next] == '-' {
+			if hi, next, ok = classChar(pattern, next+1); !ok {
+				return 0, false
+			}
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+		i = next
+	}
+}
+
+// classChar reads one possibly escaped character of a class at pattern[i].
+func classChar(pattern string, i int) (rune, int, bool) {
+	if i >= len(pattern) || pattern[i] == '-' || pattern[i] == ']' {
+		return 0, 0, false
+	}
+	if pattern[i] == '\\' {
+		i++
+		if i >= len(pattern) {
+			return 0, 0, false
+		}
+	}
+	r, w := utf8.DecodeRuneInString(pattern[i:])
+	return r, i + w, true
+}
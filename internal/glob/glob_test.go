@@ -0,0 +1,74 @@
+package glob
+
+import (
+	"path"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "", true},
+		{"*", "vessel_1", true},
+		{"*", "node/device", true},
+		{"plant_*", "plant_1/pump", true},
+		{"*/pump", "plant_1/pump", true},
+		{"*/pump", "plant_1/valve", false},
+		{"reefer_*", "reefer_7", true},
+		{"reefer_*", "tanker_7", false},
+		{"temp?", "temp1", true},
+		{"temp?", "temp", false},
+		{"temp?", "temp12", false},
+		{"a?c", "a/c", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"*x", "xxxx", true},
+		{"[a-c]at", "bat", true},
+		{"[a-c]at", "rat", false},
+		{"[^a-c]at", "rat", true},
+		{"[^a-c]at", "cat", false},
+		{"[/]", "/", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{"é?", "éa", true},
+		{"?", "é", true},
+		{"*?", "é", true},
+		{"**", "anything", true},
+		{"", "", true},
+		{"", "a", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// Apart from '/', Match must agree with path.Match.
+func TestMatchAgreesWithPath(t *testing.T) {
+	patterns := []string{"*", "a*", "*a", "a?c", "[a-c]*", "[^x]y", `a\*b`, "*b*c*"}
+	names := []string{"", "a", "abc", "xy", "zy", "a*b", "aabbcc", "cba"}
+	for _, p := range patterns {
+		for _, n := range names {
+			want, _ := path.Match(p, n)
+			if got := Match(p, n); got != want {
+				t.Errorf("Match(%q, %q) = %v, path.Match says %v", p, n, got, want)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, p := range []string{"*", "ship_[0-9]", `a\*`, "node/*"} {
+		if err := Validate(p); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", p, err)
+		}
+	}
+	for _, p := range []string{"[", "[]", "a[b", `a\`} {
+		if err := Validate(p); err == nil {
+			t.Errorf("Validate(%q) = nil, want error", p)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package heartbeat
+
+import (
+	"net/http"
+	"time"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v4"
+)
+
+// SeriesStatus is the heartbeat state of one cargo series.
+type SeriesStatus struct {
+	CargoID             string     `json:"cargo_id"`
+	Status              string     `json:"status"`
+	LastSeen            *time.Time `json:"last_seen"`
+	LastDataTime        *time.Time `json:"last_data_time"`
+	ExpectedIntervalSec *int       `json:"expected_interval_sec"`
+	StatusChangedAt     time.Time  `json:"status_changed_at"`
+}
+
+// ShipStatus is the response of GET /ships/{id}/status.
+type ShipStatus struct {
+	ShipID              string         `json:"ship_id"`
+	Status              string         `json:"status"`
+	LastSeen            *time.Time     `json:"last_seen"`
+	LastDataTime        *time.Time     `json:"last_data_time"`
+	ExpectedIntervalSec *int           `json:"expected_interval_sec"`
+	StatusChangedAt     time.Time      `json:"status_changed_at"`
+	Series              []SeriesStatus `json:"series"`
+}
+
+// expectationsRequest is the body of PUT /ships/{id}/heartbeat. A null ship
+// interval falls back to HEARTBEAT_DEFAULT_INTERVAL_SEC; a null series interval
+// stops checking that series.
+type expectationsRequest struct {
+	ExpectedIntervalSec *int            `json:"expected_interval_sec" validate:"omitempty,min=1"`
+	Series              map[string]*int `json:"series"                validate:"dive,keys,min=1,max=100,endkeys,omitempty,min=1"`
+}
+
+// GetShipStatus reports when a ship and each of its series last reported, and
+// whether they are online.
+func GetShipStatus(c *fiber.Ctx) error {
+	shipID := c.Params("id")
+	status := ShipStatus{ShipID: shipID, Series: []SeriesStatus{}}
+	err := db.Pool.QueryRow(c.Context(), `
+		SELECT status, last_seen, last_data_time, expected_interval_sec, status_changed_at
+		FROM ship_status WHERE ship_id = $1`, shipID,
+	).Scan(&status.Status, &status.LastSeen, &status.LastDataTime, &status.ExpectedIntervalSec, &status.StatusChangedAt)
+	if err == pgx.ErrNoRows {
+		return fiber.NewError(http.StatusNotFound, "Ship not found")
+	}
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load ship status")
+	}
+	if status.ExpectedIntervalSec == nil && config.AppConfig.HeartbeatExpected > 0 {
+		secs := int(config.AppConfig.HeartbeatExpected / time.Second)
+		status.ExpectedIntervalSec = &secs
+	}
+
+	rows, err := db.Pool.Query(c.Context(), `
+		SELECT cargo_id, status, last_seen, last_data_time, expected_interval_sec, status_changed_at
+		FROM series_status WHERE ship_id = $1 ORDER BY cargo_id`, shipID)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load ship status")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s SeriesStatus
+		if err := rows.Scan(&s.CargoID, &s.Status, &s.LastSeen, &s.LastDataTime, &s.ExpectedIntervalSec, &s.StatusChangedAt); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to load ship status")
+		}
+		status.Series = append(status.Series, s)
+	}
+	if rows.Err() != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load ship status")
+	}
+	return c.JSON(status)
+}
+
+// SetHeartbeatExpectations sets how often a ship, and optionally some of its
+// series, are expected to report. Unknown ships and series are created so they
+// are flagged if they never report.
+func SetHeartbeatExpectations(c *fiber.Ctx) error {
+	shipID := c.Params("id")
+	var req expectationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	if errs := utils.ValidateStruct(&req); len(errs) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	tx, err := db.Pool.Begin(c.Context())
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update heartbeat expectations")
+	}
+	defer tx.Rollback(c.Context())
+
+	if _, err := tx.Exec(c.Context(), `
+		INSERT INTO ship_status (ship_id, expected_interval_sec, status, status_changed_at)
+		VALUES ($1, $2, 'online', now())
+		ON CONFLICT (ship_id) DO UPDATE SET expected_interval_sec = EXCLUDED.expected_interval_sec`,
+		shipID, req.ExpectedIntervalSec); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update heartbeat expectations")
+	}
+	for cargoID, interval := range req.Series {
+		if _, err := tx.Exec(c.Context(), `
+			INSERT INTO series_status (ship_id, cargo_id, expected_interval_sec, status, status_changed_at)
+			VALUES ($1, $2, $3, 'online', now())
+			ON CONFLICT (ship_id, cargo_id) DO UPDATE SET expected_interval_sec = EXCLUDED.expected_interval_sec`,
+			shipID, cargoID, interval); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to update heartbeat expectations")
+		}
+	}
+	if err := tx.Commit(c.Context()); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update heartbeat expectations")
+	}
+	return GetShipStatus(c)
+}
@@ -0,0 +1,250 @@
+// Package heartbeat tracks when each ship and cargo series last reported and
+// flags those that stay silent for longer than expected. Status changes are
+// sent as webhook events.
+package heartbeat
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/webhook"
+)
+
+// Statuses of ships and series.
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// Webhook event types sent on status changes.
+const (
+	EventShipOnline    = "ship.online"
+	EventShipOffline   = "ship.offline"
+	EventSeriesOnline  = "series.online"
+	EventSeriesOffline = "series.offline"
+)
+
+// Notification is the data of a heartbeat webhook event. CargoID is empty for
+// ship events.
+type Notification struct {
+	ShipID              string     `json:"ship_id"`
+	CargoID             string     `json:"cargo_id,omitempty"`
+	Status              string     `json:"status"`
+	LastSeen            *time.Time `json:"last_seen"`
+	ExpectedIntervalSec *int       `json:"expected_interval_sec,omitempty"`
+	Time                time.Time  `json:"time"`
+}
+
+// Record updates the last-seen times of the ships and series in a committed
+// batch and brings offline ones back online. last_seen is the time the batch
+// was committed; last_data_time the newest point time.
+func Record(ctx context.Context, batch []general.SensorData) error {
+	type series struct{ shipID, cargoID string }
+	shipTimes := make(map[string]time.Time)
+	seriesTimes := make(map[series]time.Time)
+	for _, d := range batch {
+		if t, ok := shipTimes[d.ShipID]; !ok || d.Time.After(t) {
+			shipTimes[d.ShipID] = d.Time
+		}
+		key := series{d.ShipID, d.CargoID}
+		if t, ok := seriesTimes[key]; !ok || d.Time.After(t) {
+			seriesTimes[key] = d.Time
+		}
+	}
+	if len(shipTimes) == 0 {
+		return nil
+	}
+
+	// Rows are upserted in key order, so that concurrent workers recording
+	// overlapping ships lock them in the same order and cannot deadlock.
+	ships := make([]string, 0, len(shipTimes))
+	for ship := range shipTimes {
+		ships = append(ships, ship)
+	}
+	sort.Strings(ships)
+	shipDataTimes := make([]time.Time, len(ships))
+	for i, ship := range ships {
+		shipDataTimes[i] = shipTimes[ship]
+	}
+
+	seriesKeys := make([]series, 0, len(seriesTimes))
+	for key := range seriesTimes {
+		seriesKeys = append(seriesKeys, key)
+	}
+	sort.Slice(seriesKeys, func(i, j int) bool {
+		if seriesKeys[i].shipID != seriesKeys[j].shipID {
+			return seriesKeys[i].shipID < seriesKeys[j].shipID
+		}
+		return seriesKeys[i].cargoID < seriesKeys[j].cargoID
+	})
+	seriesShips := make([]string, len(seriesKeys))
+	seriesCargos := make([]string, len(seriesKeys))
+	seriesDataTimes := make([]time.Time, len(seriesKeys))
+	for i, key := range seriesKeys {
+		seriesShips[i], seriesCargos[i], seriesDataTimes[i] = key.shipID, key.cargoID, seriesTimes[key]
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var notifications []Notification
+	now := time.Now().UTC()
+
+	rows, err := tx.Query(ctx, `
+		UPDATE ship_status SET status = 'online', status_changed_at = now()
+		WHERE ship_id = ANY($1) AND status = 'offline'
+		RETURNING ship_id, last_seen, expected_interval_sec`, ships)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		n := Notification{Status: StatusOnline, Time: now}
+		if err := rows.Scan(&n.ShipID, &n.LastSeen, &n.ExpectedIntervalSec); err != nil {
+			rows.Close()
+			return err
+		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		UPDATE series_status s SET status = 'online', status_changed_at = now()
+		FROM unnest($1::text[], $2::text[]) AS b (ship_id, cargo_id)
+		WHERE s.ship_id = b.ship_id AND s.cargo_id = b.cargo_id AND s.status = 'offline'
+		RETURNING s.ship_id, s.cargo_id, s.last_seen, s.expected_interval_sec`, seriesShips, seriesCargos)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		n := Notification{Status: StatusOnline, Time: now}
+		if err := rows.Scan(&n.ShipID, &n.CargoID, &n.LastSeen, &n.ExpectedIntervalSec); err != nil {
+			rows.Close()
+			return err
+		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ship_status (ship_id, last_seen, last_data_time, status, status_changed_at)
+		SELECT ship_id, now(), data_time, 'online', now()
+		FROM unnest($1::text[], $2::timestamptz[]) AS b (ship_id, data_time)
+		ON CONFLICT (ship_id) DO UPDATE SET
+			last_seen = EXCLUDED.last_seen,
+			last_data_time = GREATEST(ship_status.last_data_time, EXCLUDED.last_data_time)`,
+		ships, shipDataTimes); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO series_status (ship_id, cargo_id, last_seen, last_data_time, status, status_changed_at)
+		SELECT ship_id, cargo_id, now(), data_time, 'online', now()
+		FROM unnest($1::text[], $2::text[], $3::timestamptz[]) AS b (ship_id, cargo_id, data_time)
+		ON CONFLICT (ship_id, cargo_id) DO UPDATE SET
+			last_seen = EXCLUDED.last_seen,
+			last_data_time = GREATEST(series_status.last_data_time, EXCLUDED.last_data_time)`,
+		seriesShips, seriesCargos, seriesDataTimes); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	publish(ctx, notifications)
+	return nil
+}
+
+// Run marks ships and series offline every interval until ctx is cancelled.
+// Ships use their own expected interval or defaultInterval (0 disables the
+// check); series are only checked if they have an expected interval.
+func Run(ctx context.Context, interval, defaultInterval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := checkStale(ctx, defaultInterval); err != nil && ctx.Err() == nil {
+				log.Printf("[Heartbeat] Failed to check for stale ships: %v", err)
+			}
+		}
+	}
+}
+
+// checkStale flips overdue ships and series to offline. The UPDATE decides
+// which worker reports a change, so each one is notified once.
+func checkStale(ctx context.Context, defaultInterval time.Duration) error {
+	now := time.Now().UTC()
+	var notifications []Notification
+
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE ship_status SET status = 'offline', status_changed_at = now()
+		WHERE status = 'online'
+		  AND COALESCE(expected_interval_sec, $1) > 0
+		  AND COALESCE(last_seen, status_changed_at) < now() - make_interval(secs => COALESCE(expected_interval_sec, $1))
+		RETURNING ship_id, last_seen, COALESCE(expected_interval_sec, $1)`,
+		int(defaultInterval/time.Second))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		n := Notification{Status: StatusOffline, Time: now}
+		if err := rows.Scan(&n.ShipID, &n.LastSeen, &n.ExpectedIntervalSec); err != nil {
+			rows.Close()
+			return err
+		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+
+	rows, err = db.Pool.Query(ctx, `
+		UPDATE series_status SET status = 'offline', status_changed_at = now()
+		WHERE status = 'online'
+		  AND expected_interval_sec > 0
+		  AND COALESCE(last_seen, status_changed_at) < now() - make_interval(secs => expected_interval_sec)
+		RETURNING ship_id, cargo_id, last_seen, expected_interval_sec`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		n := Notification{Status: StatusOffline, Time: now}
+		if err := rows.Scan(&n.ShipID, &n.CargoID, &n.LastSeen, &n.ExpectedIntervalSec); err != nil {
+			rows.Close()
+			return err
+		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+
+	publish(ctx, notifications)
+	return nil
+}
+
+func publish(ctx context.Context, notifications []Notification) {
+	for _, n := range notifications {
+		eventType := EventShipOnline
+		switch {
+		case n.CargoID == "" && n.Status == StatusOffline:
+			eventType = EventShipOffline
+		case n.CargoID != "" && n.Status == StatusOnline:
+			eventType = EventSeriesOnline
+		case n.CargoID != "":
+			eventType = EventSeriesOffline
+		}
+		if n.CargoID == "" {
+			log.Printf("[Heartbeat] Ship %s is %s", n.ShipID, n.Status)
+		} else {
+			log.Printf("[Heartbeat] Series %s/%s is %s", n.ShipID, n.CargoID, n.Status)
+		}
+		if err := webhook.Publish(ctx, eventType, n); err != nil {
+			log.Printf("[Heartbeat] Failed to queue %s notification for %s: %v", eventType, n.ShipID, err)
+		}
+	}
+}
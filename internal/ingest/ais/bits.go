@@ -0,0 +1,69 @@
+package ais
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bitReader reads fields from a de-armored AIS payload.
+type bitReader struct {
+	bits []byte // one bit per element
+}
+
+// newBitReader de-armors the 6-bit ASCII payload, dropping the fill bits.
+func newBitReader(payload string, fillBits int) (*bitReader, error) {
+	bits := make([]byte, 0, len(payload)*6)
+	for i := 0; i < len(payload); i++ {
+		c := payload[i]
+		if c < 48 || c > 119 || (c > 87 && c < 96) {
+			return nil, fmt.Errorf("invalid payload character %q", c)
+		}
+		v := c - 48
+		if v > 40 {
+			v -= 8
+		}
+		for b := 5; b >= 0; b-- {
+			bits = append(bits, (v>>uint(b))&1)
+		}
+	}
+	if fillBits < 0 || fillBits > 5 || fillBits > len(bits) {
+		return nil, fmt.Errorf("invalid fill bits %d", fillBits)
+	}
+	return &bitReader{bits: bits[:len(bits)-fillBits]}, nil
+}
+
+func (r *bitReader) len() int { return len(r.bits) }
+
+// uint reads an unsigned field of n bits starting at bit offset start.
+func (r *bitReader) uint(start, n int) uint64 {
+	var v uint64
+	for i := start; i < start+n; i++ {
+		v <<= 1
+		if i < len(r.bits) {
+			v |= uint64(r.bits[i])
+		}
+	}
+	return v
+}
+
+// int reads a two's complement signed field of n bits.
+func (r *bitReader) int(start, n int) int64 {
+	v := r.uint(start, n)
+	if v&(1<<uint(n-1)) != 0 {
+		return int64(v) - (1 << uint(n))
+	}
+	return int64(v)
+}
+
+// text reads n bits of 6-bit AIS text, trimming '@' padding and spaces.
+func (r *bitReader) text(start, n int) string {
+	var sb strings.Builder
+	for i := start; i+6 <= start+n && i+6 <= len(r.bits); i += 6 {
+		c := byte(r.uint(i, 6))
+		if c < 32 {
+			c += 64
+		}
+		sb.WriteByte(c)
+	}
+	return strings.TrimSpace(strings.TrimRight(sb.String(), "@"))
+}
@@ -0,0 +1,267 @@
+package ais
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ingest/nmea"
+)
+
+// fragmentTimeout is how long an incomplete multi-sentence message is kept.
+const fragmentTimeout = 30 * time.Second
+
+// ErrUnsupported is returned for valid AIS messages of types that are not decoded.
+var ErrUnsupported = errors.New("unsupported AIS message type")
+
+// Result is the decoded content of one complete AIS message.
+type Result struct {
+	Points   []general.SensorData
+	Metadata *ShipMetadata
+}
+
+// Decoder reassembles multi-sentence messages and decodes them. It is safe
+// for concurrent use; fragments are matched by source, sequential message ID
+// and channel.
+type Decoder struct {
+	mu        sync.Mutex
+	fragments map[string]*fragmentSet
+}
+
+type fragmentSet struct {
+	parts    []string
+	received int
+	fillBits int
+	started  time.Time
+}
+
+// NewDecoder creates a decoder with an empty reassembly buffer.
+func NewDecoder() *Decoder {
+	return &Decoder{fragments: make(map[string]*fragmentSet)}
+}
+
+// Feed processes one sentence received from source at now. It returns nil
+// without error while a multi-sentence message is still incomplete. source
+// identifies the receiver, e.g. by its address, as sequential message IDs are
+// only unique per receiver.
+func (d *Decoder) Feed(source, line string, now time.Time) (*Result, error) {
+	// Strip an optional NMEA 4.0 tag block ("\s:rx1,c:1700000000*hh\!AIVDM...").
+	if strings.HasPrefix(line, "\\") {
+		if end := strings.Index(line[1:], "\\"); end >= 0 {
+			line = line[end+2:]
+		}
+	}
+
+	s, err := nmea.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	if s.Type != "VDM" && s.Type != "VDO" {
+		return nil, fmt.Errorf("not an AIS sentence: %s", s.Type)
+	}
+	if len(s.Fields) < 6 {
+		return nil, fmt.Errorf("AIS sentence has %d fields, expected 6", len(s.Fields))
+	}
+
+	total, err1 := strconv.Atoi(s.Fields[0])
+	number, err2 := strconv.Atoi(s.Fields[1])
+	fillBits, err3 := strconv.Atoi(s.Fields[5])
+	if err1 != nil || err2 != nil || err3 != nil || total < 1 || number < 1 || number > total {
+		return nil, fmt.Errorf("invalid fragment header")
+	}
+
+	payload, fill := s.Fields[4], fillBits
+	if total > 1 {
+		var complete bool
+		payload, fill, complete = d.addFragment(source+"/"+s.Fields[2]+"/"+s.Fields[3], total, number, s.Fields[4], fillBits, now)
+		if !complete {
+			return nil, nil
+		}
+	}
+
+	bits, err := newBitReader(payload, fill)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMessage(bits, now)
+}
+
+// addFragment stores a fragment and returns the joined payload once all parts have arrived.
+func (d *Decoder) addFragment(key string, total, number int, payload string, fillBits int, now time.Time) (string, int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k, set := range d.fragments {
+		if now.Sub(set.started) > fragmentTimeout {
+			delete(d.fragments, k)
+		}
+	}
+
+	// Fragments may arrive out of order (e.g. over UDP). A different total or
+	// a fragment number that was already received means the sequential ID has
+	// been reused for a new message, so the stale parts are dropped.
+	set, ok := d.fragments[key]
+	if !ok || len(set.parts) != total || set.parts[number-1] != "" {
+		set = &fragmentSet{parts: make([]string, total), started: now}
+		d.fragments[key] = set
+	}
+	set.parts[number-1] = payload
+	set.received++
+	if number == total {
+		set.fillBits = fillBits
+	}
+
+	if set.received < total {
+		return "", 0, false
+	}
+	delete(d.fragments, key)
+	return strings.Join(set.parts, ""), set.fillBits, true
+}
+
+// decodeMessage dispatches on the message type in the first six bits.
+func decodeMessage(r *bitReader, now time.Time) (*Result, error) {
+	if r.len() < 38 {
+		return nil, fmt.Errorf("AIS message too short")
+	}
+	msgType := r.uint(0, 6)
+	mmsi := strconv.FormatUint(r.uint(8, 30), 10)
+	ts := now.UTC()
+
+	switch msgType {
+	case 1, 2, 3:
+		if r.len() < 168 {
+			return nil, fmt.Errorf("type %d message too short", msgType)
+		}
+		p := &points{shipID: mmsi, ts: ts}
+		p.add("nav_status", float64(r.uint(38, 4)))
+		if rot := r.int(42, 8); rot > -127 && rot < 127 {
+			// ROT is encoded as 4.733 * sqrt(degrees per minute).
+			deg := math.Pow(float64(rot)/4.733, 2)
+			if rot < 0 {
+				deg = -deg
+			}
+			p.add("rate_of_turn", deg)
+		}
+		p.movement(r, 50, 61, 89, 116, 128)
+		return &Result{Points: p.data}, nil
+	case 18:
+		if r.len() < 168 {
+			return nil, fmt.Errorf("type 18 message too short")
+		}
+		p := &points{shipID: mmsi, ts: ts}
+		p.movement(r, 46, 57, 85, 112, 124)
+		return &Result{Points: p.data}, nil
+	case 19:
+		if r.len() < 312 {
+			return nil, fmt.Errorf("type 19 message too short")
+		}
+		p := &points{shipID: mmsi, ts: ts}
+		p.movement(r, 46, 57, 85, 112, 124)
+		meta := &ShipMetadata{ShipID: mmsi, UpdatedAt: ts}
+		meta.Name = optText(r.text(143, 120))
+		meta.ShipType = optInt(int64(r.uint(263, 8)))
+		meta.setDimensions(r, 271)
+		return &Result{Points: p.data, Metadata: meta}, nil
+	case 5:
+		if r.len() < 420 {
+			return nil, fmt.Errorf("type 5 message too short")
+		}
+		meta := &ShipMetadata{ShipID: mmsi, UpdatedAt: ts}
+		meta.IMO = optInt(int64(r.uint(40, 30)))
+		meta.CallSign = optText(r.text(70, 42))
+		meta.Name = optText(r.text(112, 120))
+		meta.ShipType = optInt(int64(r.uint(232, 8)))
+		meta.setDimensions(r, 240)
+		meta.ETA = eta(r.uint(274, 4), r.uint(278, 5), r.uint(283, 5), r.uint(288, 6), ts)
+		if draught := float64(r.uint(294, 8)) / 10; draught > 0 {
+			meta.Draught = &draught
+		}
+		meta.Destination = optText(r.text(302, 120))
+		return &Result{Metadata: meta}, nil
+	case 24:
+		meta := &ShipMetadata{ShipID: mmsi, UpdatedAt: ts}
+		switch r.uint(38, 2) {
+		case 0:
+			meta.Name = optText(r.text(40, 120))
+		case 1:
+			meta.ShipType = optInt(int64(r.uint(40, 8)))
+			meta.CallSign = optText(r.text(90, 42))
+			meta.setDimensions(r, 132)
+		default:
+			return nil, fmt.Errorf("invalid type 24 part number")
+		}
+		return &Result{Metadata: meta}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupported, msgType)
+	}
+}
+
+// points accumulates telemetry for one ship and timestamp.
+type points struct {
+	shipID string
+	ts     time.Time
+	data   []general.SensorData
+}
+
+func (p *points) add(cargoID string, v float64) {
+	p.data = append(p.data, general.SensorData{Time: p.ts, ShipID: p.shipID, CargoID: cargoID, Value: &v})
+}
+
+// movement adds speed, position, course and heading, skipping "not available" values.
+func (p *points) movement(r *bitReader, sogAt, lonAt, latAt, cogAt, headingAt int) {
+	if sog := r.uint(sogAt, 10); sog != 1023 {
+		p.add("sog", float64(sog)/10)
+	}
+	lon := float64(r.int(lonAt, 28)) / 600000
+	lat := float64(r.int(latAt, 27)) / 600000
+	if math.Abs(lon) <= 180 && math.Abs(lat) <= 90 {
+		p.add("latitude", lat)
+		p.add("longitude", lon)
+	}
+	if cog := r.uint(cogAt, 12); cog < 3600 {
+		p.add("cog", float64(cog)/10)
+	}
+	if heading := r.uint(headingAt, 9); heading < 360 {
+		p.add("heading", float64(heading))
+	}
+}
+
+// setDimensions reads the 30-bit dimension block (to bow, stern, port, starboard).
+func (m *ShipMetadata) setDimensions(r *bitReader, start int) {
+	length := int64(r.uint(start, 9) + r.uint(start+9, 9))
+	beam := int64(r.uint(start+18, 6) + r.uint(start+24, 6))
+	m.Length = optInt(length)
+	m.Beam = optInt(beam)
+}
+
+// eta builds the estimated time of arrival from its month/day/hour/minute
+// fields, choosing the year so the ETA is not far in the past.
+func eta(month, day, hour, minute uint64, now time.Time) *time.Time {
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 {
+		return nil
+	}
+	t := time.Date(now.Year(), time.Month(month), int(day), int(hour), int(minute), 0, 0, time.UTC)
+	if now.Sub(t) > 180*24*time.Hour {
+		t = t.AddDate(1, 0, 0)
+	}
+	return &t
+}
+
+func optText(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optInt(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,379 @@
+package ais
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"go-ingest-service/internal/ingest/nmea"
+)
+
+// bitWriter builds AIS payloads for the tests.
+type bitWriter struct {
+	bits []byte
+}
+
+func (w *bitWriter) uint(v uint64, n int) *bitWriter {
+	for b := n - 1; b >= 0; b-- {
+		w.bits = append(w.bits, byte(v>>uint(b)&1))
+	}
+	return w
+}
+
+func (w *bitWriter) int(v int64, n int) *bitWriter {
+	return w.uint(uint64(v)&(1<<uint(n)-1), n)
+}
+
+// text writes s as 6-bit AIS text padded with '@' to n bits.
+func (w *bitWriter) text(s string, n int) *bitWriter {
+	for i := 0; i < n/6; i++ {
+		c := byte('@')
+		if i < len(s) {
+			c = s[i]
+		}
+		w.uint(uint64(c&0x3f), 6)
+	}
+	return w
+}
+
+// armor returns the 6-bit ASCII payload and its fill bits.
+func (w *bitWriter) armor() (string, int) {
+	bits := w.bits
+	fill := (6 - len(bits)%6) % 6
+	bits = append(bits[:len(bits):len(bits)], make([]byte, fill)...)
+	payload := make([]byte, 0, len(bits)/6)
+	for i := 0; i < len(bits); i += 6 {
+		var v byte
+		for _, b := range bits[i : i+6] {
+			v = v<<1 | b
+		}
+		c := v + 48
+		if c > 87 {
+			c += 8
+		}
+		payload = append(payload, c)
+	}
+	return string(payload), fill
+}
+
+func vdm(total, number int, seq, payload string, fill int) string {
+	body := fmt.Sprintf("AIVDM,%d,%d,%s,A,%s,%d", total, number, seq, payload, fill)
+	return fmt.Sprintf("!%s*%02X", body, nmea.Checksum(body))
+}
+
+func TestBitReader(t *testing.T) {
+	tests := []struct {
+		payload  string
+		fill     int
+		len      int
+		start, n int
+		uint     uint64
+		int      int64
+		wantErr  bool
+	}{
+		{payload: "0", len: 6, n: 6, uint: 0, int: 0},
+		{payload: "1", len: 6, n: 6, uint: 1, int: 1},
+		{payload: "W", len: 6, n: 6, uint: 39, int: -25},
+		{payload: "`", len: 6, n: 6, uint: 40, int: -24},
+		{payload: "w", len: 6, n: 6, uint: 63, int: -1},
+		{payload: "w0", len: 12, start: 3, n: 6, uint: 0x38, int: -8},
+		{payload: "w", fill: 2, len: 4, n: 4, uint: 15, int: -1},
+		// Reads past the end are zero-padded.
+		{payload: "1", len: 6, start: 4, n: 4, uint: 4, int: 4},
+		{payload: "X", wantErr: true},
+		{payload: "x", wantErr: true},
+		{payload: "1", fill: 6, wantErr: true},
+		{payload: "", fill: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := newBitReader(tt.payload, tt.fill)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("newBitReader(%q, %d) succeeded, want error", tt.payload, tt.fill)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newBitReader(%q, %d) = %v", tt.payload, tt.fill, err)
+			continue
+		}
+		if r.len() != tt.len {
+			t.Errorf("newBitReader(%q, %d) has %d bits, want %d", tt.payload, tt.fill, r.len(), tt.len)
+		}
+		if got := r.uint(tt.start, tt.n); got != tt.uint {
+			t.Errorf("%q uint(%d, %d) = %d, want %d", tt.payload, tt.start, tt.n, got, tt.uint)
+		}
+		if got := r.int(tt.start, tt.n); got != tt.int {
+			t.Errorf("%q int(%d, %d) = %d, want %d", tt.payload, tt.start, tt.n, got, tt.int)
+		}
+	}
+}
+
+func TestBitReaderText(t *testing.T) {
+	tests := []struct {
+		text string
+		bits int
+		want string
+	}{
+		{"EVER GIVEN", 120, "EVER GIVEN"},
+		{"ROTTERDAM  ", 120, "ROTTERDAM"},
+		{"", 42, ""},
+		{"A1-B", 24, "A1-B"},
+	}
+	for _, tt := range tests {
+		payload, fill := (&bitWriter{}).text(tt.text, tt.bits).armor()
+		r, err := newBitReader(payload, fill)
+		if err != nil {
+			t.Fatalf("newBitReader(%q) = %v", payload, err)
+		}
+		if got := r.text(0, tt.bits); got != tt.want {
+			t.Errorf("text of %q = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// positionReport encodes a class A position report (types 1-3).
+func positionReport(mmsi uint64, rot int64, sog uint64, lon, lat float64, cog, heading uint64) *bitWriter {
+	w := &bitWriter{}
+	w.uint(1, 6).uint(0, 2).uint(mmsi, 30).uint(0, 4).int(rot, 8).uint(sog, 10).uint(0, 1)
+	w.int(int64(math.Round(lon*600000)), 28).int(int64(math.Round(lat*600000)), 27)
+	w.uint(cog, 12).uint(heading, 9).uint(0, 6+2+3+1+19)
+	return w
+}
+
+func TestDecodeMessage(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	classB := &bitWriter{}
+	classB.uint(18, 6).uint(0, 2).uint(211234567, 30).uint(0, 8).uint(1023, 10).uint(0, 1)
+	classB.int(181*600000, 28).int(91*600000, 27).uint(3600, 12).uint(511, 9).uint(0, 168-133)
+
+	tests := []struct {
+		name    string
+		w       *bitWriter
+		shipID  string
+		want    map[string]float64
+		wantErr error
+	}{
+		{
+			name:   "class A position",
+			w:      positionReport(244670316, 0, 123, 4.5, 52.1, 1800, 181),
+			shipID: "244670316",
+			want:   map[string]float64{"nav_status": 0, "rate_of_turn": 0, "sog": 12.3, "latitude": 52.1, "longitude": 4.5, "cog": 180, "heading": 181},
+		},
+		{
+			name:   "class A turning left in the western hemisphere",
+			w:      positionReport(367000001, -47, 0, -74.0125, 40.7, 0, 90),
+			shipID: "367000001",
+			want:   map[string]float64{"nav_status": 0, "rate_of_turn": -math.Pow(47/4.733, 2), "sog": 0, "latitude": 40.7, "longitude": -74.0125, "cog": 0, "heading": 90},
+		},
+		{
+			name:   "class A without turn indicator",
+			w:      positionReport(244670316, -128, 1023, 181, 91, 3600, 511),
+			shipID: "244670316",
+			want:   map[string]float64{"nav_status": 0},
+		},
+		{
+			name:   "class B with nothing available",
+			w:      classB,
+			shipID: "211234567",
+			want:   map[string]float64{},
+		},
+		{
+			name:    "unsupported type",
+			w:       (&bitWriter{}).uint(21, 6).uint(0, 2).uint(992000001, 30).uint(0, 300),
+			wantErr: ErrUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		payload, fill := tt.w.armor()
+		r, err := newBitReader(payload, fill)
+		if err != nil {
+			t.Fatalf("%s: newBitReader = %v", tt.name, err)
+		}
+		res, err := decodeMessage(r, now)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: decodeMessage = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: decodeMessage = %v", tt.name, err)
+			continue
+		}
+		if len(res.Points) != len(tt.want) {
+			t.Errorf("%s: %d points, want %d", tt.name, len(res.Points), len(tt.want))
+		}
+		for _, d := range res.Points {
+			want, ok := tt.want[d.CargoID]
+			if !ok || math.Abs(*d.Value-want) > 1e-6 {
+				t.Errorf("%s: %s = %v, want %v", tt.name, d.CargoID, *d.Value, want)
+			}
+			if d.ShipID != tt.shipID || !d.Time.Equal(now) {
+				t.Errorf("%s: %s at %s/%v, want %s/%v", tt.name, d.CargoID, d.ShipID, d.Time, tt.shipID, now)
+			}
+		}
+	}
+}
+
+// staticVoyage encodes a type 5 static and voyage data message.
+func staticVoyage() *bitWriter {
+	w := &bitWriter{}
+	w.uint(5, 6).uint(0, 2).uint(353136000, 30).uint(0, 2).uint(9811000, 30)
+	w.text("H3RC", 42).text("EVER GIVEN", 120).uint(70, 8)
+	w.uint(300, 9).uint(100, 9).uint(30, 6).uint(29, 6).uint(1, 4)
+	w.uint(3, 4).uint(29, 5).uint(14, 5).uint(30, 6).uint(157, 8)
+	w.text("ROTTERDAM", 120).uint(0, 2)
+	return w
+}
+
+func TestDecodeStaticVoyage(t *testing.T) {
+	now := time.Date(2024, 3, 23, 8, 0, 0, 0, time.UTC)
+	payload, fill := staticVoyage().armor()
+	res, err := NewDecoder().Feed("", vdm(1, 1, "", payload, fill), now)
+	if err != nil {
+		t.Fatalf("Feed = %v", err)
+	}
+	m := res.Metadata
+	if m == nil || len(res.Points) != 0 {
+		t.Fatalf("Feed = %+v, want metadata only", res)
+	}
+	eta := time.Date(2024, 3, 29, 14, 30, 0, 0, time.UTC)
+	switch {
+	case m.ShipID != "353136000":
+		t.Errorf("ShipID = %q", m.ShipID)
+	case m.IMO == nil || *m.IMO != 9811000:
+		t.Errorf("IMO = %v", m.IMO)
+	case m.CallSign == nil || *m.CallSign != "H3RC":
+		t.Errorf("CallSign = %v", m.CallSign)
+	case m.Name == nil || *m.Name != "EVER GIVEN":
+		t.Errorf("Name = %v", m.Name)
+	case m.ShipType == nil || *m.ShipType != 70:
+		t.Errorf("ShipType = %v", m.ShipType)
+	case m.Length == nil || *m.Length != 400 || m.Beam == nil || *m.Beam != 59:
+		t.Errorf("Length, Beam = %v, %v", m.Length, m.Beam)
+	case m.Draught == nil || *m.Draught != 15.7:
+		t.Errorf("Draught = %v", m.Draught)
+	case m.Destination == nil || *m.Destination != "ROTTERDAM":
+		t.Errorf("Destination = %v", m.Destination)
+	case m.ETA == nil || !m.ETA.Equal(eta):
+		t.Errorf("ETA = %v, want %v", m.ETA, eta)
+	}
+}
+
+func TestETA(t *testing.T) {
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		month, day, hour, minute uint64
+		want                     *time.Time
+	}{
+		{12, 24, 18, 0, ptr(time.Date(2024, 12, 24, 18, 0, 0, 0, time.UTC))},
+		// Early next year rather than almost a year ago.
+		{1, 5, 6, 30, ptr(time.Date(2025, 1, 5, 6, 30, 0, 0, time.UTC))},
+		// Recently passed ETAs stay in this year.
+		{11, 1, 0, 0, ptr(time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))},
+		{0, 0, 24, 60, nil},
+		{13, 1, 0, 0, nil},
+		{1, 1, 24, 0, nil},
+	}
+	for _, tt := range tests {
+		got := eta(tt.month, tt.day, tt.hour, tt.minute, now)
+		if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+			t.Errorf("eta(%d, %d, %d, %d) = %v, want %v", tt.month, tt.day, tt.hour, tt.minute, got, tt.want)
+		}
+	}
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestFeedFragments(t *testing.T) {
+	now := time.Date(2024, 3, 23, 8, 0, 0, 0, time.UTC)
+	payload, fill := staticVoyage().armor()
+	first, second := vdm(2, 1, "3", payload[:40], 0), vdm(2, 2, "3", payload[40:], fill)
+
+	tests := []struct {
+		name  string
+		lines []string
+		times []time.Duration
+		// complete is the index of the line completing the message, or -1.
+		complete int
+	}{
+		{"in order", []string{first, second}, []time.Duration{0, 0}, 1},
+		{"out of order", []string{second, first}, []time.Duration{0, 0}, 1},
+		{"duplicate first part restarts", []string{first, first, second}, []time.Duration{0, 0, 0}, 2},
+		{"expired first part", []string{first, second}, []time.Duration{0, fragmentTimeout + time.Second}, -1},
+		{"tag block", []string{`\s:rx1,c:1711180800*5A\` + first, second}, []time.Duration{0, 0}, 1},
+	}
+	for _, tt := range tests {
+		d := NewDecoder()
+		completed := -1
+		for i, line := range tt.lines {
+			res, err := d.Feed("", line, now.Add(tt.times[i]))
+			if err != nil {
+				t.Fatalf("%s: Feed(%q) = %v", tt.name, line, err)
+			}
+			if res != nil {
+				if completed >= 0 {
+					t.Errorf("%s: completed twice", tt.name)
+				}
+				completed = i
+				if res.Metadata == nil || res.Metadata.Name == nil || *res.Metadata.Name != "EVER GIVEN" {
+					t.Errorf("%s: Feed = %+v, want the reassembled message", tt.name, res.Metadata)
+				}
+			}
+		}
+		if completed != tt.complete {
+			t.Errorf("%s: completed at line %d, want %d", tt.name, completed, tt.complete)
+		}
+	}
+}
+
+func TestFeedSources(t *testing.T) {
+	now := time.Date(2024, 3, 23, 8, 0, 0, 0, time.UTC)
+	payload, fill := staticVoyage().armor()
+	first, second := vdm(2, 1, "3", payload[:40], 0), vdm(2, 2, "3", payload[40:], fill)
+
+	// Two receivers using the same sequential message ID at the same time.
+	d := NewDecoder()
+	feeds := []struct{ source, line string }{
+		{"tcp/10.0.0.1:4001", first},
+		{"udp/10.0.0.2:5001", first},
+		{"tcp/10.0.0.1:4001", second},
+		{"udp/10.0.0.2:5001", second},
+	}
+	var completed []int
+	for i, f := range feeds {
+		res, err := d.Feed(f.source, f.line, now)
+		if err != nil {
+			t.Fatalf("Feed(%q, %q) = %v", f.source, f.line, err)
+		}
+		if res != nil {
+			completed = append(completed, i)
+			if res.Metadata == nil || res.Metadata.Name == nil || *res.Metadata.Name != "EVER GIVEN" {
+				t.Errorf("Feed(%q) = %+v, want the reassembled message", f.source, res.Metadata)
+			}
+		}
+	}
+	if len(completed) != 2 || completed[0] != 2 || completed[1] != 3 {
+		t.Errorf("completed at lines %v, want [2 3]", completed)
+	}
+}
+
+func TestFeedErrors(t *testing.T) {
+	now := time.Date(2024, 3, 23, 8, 0, 0, 0, time.UTC)
+	tests := []string{
+		"$GPHDT,274.07,T*1B",
+		vdm(1, 2, "", "0", 0),
+		vdm(0, 1, "", "0", 0),
+		vdm(1, 1, "", "0", 0),
+		vdm(1, 1, "", "X", 0),
+		"!AIVDM,1,1,,A,0*00",
+	}
+	for _, line := range tests {
+		if res, err := NewDecoder().Feed("", line, now); err == nil {
+			t.Errorf("Feed(%q) = %+v, want error", line, res)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ais
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/middleware"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxReportedErrors caps how many rejected sentences are listed in a response.
+const maxReportedErrors = 100
+
+// IngestAIS accepts raw AIVDM/AIVDO sentences, one per line. Fragments of
+// multi-sentence messages must be sent in the same request.
+func IngestAIS(c *fiber.Ctx) error {
+	decoder := NewDecoder()
+	now := time.Now()
+	var batch []general.SensorData
+	var metadata []ShipMetadata
+	var rejected []models.RejectedItem
+	var sentences, ignored, rejectedCount int
+
+	scanner := bufio.NewScanner(middleware.RequestBodyReader(c))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		sentences++
+
+		result, err := decoder.Feed("", line, now)
+		if errors.Is(err, ErrUnsupported) {
+			ignored++
+			continue
+		}
+		if err != nil {
+			rejectedCount++
+			if len(rejected) < maxReportedErrors {
+				rejected = append(rejected, models.RejectedItem{Index: lineNo, Errors: []models.ErrorDetail{{
+					Loc:  []string{fmt.Sprintf("line %d", lineNo)},
+					Msg:  err.Error(),
+					Type: "ais_invalid",
+				}}})
+			}
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		batch = append(batch, validPoints(result.Points)...)
+		if result.Metadata != nil {
+			metadata = append(metadata, *result.Metadata)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Failed to read request body")
+	}
+
+	if err := enqueueResults(c.Context(), batch, metadata); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to queue AIS data")
+	}
+
+	status := http.StatusOK
+	if rejectedCount > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"status":         "AIS data received and queued",
+		"sentences":      sentences,
+		"points":         len(batch),
+		"ships_updated":  len(metadata),
+		"ignored":        ignored,
+		"rejected_count": rejectedCount,
+		"rejected":       rejected,
+	})
+}
+
+// validPoints drops points that fail the standard SensorData validation.
+func validPoints(points []general.SensorData) []general.SensorData {
+	valid := points[:0]
+	for _, p := range points {
+		if errs := utils.ValidateStruct(&p); len(errs) == 0 {
+			valid = append(valid, p)
+		}
+	}
+	return valid
+}
+
+// enqueueResults queues telemetry and metadata as separate messages.
+func enqueueResults(ctx context.Context, batch []general.SensorData, metadata []ShipMetadata) error {
+	if len(batch) > 0 {
+		if _, err := general.Enqueue(ctx, batch, false); err != nil {
+			return err
+		}
+	}
+	if len(metadata) > 0 {
+		if _, err := general.EnqueueMessage(ctx, QueueType, metadata, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
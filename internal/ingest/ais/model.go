@@ -0,0 +1,26 @@
+// Package ais decodes AIVDM/AIVDO sentences from AIS receivers. Position
+// reports become telemetry points keyed by MMSI, and static and voyage data
+// become ship metadata records.
+package ais
+
+import "time"
+
+// QueueType is the queue message type for ship metadata records.
+const QueueType = "ship_metadata"
+
+// ShipMetadata holds static and voyage data for a ship. Fields that a message
+// does not carry are nil, so partial updates (e.g. type 24 part A or B) can be
+// merged into the stored record.
+type ShipMetadata struct {
+	ShipID      string     `json:"ship_id"`
+	Name        *string    `json:"name,omitempty"`
+	CallSign    *string    `json:"call_sign,omitempty"`
+	IMO         *int64     `json:"imo,omitempty"`
+	ShipType    *int64     `json:"ship_type,omitempty"`
+	Length      *int64     `json:"length,omitempty"` // metres, bow to stern
+	Beam        *int64     `json:"beam,omitempty"`   // metres, port to starboard
+	Draught     *float64   `json:"draught,omitempty"`
+	Destination *string    `json:"destination,omitempty"`
+	ETA         *time.Time `json:"eta,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
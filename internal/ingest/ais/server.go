@@ -0,0 +1,60 @@
+package ais
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ingest/listener"
+)
+
+// Server receives AIS sentences over TCP and UDP, e.g. from shore receivers
+// or an AIS feed aggregator.
+type Server struct {
+	Addr    string
+	Batcher *listener.Batcher
+	decoder *Decoder
+}
+
+// ListenAndServe serves TCP and UDP on the same address until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.decoder = NewDecoder()
+	handle := func(from net.Addr, line string) { s.handleLine(ctx, from, line) }
+
+	errChan := make(chan error, 2)
+	go func() { errChan <- listener.ServeTCPFrom(ctx, "AIS", s.Addr, handle) }()
+	go func() { errChan <- listener.ServeUDPFrom(ctx, "AIS", s.Addr, handle) }()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errChan; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleLine(ctx context.Context, from net.Addr, line string) {
+	// Receivers number their multi-sentence messages independently.
+	result, err := s.decoder.Feed(from.Network()+"/"+from.String(), line, time.Now())
+	if err != nil {
+		if !errors.Is(err, ErrUnsupported) {
+			log.Printf("[AIS] Skipping sentence %q: %v", line, err)
+		}
+		return
+	}
+	if result == nil {
+		return
+	}
+
+	for _, p := range result.Points {
+		s.Batcher.Add(p)
+	}
+	if result.Metadata != nil {
+		if _, err := general.EnqueueMessage(ctx, QueueType, []ShipMetadata{*result.Metadata}, false); err != nil {
+			log.Printf("[AIS] Failed to queue metadata for %s: %v", result.Metadata.ShipID, err)
+		}
+	}
+}
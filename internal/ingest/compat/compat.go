@@ -0,0 +1,44 @@
+// Package compat provides ingest endpoints that speak other time-series
+// backends' write APIs (OpenTSDB, Datadog), so existing agents can be pointed
+// at Telemetry Harbor without reconfiguration.
+//
+// Metric names become cargo IDs. The ship_id is taken from the tag named by
+// COMPAT_SHIP_TAG (default "ship"), falling back to the host.
+package compat
+
+import (
+	"math"
+	"time"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+)
+
+// epochTime converts seconds, or milliseconds for values too large to be
+// seconds, to a timestamp.
+func epochTime(ts float64) time.Time {
+	if ts > 1e11 {
+		ts /= 1000
+	}
+	whole, frac := math.Modf(ts)
+	return time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3).UTC()
+}
+
+// shipFromTags picks the ship_id from the configured ship tag or the host.
+func shipFromTags(tags map[string]string, host string) string {
+	if ship := tags[config.AppConfig.CompatShipTag]; ship != "" {
+		return ship
+	}
+	if host != "" {
+		return host
+	}
+	return tags["host"]
+}
+
+// point builds and validates a data point, returning the validation errors if any.
+func point(ts time.Time, shipID, metric string, value float64) (general.SensorData, []models.ErrorDetail) {
+	data := general.SensorData{Time: ts, ShipID: shipID, CargoID: metric, Value: &value}
+	return data, utils.ValidateStruct(&data)
+}
@@ -0,0 +1,153 @@
+package compat
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/ingest/general"
+)
+
+type sample struct {
+	ship, cargo string
+	time        time.Time
+	value       float64
+}
+
+func pointsOf(batch []general.SensorData) []sample {
+	var got []sample
+	for _, d := range batch {
+		got = append(got, sample{d.ShipID, d.CargoID, d.Time, *d.Value})
+	}
+	return got
+}
+
+func samePoints(got, want []sample) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i].ship != want[i].ship || got[i].cargo != want[i].cargo || !got[i].time.Equal(want[i].time) || got[i].value != want[i].value {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEpochTime(t *testing.T) {
+	tests := []struct {
+		ts   float64
+		want time.Time
+	}{
+		{1700000000, time.Unix(1700000000, 0)},
+		{1700000000.25, time.Unix(1700000000, 250e6)},
+		{1700000000123, time.Unix(1700000000, 123e6)},
+		{1e11, time.Unix(1e11, 0)},
+	}
+	for _, tt := range tests {
+		if got := epochTime(tt.ts); !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("epochTime(%v) = %v, want %v", tt.ts, got, tt.want.UTC())
+		}
+	}
+}
+
+func TestConvertOpenTSDB(t *testing.T) {
+	config.AppConfig = &config.Config{CompatShipTag: "ship"}
+	ts := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		json    string
+		want    sample
+		wantErr bool
+	}{
+		{"seconds", `{"metric":"engine.rpm","timestamp":1700000000,"value":900,"tags":{"ship":"vessel_1","host":"gw"}}`, sample{"vessel_1", "engine.rpm", ts, 900}, false},
+		{"milliseconds", `{"metric":"temp","timestamp":1700000000500,"value":1.5,"tags":{"ship":"vessel_1"}}`, sample{"vessel_1", "temp", ts.Add(500 * time.Millisecond), 1.5}, false},
+		{"value as string", `{"metric":"temp","timestamp":1700000000,"value":"-2.5e1","tags":{"ship":"vessel_1"}}`, sample{"vessel_1", "temp", ts, -25}, false},
+		{"host fallback", `{"metric":"temp","timestamp":1700000000,"value":1,"tags":{"host":"vessel_2"}}`, sample{"vessel_2", "temp", ts, 1}, false},
+		{"no ship", `{"metric":"temp","timestamp":1700000000,"value":1,"tags":{"dc":"eu"}}`, sample{}, true},
+		{"no metric", `{"timestamp":1700000000,"value":1,"tags":{"ship":"vessel_1"}}`, sample{}, true},
+		{"zero timestamp", `{"metric":"temp","timestamp":0,"value":1,"tags":{"ship":"vessel_1"}}`, sample{}, true},
+	}
+	for _, tt := range tests {
+		var p openTSDBPoint
+		if err := json.Unmarshal([]byte(tt.json), &p); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		data, err := convertOpenTSDB(p)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: convertOpenTSDB() error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !samePoints(pointsOf([]general.SensorData{data}), []sample{tt.want}) {
+			t.Errorf("%s: convertOpenTSDB() = %+v, want %+v", tt.name, pointsOf([]general.SensorData{data}), tt.want)
+		}
+	}
+}
+
+func TestConvertOpenTSDBInvalidNumbers(t *testing.T) {
+	config.AppConfig = &config.Config{CompatShipTag: "ship"}
+	tags := map[string]string{"ship": "vessel_1"}
+	for _, p := range []openTSDBPoint{
+		{Metric: "temp", Timestamp: "soon", Value: "1", Tags: tags},
+		{Metric: "temp", Timestamp: "1700000000", Value: "warm", Tags: tags},
+	} {
+		if _, err := convertOpenTSDB(p); err == nil {
+			t.Errorf("convertOpenTSDB(%+v) succeeded, want error", p)
+		}
+	}
+}
+
+func TestConvertDatadog(t *testing.T) {
+	config.AppConfig = &config.Config{CompatShipTag: "ship"}
+	ts := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		json       string
+		want       []sample
+		wantErrors int
+	}{
+		{
+			name: "ship tag, host and host tag",
+			json: `[
+				{"metric":"engine.rpm","points":[[1700000000,900],[1700000010,910]],"host":"gw","tags":["ship:vessel_1","env:prod"]},
+				{"metric":"temp","points":[[1700000000,4.5]],"host":"vessel_2","tags":["host:other"]},
+				{"metric":"temp","points":[[1700000000,5]],"tags":["host:vessel_3"]}
+			]`,
+			want: []sample{
+				{"vessel_1", "engine.rpm", ts, 900},
+				{"vessel_1", "engine.rpm", ts.Add(10 * time.Second), 910},
+				{"vessel_2", "temp", ts, 4.5},
+				{"vessel_3", "temp", ts, 5},
+			},
+		},
+		{
+			name: "tag values may contain colons",
+			json: `[{"metric":"temp","points":[[1700000000,1]],"tags":["ship:imo:9811000"]}]`,
+			want: []sample{{"imo:9811000", "temp", ts, 1}},
+		},
+		{
+			name: "null timestamps and values are skipped",
+			json: `[{"metric":"temp","points":[[null,1],[1700000000,null],[1700000000,2]],"host":"vessel_1"}]`,
+			want: []sample{{"vessel_1", "temp", ts, 2}},
+		},
+		{
+			name:       "points without a ship are invalid",
+			json:       `[{"metric":"temp","points":[[1700000000,1],[1700000001,2]]}]`,
+			wantErrors: 2,
+		},
+	}
+	for _, tt := range tests {
+		var series []datadogSeries
+		if err := json.Unmarshal([]byte(tt.json), &series); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		batch, errs := convertDatadog(series)
+		if len(errs) != tt.wantErrors {
+			t.Errorf("%s: %d validation errors, want %d: %+v", tt.name, len(errs), tt.wantErrors, errs)
+		}
+		if got := pointsOf(batch); !samePoints(got, tt.want) {
+			t.Errorf("%s: convertDatadog() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
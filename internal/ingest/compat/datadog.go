@@ -0,0 +1,74 @@
+package compat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// datadogSeries is one entry of a Datadog v1 `POST /api/v1/series` payload.
+type datadogSeries struct {
+	Metric string        `json:"metric"`
+	Points [][2]*float64 `json:"points"` // [timestamp, value]
+	Host   string        `json:"host"`
+	Tags   []string      `json:"tags"`
+	Type   string        `json:"type"`
+}
+
+// DatadogSeries implements Datadog's v1 series endpoint. Tags in "key:value"
+// form are used for the ship_id lookup; the points of all series are queued
+// as one batch, and the batch is rejected if any point is invalid.
+func DatadogSeries(c *fiber.Ctx) error {
+	var payload struct {
+		Series []datadogSeries `json:"series"`
+	}
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid series payload")
+	}
+
+	batch, validationErrors := convertDatadog(payload.Series)
+	if len(validationErrors) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(validationErrors))
+	}
+	if len(batch) > 0 {
+		if _, err := general.Enqueue(c.Context(), batch, false); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to queue data for ingestion")
+		}
+	}
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"status": "ok"})
+}
+
+// convertDatadog turns series into points, skipping points without a
+// timestamp or value.
+func convertDatadog(series []datadogSeries) ([]general.SensorData, []models.ErrorDetail) {
+	var batch []general.SensorData
+	var validationErrors []models.ErrorDetail
+	for _, s := range series {
+		tags := make(map[string]string, len(s.Tags))
+		for _, tag := range s.Tags {
+			if k, v, ok := strings.Cut(tag, ":"); ok {
+				tags[k] = v
+			}
+		}
+		shipID := shipFromTags(tags, s.Host)
+
+		for _, p := range s.Points {
+			if p[0] == nil || p[1] == nil {
+				continue
+			}
+			data, errs := point(epochTime(*p[0]), shipID, s.Metric, *p[1])
+			if len(errs) > 0 {
+				validationErrors = append(validationErrors, errs...)
+				continue
+			}
+			batch = append(batch, data)
+		}
+	}
+	return batch, validationErrors
+}
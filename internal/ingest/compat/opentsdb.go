@@ -0,0 +1,106 @@
+package compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-ingest-service/internal/ingest/general"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// openTSDBPoint is a data point in the OpenTSDB /api/put format.
+type openTSDBPoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp json.Number       `json:"timestamp"`
+	Value     json.Number       `json:"value"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// openTSDBError mirrors the error entries of OpenTSDB's detailed response.
+type openTSDBError struct {
+	Datapoint openTSDBPoint `json:"datapoint"`
+	Error     string        `json:"error"`
+}
+
+// OpenTSDBPut implements OpenTSDB's `POST /api/put` for a single point or an
+// array of points. Like OpenTSDB it answers 204 on success, and with the
+// `summary` or `details` query parameters returns success/failure counts.
+func OpenTSDBPut(c *fiber.Ctx) error {
+	body := bytes.TrimSpace(c.Body())
+	var points []openTSDBPoint
+	var err error
+	if len(body) > 0 && body[0] == '{' {
+		var single openTSDBPoint
+		err = json.Unmarshal(body, &single)
+		points = []openTSDBPoint{single}
+	} else {
+		err = json.Unmarshal(body, &points)
+	}
+	if err != nil || len(points) == 0 {
+		return fiber.NewError(http.StatusBadRequest, "Invalid OpenTSDB put request")
+	}
+
+	batch := make([]general.SensorData, 0, len(points))
+	var failures []openTSDBError
+	for _, p := range points {
+		data, err := convertOpenTSDB(p)
+		if err != nil {
+			failures = append(failures, openTSDBError{Datapoint: p, Error: err.Error()})
+			continue
+		}
+		batch = append(batch, data)
+	}
+
+	if len(batch) > 0 {
+		if _, err := general.Enqueue(c.Context(), batch, false); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to queue data for ingestion")
+		}
+	}
+
+	status := http.StatusNoContent
+	if len(failures) > 0 {
+		status = http.StatusBadRequest
+	}
+	_, details := c.Queries()["details"]
+	_, summary := c.Queries()["summary"]
+	if !details && !summary {
+		if status == http.StatusBadRequest {
+			return fiber.NewError(status, fmt.Sprintf("%d of %d data points failed; use ?details for more information", len(failures), len(points)))
+		}
+		return c.SendStatus(status)
+	}
+
+	resp := fiber.Map{"success": len(batch), "failed": len(failures)}
+	if details {
+		if failures == nil {
+			failures = []openTSDBError{}
+		}
+		resp["errors"] = failures
+	}
+	if status == http.StatusNoContent {
+		status = http.StatusOK
+	}
+	return c.Status(status).JSON(resp)
+}
+
+func convertOpenTSDB(p openTSDBPoint) (general.SensorData, error) {
+	ts, err := strconv.ParseFloat(p.Timestamp.String(), 64)
+	if err != nil || ts <= 0 {
+		return general.SensorData{}, fmt.Errorf("invalid timestamp")
+	}
+	value, err := strconv.ParseFloat(p.Value.String(), 64)
+	if err != nil {
+		return general.SensorData{}, fmt.Errorf("invalid value")
+	}
+
+	data, errs := point(epochTime(ts), shipFromTags(p.Tags, ""), p.Metric, value)
+	if len(errs) > 0 {
+		return general.SensorData{}, fmt.Errorf("%s: %s", strings.Join(errs[0].Loc, "."), errs[0].Msg)
+	}
+	return data, nil
+}
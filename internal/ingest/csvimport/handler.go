@@ -0,0 +1,89 @@
+package csvimport
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ImportCSV accepts a CSV upload and starts a background import job.
+// The column mapping is taken from query parameters; the body is spooled to a
+// temporary file so the request can return immediately with the job ID.
+func ImportCSV(c *fiber.Ctx) error {
+	mapping := Mapping{
+		TimeColumn: c.Query("time_column"),
+		TimeFormat: c.Query("time_format"),
+		ShipColumn: c.Query("ship_column"),
+		ShipID:     c.Query("ship_id"),
+		Delimiter:  c.Query("delimiter"),
+	}
+	if cols := c.Query("cargo_columns"); cols != "" {
+		for _, col := range strings.Split(cols, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				mapping.CargoColumns = append(mapping.CargoColumns, col)
+			}
+		}
+	}
+	if err := mapping.Validate(); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid column mapping: "+err.Error())
+	}
+
+	spool, err := os.CreateTemp("", "harbor-import-*.csv")
+	if err != nil {
+		log.Printf("[Import] Failed to create spool file: %v", err)
+		return fiber.NewError(http.StatusInternalServerError, "Failed to store upload")
+	}
+	if _, err := io.Copy(spool, middleware.RequestBodyReader(c)); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		if errors.Is(err, middleware.ErrBodyTooLarge) {
+			return fiber.NewError(http.StatusRequestEntityTooLarge, "CSV upload exceeds the import size limit")
+		}
+		return fiber.NewError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return fiber.NewError(http.StatusInternalServerError, "Failed to store upload")
+	}
+
+	job := &Job{
+		ID:        uuid.NewString(),
+		Status:    StatusPending,
+		Mapping:   mapping,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := SaveJob(c.Context(), job); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create import job")
+	}
+
+	Start(job, spool)
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"status": "Import started",
+		"job_id": job.ID,
+	})
+}
+
+// GetImportJob returns the progress and error report of an import job.
+func GetImportJob(c *fiber.Ctx) error {
+	job, err := LoadJob(c.Context(), c.Params("id"))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load import job")
+	}
+	if job == nil {
+		return fiber.NewError(http.StatusNotFound, "Import job not found")
+	}
+	return c.JSON(job)
+}
@@ -0,0 +1,235 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/utils"
+)
+
+// Imports run in the background, detached from the request that started them.
+// They share a context that is cancelled on shutdown, so Shutdown can stop
+// them and wait until each has recorded its final status.
+var (
+	importCtx, cancelImports = context.WithCancel(context.Background())
+	imports                  sync.WaitGroup
+)
+
+// Start runs an import job in the background, removing the spooled upload
+// once the job has finished.
+func Start(job *Job, spool *os.File) {
+	imports.Add(1)
+	go func() {
+		defer imports.Done()
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+		Run(importCtx, job, spool)
+	}()
+}
+
+// Shutdown cancels running imports and waits for them to be marked failed.
+func Shutdown() {
+	cancelImports()
+	imports.Wait()
+}
+
+// Run reads CSV rows from r, converts them to data points using the job's mapping
+// and queues them in chunks. Progress is saved after every chunk.
+func Run(ctx context.Context, job *Job, r io.Reader) {
+	job.Status = StatusRunning
+	saveProgress(ctx, job)
+
+	if err := importRows(ctx, job, r); err != nil {
+		log.Printf("[Import] Job %s failed after %d rows: %v", job.ID, job.RowsProcessed, err)
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		if ctx.Err() != nil {
+			job.Error = "import interrupted by server shutdown"
+		}
+	} else {
+		job.Status = StatusCompleted
+	}
+
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	// The final status must be saved even when the import was cancelled.
+	saveProgress(context.WithoutCancel(ctx), job)
+	log.Printf("[Import] Job %s %s: %d rows, %d points queued, %d errors",
+		job.ID, job.Status, job.RowsProcessed, job.PointsQueued, job.ErrorCount)
+}
+
+func importRows(ctx context.Context, job *Job, r io.Reader) error {
+	m := job.Mapping
+	reader := csv.NewReader(r)
+	reader.Comma = m.delimiter()
+	reader.ReuseRecord = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	header = append([]string(nil), header...)
+
+	timeIdx, shipIdx, cargoIdx, err := resolveColumns(header, m)
+	if err != nil {
+		return err
+	}
+
+	chunkSize := config.AppConfig.CSVImportChunkSize
+	chunk := make([]general.SensorData, 0, chunkSize)
+	// Backfills yield to live traffic by waiting whenever the queue is backed up.
+	flow := &general.QueueBackpressure{Threshold: config.AppConfig.CSVImportBackpressureThreshold}
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if _, over := flow.Over(ctx); over {
+			if _, err := flow.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		if _, err := general.Enqueue(ctx, chunk, false); err != nil {
+			return fmt.Errorf("failed to queue data: %w", err)
+		}
+		job.PointsQueued += len(chunk)
+		chunk = make([]general.SensorData, 0, chunkSize)
+		saveProgress(ctx, job)
+		return nil
+	}
+
+	for row := 1; ; row++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		job.RowsProcessed++
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				job.addError(RowError{Row: row, Msg: err.Error()})
+				continue
+			}
+			return err
+		}
+
+		points, rowErrs := convertRow(record, row, header, m, timeIdx, shipIdx, cargoIdx)
+		for _, e := range rowErrs {
+			job.addError(e)
+		}
+		chunk = append(chunk, points...)
+		if len(chunk) >= chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// resolveColumns finds the indexes of the time, ship and cargo columns in the header.
+func resolveColumns(header []string, m Mapping) (timeIdx, shipIdx int, cargoIdx []int, err error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+
+	timeIdx, ok := index[m.TimeColumn]
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("time column %q not found in header", m.TimeColumn)
+	}
+
+	shipIdx = -1
+	if m.ShipColumn != "" {
+		if shipIdx, ok = index[m.ShipColumn]; !ok {
+			return 0, 0, nil, fmt.Errorf("ship column %q not found in header", m.ShipColumn)
+		}
+	}
+
+	if len(m.CargoColumns) > 0 {
+		for _, name := range m.CargoColumns {
+			i, ok := index[name]
+			if !ok {
+				return 0, 0, nil, fmt.Errorf("cargo column %q not found in header", name)
+			}
+			cargoIdx = append(cargoIdx, i)
+		}
+	} else {
+		for i := range header {
+			if i != timeIdx && i != shipIdx {
+				cargoIdx = append(cargoIdx, i)
+			}
+		}
+	}
+
+	if len(cargoIdx) == 0 {
+		return 0, 0, nil, fmt.Errorf("no cargo columns to import")
+	}
+	return timeIdx, shipIdx, cargoIdx, nil
+}
+
+// convertRow expands one wide-format CSV row into a data point per non-empty cargo column.
+func convertRow(record []string, row int, header []string, m Mapping, timeIdx, shipIdx int, cargoIdx []int) ([]general.SensorData, []RowError) {
+	var errs []RowError
+	cell := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	ts, err := m.parseTime(cell(timeIdx))
+	if err != nil {
+		return nil, []RowError{{Row: row, Column: m.TimeColumn, Msg: "invalid time: " + err.Error()}}
+	}
+
+	shipID := m.ShipID
+	if shipIdx >= 0 {
+		shipID = cell(shipIdx)
+	}
+
+	points := make([]general.SensorData, 0, len(cargoIdx))
+	for _, i := range cargoIdx {
+		raw := cell(i)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			errs = append(errs, RowError{Row: row, Column: header[i], Msg: "invalid number: " + raw})
+			continue
+		}
+
+		point := general.SensorData{
+			Time:    ts,
+			ShipID:  shipID,
+			CargoID: strings.TrimSpace(header[i]),
+			Value:   &value,
+		}
+		if vErrs := utils.ValidateStruct(&point); len(vErrs) > 0 {
+			errs = append(errs, RowError{Row: row, Column: header[i], Msg: vErrs[0].Msg + " (" + vErrs[0].Loc[0] + ")"})
+			continue
+		}
+		points = append(points, point)
+	}
+	return points, errs
+}
+
+// saveProgress persists the job report, logging rather than failing the import on errors.
+func saveProgress(ctx context.Context, job *Job) {
+	if err := SaveJob(ctx, job); err != nil {
+		log.Printf("[Import] Failed to save progress for job %s: %v", job.ID, err)
+	}
+}
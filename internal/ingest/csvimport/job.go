@@ -0,0 +1,85 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// jobTTL is how long import job reports are kept after their last update.
+const jobTTL = 24 * time.Hour
+
+// maxReportedRowErrors caps the number of row errors kept in a job report.
+const maxReportedRowErrors = 100
+
+// Job status values.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// RowError describes a CSV row (1-based, header excluded) that could not be imported.
+type RowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Msg    string `json:"msg"`
+}
+
+// Job is the progress and error report of a CSV import.
+type Job struct {
+	ID            string     `json:"job_id"`
+	Status        string     `json:"status"`
+	Mapping       Mapping    `json:"mapping"`
+	RowsProcessed int        `json:"rows_processed"`
+	PointsQueued  int        `json:"points_queued"`
+	ErrorCount    int        `json:"error_count"`
+	Errors        []RowError `json:"errors,omitempty"`
+	Error         string     `json:"error,omitempty"` // Fatal error that stopped the import.
+	CreatedAt     time.Time  `json:"created_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// addError records a row error, keeping only the first maxReportedRowErrors in the report.
+func (j *Job) addError(e RowError) {
+	j.ErrorCount++
+	if len(j.Errors) < maxReportedRowErrors {
+		j.Errors = append(j.Errors, e)
+	}
+}
+
+func jobKey(id string) string {
+	return config.AppConfig.IngestQueueName + "_import:" + id
+}
+
+// SaveJob stores the current state of an import job.
+func SaveJob(ctx context.Context, job *Job) error {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return cache.RedisClient.Set(ctx, jobKey(job.ID), jobJSON, jobTTL).Err()
+}
+
+// LoadJob fetches an import job report. It returns nil if the job does not exist.
+func LoadJob(ctx context.Context, id string) (*Job, error) {
+	jobJSON, err := cache.RedisClient.Get(ctx, jobKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(jobJSON, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
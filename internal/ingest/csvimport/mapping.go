@@ -0,0 +1,71 @@
+package csvimport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping describes how the columns of a CSV file map onto SensorData fields.
+// Every column that is not the time or ship column is treated as a cargo_id
+// (wide format) unless CargoColumns restricts the set.
+type Mapping struct {
+	TimeColumn   string   `json:"time_column"`
+	TimeFormat   string   `json:"time_format"` // "rfc3339", "unix", "unix_ms" or a Go time layout
+	ShipColumn   string   `json:"ship_column,omitempty"`
+	ShipID       string   `json:"ship_id,omitempty"` // Fixed ship_id when there is no ship column.
+	CargoColumns []string `json:"cargo_columns,omitempty"`
+	Delimiter    string   `json:"delimiter,omitempty"`
+}
+
+// Validate checks that the mapping is usable before any rows are read.
+func (m *Mapping) Validate() error {
+	if m.TimeColumn == "" {
+		m.TimeColumn = "time"
+	}
+	if m.TimeFormat == "" {
+		m.TimeFormat = "rfc3339"
+	}
+	if m.ShipColumn == "" && m.ShipID == "" {
+		return fmt.Errorf("either ship_column or ship_id is required")
+	}
+	if m.ShipColumn != "" && m.ShipID != "" {
+		return fmt.Errorf("ship_column and ship_id are mutually exclusive")
+	}
+	if len([]rune(m.Delimiter)) > 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	return nil
+}
+
+// delimiter returns the field separator, defaulting to a comma.
+func (m *Mapping) delimiter() rune {
+	if m.Delimiter == "" {
+		return ','
+	}
+	return []rune(m.Delimiter)[0]
+}
+
+// parseTime converts a time cell according to the mapping's time format.
+func (m *Mapping) parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(m.TimeFormat) {
+	case "rfc3339":
+		return time.Parse(time.RFC3339Nano, value)
+	case "unix":
+		secs, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(secs*float64(time.Second))).UTC(), nil
+	case "unix_ms":
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	default:
+		return time.Parse(m.TimeFormat, value)
+	}
+}
@@ -0,0 +1,98 @@
+package general
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ackTTL bounds how long an unread acknowledgement is kept in Redis.
+const ackTTL = 5 * time.Minute
+
+// ackWaiters limits how many synchronous requests wait at once. Each holds a
+// pooled Redis connection in BLPOP while it waits, and too many of them would
+// starve everything else that uses the pool.
+var (
+	ackWaitersOnce sync.Once
+	ackWaiters     chan struct{}
+)
+
+// AckKey returns the Redis list the worker pushes the acknowledgement for a message to.
+func AckKey(id string) string {
+	return config.AppConfig.IngestQueueName + "_ack:" + id
+}
+
+// PublishAck stores the outcome of a queued message for a waiting synchronous request.
+func PublishAck(ctx context.Context, ack models.IngestAck) error {
+	ackJSON, err := json.Marshal(ack)
+	if err != nil {
+		return err
+	}
+	key := AckKey(ack.ID)
+	pipe := cache.RedisClient.TxPipeline()
+	pipe.RPush(ctx, key, ackJSON)
+	pipe.Expire(ctx, key, ackTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// WaitForAck blocks until the worker acknowledges the message or the timeout elapses.
+// A nil acknowledgement with a nil error means the timeout was reached, or that
+// SYNC_INGEST_MAX_WAITERS requests were already waiting.
+func WaitForAck(ctx context.Context, id string, timeout time.Duration) (*models.IngestAck, error) {
+	ackWaitersOnce.Do(func() {
+		ackWaiters = make(chan struct{}, config.AppConfig.SyncIngestMaxWaiters)
+	})
+	select {
+	case ackWaiters <- struct{}{}:
+		defer func() { <-ackWaiters }()
+	default:
+		return nil, nil
+	}
+
+	result, err := cache.RedisClient.BLPop(ctx, timeout, AckKey(id)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ack models.IngestAck
+	if err := json.Unmarshal([]byte(result[1]), &ack); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
+
+// syncTimeout reports whether the client asked to wait for the database commit,
+// either with `?sync=true` or a `Prefer: wait` header, and how long to wait.
+// A `Prefer: wait=N` value (seconds) may shorten, but never extend, the configured timeout.
+func syncTimeout(c *fiber.Ctx) (time.Duration, bool) {
+	timeout := config.AppConfig.SyncIngestTimeout
+	sync := c.QueryBool("sync", false)
+	for _, pref := range strings.Split(c.Get("Prefer"), ",") {
+		pref = strings.ToLower(strings.TrimSpace(pref))
+		if pref == "wait" {
+			sync = true
+			continue
+		}
+		if v, ok := strings.CutPrefix(pref, "wait="); ok {
+			sync = true
+			if secs, err := strconv.Atoi(v); err == nil && secs > 0 && time.Duration(secs)*time.Second < timeout {
+				timeout = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return timeout, sync
+}
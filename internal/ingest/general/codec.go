@@ -0,0 +1,205 @@
+package general
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Supported binary payload encodings, negotiated from the Content-Type header.
+const (
+	encodingJSON    = "json"
+	encodingMsgpack = "msgpack"
+	encodingCBOR    = "cbor"
+)
+
+// binaryPoint is the wire form of SensorData for MessagePack and CBOR payloads.
+// Time may be a native timestamp, an RFC3339 string or an epoch number, and Value
+// may be any numeric type, so constrained devices can use compact encodings.
+// Values holds the readings of a wide-format point.
+type binaryPoint struct {
+	Time    interface{}            `msgpack:"time"     cbor:"time"`
+	ShipID  string                 `msgpack:"ship_id"  cbor:"ship_id"`
+	CargoID string                 `msgpack:"cargo_id" cbor:"cargo_id"`
+	Value   interface{}            `msgpack:"value"    cbor:"value"`
+	Values  map[string]interface{} `msgpack:"values"   cbor:"values"`
+}
+
+// jsonPoint is the wire form of SensorData for JSON payloads. Time is kept raw
+// so that it may be omitted, an RFC3339 string or an epoch number. Values
+// holds the readings of a wide-format point, keyed by cargo ID.
+type jsonPoint struct {
+	Time    json.RawMessage     `json:"time"`
+	ShipID  string              `json:"ship_id"`
+	CargoID string              `json:"cargo_id"`
+	Value   *float64            `json:"value"`
+	Values  map[string]*float64 `json:"values"`
+}
+
+// payloadEncoding maps the request Content-Type onto a supported encoding.
+// Anything that is not a binary encoding is treated as JSON, as before.
+func payloadEncoding(c *fiber.Ctx) string {
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(c.Get(fiber.HeaderContentType), ";")[0]))
+	switch contentType {
+	case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		return encodingMsgpack
+	case "application/cbor":
+		return encodingCBOR
+	default:
+		return encodingJSON
+	}
+}
+
+// decodeSingle decodes one data point, long or wide format, in the negotiated
+// encoding. An error means the body is malformed; invalid fields are reported
+// when the result is validated.
+func decodeSingle(encoding string, body []byte, tp *TimeParser) (*decodedPoints, error) {
+	d := &decodedPoints{}
+	if encoding == encodingJSON {
+		var point jsonPoint
+		if err := json.Unmarshal(body, &point); err != nil {
+			return nil, err
+		}
+		point.expand(d, 0, tp)
+		return d, nil
+	}
+
+	var point binaryPoint
+	if err := unmarshalBinary(encoding, body, &point); err != nil {
+		return nil, err
+	}
+	if err := point.expand(d, 0, tp); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// decodeBatch decodes an array of data points, each long or wide format, in
+// the negotiated encoding.
+func decodeBatch(encoding string, body []byte, tp *TimeParser) (*decodedPoints, error) {
+	d := &decodedPoints{batch: true}
+	if encoding == encodingJSON {
+		var points []jsonPoint
+		if err := json.Unmarshal(body, &points); err != nil {
+			return nil, err
+		}
+		for i := range points {
+			points[i].expand(d, i, tp)
+		}
+		return d, nil
+	}
+
+	var points []binaryPoint
+	if err := unmarshalBinary(encoding, body, &points); err != nil {
+		return nil, err
+	}
+	for i := range points {
+		if err := points[i].expand(d, i, tp); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return d, nil
+}
+
+func unmarshalBinary(encoding string, body []byte, v interface{}) error {
+	if encoding == encodingCBOR {
+		return cbor.Unmarshal(body, v)
+	}
+	return msgpack.Unmarshal(body, v)
+}
+
+// expand adds the rows of a JSON point to d.
+func (p jsonPoint) expand(d *decodedPoints, index int, tp *TimeParser) {
+	raw, err := rawJSONTime(p.Time)
+	var ts time.Time
+	if err == nil {
+		ts, err = tp.Parse(raw)
+	}
+	item := d.addItem(index, ts, err, tp)
+
+	if p.Values == nil {
+		d.addRow(item, "", SensorData{Time: item.time, ShipID: p.ShipID, CargoID: p.CargoID, Value: p.Value})
+		return
+	}
+	if !d.checkWide(item, p.CargoID != "" || p.Value != nil, len(p.Values)) {
+		return
+	}
+	for _, cargoID := range sortedKeys(p.Values) {
+		d.addRow(item, cargoID, SensorData{Time: item.time, ShipID: p.ShipID, CargoID: cargoID, Value: p.Values[cargoID]})
+	}
+}
+
+// expand adds the rows of a binary point to d. An error means a value is not numeric.
+func (p binaryPoint) expand(d *decodedPoints, index int, tp *TimeParser) error {
+	ts, err := tp.Parse(p.Time)
+	item := d.addItem(index, ts, err, tp)
+
+	if p.Values == nil {
+		value, err := binaryValue(p.Value)
+		if err != nil {
+			return err
+		}
+		d.addRow(item, "", SensorData{Time: item.time, ShipID: p.ShipID, CargoID: p.CargoID, Value: value})
+		return nil
+	}
+	if !d.checkWide(item, p.CargoID != "" || p.Value != nil, len(p.Values)) {
+		return nil
+	}
+	for _, cargoID := range sortedKeys(p.Values) {
+		value, err := binaryValue(p.Values[cargoID])
+		if err != nil {
+			return fmt.Errorf("values.%s: %w", cargoID, err)
+		}
+		d.addRow(item, cargoID, SensorData{Time: item.time, ShipID: p.ShipID, CargoID: cargoID, Value: value})
+	}
+	return nil
+}
+
+// binaryValue converts a loosely typed value. A missing value is left nil so
+// the usual validation reports it.
+func binaryValue(v interface{}) (*float64, error) {
+	if v == nil {
+		return nil, nil
+	}
+	f, ok := toFloat64(v)
+	if !ok {
+		return nil, fmt.Errorf("value must be numeric, got %T", v)
+	}
+	return &f, nil
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
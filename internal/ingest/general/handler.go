@@ -1,88 +1,104 @@
 package general
 
 import (
-	"encoding/json"
-	"fmt"
+	"log"
 	"net/http"
+	"time"
 
-	"go-ingest-service/internal/cache"
-	"go-ingest-service/internal/config"
 	"go-ingest-service/internal/models"
-	"go-ingest-service/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// IngestData handles single data point ingestion.
+// IngestData handles single data point ingestion. A wide-format point
+// (`values` instead of `cargo_id`/`value`) is queued as a batch of its rows.
 func IngestData(c *fiber.Ctx) error {
 	rawBody := c.Body()
-	fmt.Printf("Raw request body: %s\n", string(rawBody))
+	encoding := payloadEncoding(c)
+	tp, err := NewTimeParser(c)
+	if err != nil {
+		return err
+	}
 
-	var data SensorData
-	if err := json.Unmarshal(rawBody, &data); err != nil {
-		fmt.Printf("JSON Unmarshal error: %v\n", err)
+	decoded, err := decodeSingle(encoding, rawBody, tp)
+	if err != nil {
+		log.Printf("[Ingest] Failed to decode %s body: %v", encoding, err)
 		return fiber.NewError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if validationErrors := utils.ValidateStruct(&data); len(validationErrors) > 0 {
-		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(validationErrors))
+	rows, rejected := decoded.split()
+	if len(rejected) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(flattenRejected(rejected)))
 	}
 
-	queuedData := models.QueuedData{
-		RetryCount: 0,
-		Type:       "general",
-		Data:       data,
+	// Long-format points keep the single-object queue format.
+	var queued interface{} = rows[0]
+	if decoded.wide() {
+		queued = rows
 	}
 
-	dataJSON, err := json.Marshal(queuedData)
+	timeout, sync := syncTimeout(c)
+	id, err := EnqueueMessage(c.Context(), "general", queued, sync)
 	if err != nil {
-		return fiber.NewError(http.StatusInternalServerError, "Failed to prepare data for queue")
+		return fiber.NewError(http.StatusInternalServerError, "Failed to queue data for ingestion")
 	}
 
-	if err := cache.RedisClient.RPush(c.Context(), config.AppConfig.IngestQueueName, dataJSON).Err(); err != nil {
-		return fiber.NewError(http.StatusInternalServerError, "Failed to queue data for ingestion")
+	if sync {
+		return respondWithAck(c, id, timeout)
 	}
 
+	if decoded.wide() {
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status":  "Data received and queued",
+			"ship_id": rows[0].ShipID,
+			"count":   len(rows),
+		})
+	}
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"status":   "Data received and queued",
-		"ship_id":  data.ShipID,
-		"cargo_id": data.CargoID,
+		"ship_id":  rows[0].ShipID,
+		"cargo_id": rows[0].CargoID,
 	})
 }
 
 // IngestBatchData now sends the entire batch as a single message.
+// Wide-format items are expanded into one row per value.
 func IngestBatchData(c *fiber.Ctx) error {
 	rawBody := c.Body()
-	fmt.Printf("Raw request body: %s\n", string(rawBody))
-	
-	var batch []SensorData
-	if err := json.Unmarshal(rawBody, &batch); err != nil {
-		fmt.Printf("JSON Unmarshal error: %v\n", err)
+	encoding := payloadEncoding(c)
+	tp, err := NewTimeParser(c)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := decodeBatch(encoding, rawBody, tp)
+	if err != nil {
+		log.Printf("[Ingest] Failed to decode %s body: %v", encoding, err)
 		return fiber.NewError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if len(batch) == 0 {
+	if len(decoded.items) == 0 {
 		return fiber.NewError(http.StatusBadRequest, "Batch cannot be empty")
 	}
 	
-	if validationErrors := utils.ValidateBatch(batch); len(validationErrors) > 0 {
-		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(validationErrors))
+	// In partial mode (`?partial=true`) invalid items are dropped and reported instead of failing the batch.
+	batch, rejected := decoded.split()
+	if len(rejected) > 0 && (len(batch) == 0 || !c.QueryBool("partial", false)) {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(flattenRejected(rejected)))
 	}
 
-
-	queuedData := models.QueuedData{
-		RetryCount: 0,
-		Type:       "general",
-		Data:       batch,
+	timeout, sync := syncTimeout(c)
+	id, err := Enqueue(c.Context(), batch, sync)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to queue batch data")
 	}
 
-	dataJSON, err := json.Marshal(queuedData)
-	if err != nil {
-		return fiber.NewError(http.StatusInternalServerError, "Failed to prepare batch data")
+	if len(rejected) > 0 {
+		return respondPartial(c, id, len(batch), rejected, sync, timeout)
 	}
 
-	if err := cache.RedisClient.RPush(c.Context(), config.AppConfig.IngestQueueName, dataJSON).Err(); err != nil {
-		return fiber.NewError(http.StatusInternalServerError, "Failed to queue batch data")
+	if sync {
+		return respondWithAck(c, id, timeout)
 	}
 
 	return c.Status(http.StatusOK).JSON(fiber.Map{
@@ -90,3 +106,58 @@ func IngestBatchData(c *fiber.Ctx) error {
 		"count":  len(batch),
 	})
 }
+
+// respondWithAck waits for the worker to commit a queued message and reports the result.
+// If the worker does not answer in time the data is still queued, so 202 is returned.
+func respondWithAck(c *fiber.Ctx, id string, timeout time.Duration) error {
+	ack, err := WaitForAck(c.Context(), id, timeout)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to wait for ingest acknowledgement")
+	}
+
+	if ack == nil {
+		return c.Status(http.StatusAccepted).JSON(fiber.Map{
+			"status":     "Data queued, commit not confirmed before timeout",
+			"message_id": id,
+		})
+	}
+
+	if ack.Status != "committed" {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"status":     "Data could not be committed",
+			"message_id": id,
+			"error":      ack.Error,
+		})
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"status":      "Data committed",
+		"message_id":  id,
+		"inserted":    ack.Inserted,
+		"skipped":     ack.Skipped,
+		"quarantined": ack.Quarantined,
+		"dropped":     ack.Dropped,
+		"compressed":  ack.Compressed,
+	})
+}
+
+// respondPartial reports a batch that was only partly accepted with 207 Multi-Status.
+func respondPartial(c *fiber.Ctx, id string, accepted int, rejected []models.RejectedItem, sync bool, timeout time.Duration) error {
+	resp := models.PartialIngestResponse{
+		StatusCode: http.StatusMultiStatus,
+		Message:    "Batch partially accepted",
+		Accepted:   accepted,
+		Rejected:   rejected,
+		MessageID:  id,
+	}
+
+	if sync {
+		ack, err := WaitForAck(c.Context(), id, timeout)
+		if err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to wait for ingest acknowledgement")
+		}
+		resp.Commit = ack
+	}
+
+	return c.Status(http.StatusMultiStatus).JSON(resp)
+}
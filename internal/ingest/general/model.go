@@ -3,9 +3,14 @@ package general
 import "time"
 
 // SensorData defines the structure for "general" data points.
+// Time must lie within the window set by TIMESTAMP_MAX_FUTURE_SEC and TIMESTAMP_MAX_AGE_DAYS.
 type SensorData struct {
-	Time       time.Time `json:"time"      validate:"required"`
+	Time       time.Time `json:"time"      validate:"required,timewindow"`
 	ShipID     string    `json:"ship_id"   validate:"required,min=1,max=100"`
 	CargoID    string    `json:"cargo_id"  validate:"required,min=1,max=100"`
 	Value      *float64   `json:"value"     validate:"required"`
+
+	// TransformVersion is set by the worker to the transform configuration
+	// that produced Value; nil if no transform applied.
+	TransformVersion *int64 `json:"-"`
 }
@@ -0,0 +1,155 @@
+package general
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+)
+
+// decodedPoints is a decoded payload before validation. Each item is a point
+// as sent by the client; a long-format item yields one row and a wide-format
+// item (`values`) one row per cargo ID.
+type decodedPoints struct {
+	batch bool
+	items []*decodedItem
+	rows  []decodedRow
+}
+
+// decodedItem is one point of the payload. errors holds the problems that
+// affect all of its rows, such as an unparsable timestamp.
+type decodedItem struct {
+	index  int
+	time   time.Time
+	wide   bool
+	errors []models.ErrorDetail
+}
+
+// decodedRow is a SensorData row and the item it was expanded from. cargo is
+// the key in `values` for wide-format items.
+type decodedRow struct {
+	item  *decodedItem
+	cargo string
+	data  SensorData
+}
+
+// loc returns the error location of a field of the item at index, prefixed
+// with the index for batches.
+func (d *decodedPoints) loc(index int, field string) string {
+	if d.batch {
+		return fmt.Sprintf("[%d].%s", index, field)
+	}
+	return field
+}
+
+// addItem starts a new item. Items with an unparsable timestamp are stamped
+// with the receipt time so only the timestamp error is reported for them.
+func (d *decodedPoints) addItem(index int, ts time.Time, timeErr error, tp *TimeParser) *decodedItem {
+	item := &decodedItem{index: index, time: ts}
+	if timeErr != nil {
+		item.time = tp.Now
+		item.errors = append(item.errors, timeError(d.loc(index, "Time"), timeErr))
+	}
+	d.items = append(d.items, item)
+	return item
+}
+
+func (d *decodedPoints) addRow(item *decodedItem, cargo string, data SensorData) {
+	d.rows = append(d.rows, decodedRow{item: item, cargo: cargo, data: data})
+}
+
+// checkWide marks item as wide-format and reports whether it can be expanded:
+// it must not also carry cargo_id/value and needs at least one value.
+func (d *decodedPoints) checkWide(item *decodedItem, hasLongFields bool, values int) bool {
+	item.wide = true
+	msg := ""
+	switch {
+	case hasLongFields:
+		msg = "Use either cargo_id and value or values, not both"
+	case values == 0:
+		msg = "values must not be empty"
+	default:
+		return true
+	}
+	item.errors = append(item.errors, models.ErrorDetail{
+		Loc:  []string{d.loc(item.index, "values")},
+		Msg:  msg,
+		Type: "validation_error.wide_format",
+	})
+	return false
+}
+
+// wide reports whether the payload was a single wide-format point.
+func (d *decodedPoints) wide() bool {
+	return !d.batch && len(d.items) == 1 && d.items[0].wide
+}
+
+// split validates every row and separates the valid rows from the rejected
+// items, keeping the original index of each rejection. The rows of an item
+// with item-level errors are all rejected; for wide-format items every value
+// is validated on its own.
+func (d *decodedPoints) split() ([]SensorData, []models.RejectedItem) {
+	valid := make([]SensorData, 0, len(d.rows))
+	rowErrors := make(map[*decodedItem][]models.ErrorDetail)
+	seen := make(map[string]bool)
+
+	for _, row := range d.rows {
+		errs := d.validateRow(row)
+		if len(errs) == 0 && len(row.item.errors) == 0 {
+			valid = append(valid, row.data)
+			continue
+		}
+		// Shared fields of wide items fail the same way on every row; report them once.
+		for _, e := range errs {
+			key := e.Loc[0] + "\x00" + e.Type
+			if !seen[key] {
+				seen[key] = true
+				rowErrors[row.item] = append(rowErrors[row.item], e)
+			}
+		}
+	}
+
+	var rejected []models.RejectedItem
+	for _, item := range d.items {
+		errs := append(append([]models.ErrorDetail{}, item.errors...), rowErrors[item]...)
+		if len(errs) > 0 {
+			rejected = append(rejected, models.RejectedItem{Index: item.index, Errors: errs})
+		}
+	}
+	return valid, rejected
+}
+
+// validateRow validates a row, locating errors on wide-format values as
+// `values.<cargo_id>.<Field>`.
+func (d *decodedPoints) validateRow(row decodedRow) []models.ErrorDetail {
+	errs := utils.ValidateStruct(&row.data)
+	for i := range errs {
+		field := strings.Join(errs[i].Loc, ".")
+		if row.cargo != "" && (field == "CargoID" || field == "Value") {
+			field = "values." + row.cargo + "." + field
+		}
+		errs[i].Loc = []string{d.loc(row.item.index, field)}
+	}
+	return errs
+}
+
+// flattenRejected collects the errors of all rejected items.
+func flattenRejected(rejected []models.RejectedItem) []models.ErrorDetail {
+	var details []models.ErrorDetail
+	for _, r := range rejected {
+		details = append(details, r.Errors...)
+	}
+	return details
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
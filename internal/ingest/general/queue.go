@@ -0,0 +1,89 @@
+package general
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Enqueue pushes already validated data points onto the ingest queue as a single
+// "general" message and returns the message ID.
+func Enqueue(ctx context.Context, batch []SensorData, awaitAck bool) (string, error) {
+	return EnqueueMessage(ctx, "general", batch, awaitAck)
+}
+
+// EnqueueMessage pushes a message of any type the worker understands onto the
+// ingest queue and returns the message ID.
+func EnqueueMessage(ctx context.Context, msgType string, data interface{}, awaitAck bool) (string, error) {
+	queuedData := models.QueuedData{
+		ID:         uuid.NewString(),
+		RetryCount: 0,
+		Type:       msgType,
+		Data:       data,
+		AwaitAck:   awaitAck,
+	}
+
+	dataJSON, err := json.Marshal(queuedData)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cache.RedisClient.RPush(ctx, config.AppConfig.IngestQueueName, dataJSON).Err(); err != nil {
+		return "", err
+	}
+	return queuedData.ID, nil
+}
+
+// queueCheckInterval limits how often the queue length is polled for backpressure.
+const queueCheckInterval = time.Second
+
+// QueueBackpressure throttles a producer while the ingest queue holds
+// Threshold or more messages. A zero or negative Threshold disables it.
+type QueueBackpressure struct {
+	Threshold int64
+	lastCheck time.Time
+}
+
+// Over checks the queue length, at most once per queueCheckInterval, and
+// reports whether it is at or above the threshold.
+func (b *QueueBackpressure) Over(ctx context.Context) (int64, bool) {
+	if b.Threshold <= 0 || time.Since(b.lastCheck) < queueCheckInterval {
+		return 0, false
+	}
+	b.lastCheck = time.Now()
+
+	length, err := cache.RedisClient.LLen(ctx, config.AppConfig.IngestQueueName).Result()
+	if err != nil {
+		return 0, false
+	}
+	return length, length >= b.Threshold
+}
+
+// Wait blocks until the queue has drained below 80% of the threshold and
+// returns its length. It returns early with ctx's error if ctx is done.
+func (b *QueueBackpressure) Wait(ctx context.Context) (int64, error) {
+	resumeAt := b.Threshold * 8 / 10
+	ticker := time.NewTicker(queueCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+		length, err := cache.RedisClient.LLen(ctx, config.AppConfig.IngestQueueName).Result()
+		if err != nil && ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if err != nil || length < resumeAt {
+			b.lastCheck = time.Now()
+			return length, nil
+		}
+	}
+}
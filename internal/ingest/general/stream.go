@@ -0,0 +1,141 @@
+package general
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/middleware"
+	"go-ingest-service/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxReportedLineErrors caps how many rejected lines are listed in a stream response.
+const maxReportedLineErrors = 100
+
+// IngestStreamData handles newline-delimited JSON uploads of arbitrary size.
+// Lines are decoded and validated one at a time and queued in chunks of
+// NDJSON_CHUNK_SIZE points, so the full body is never held in memory.
+// Invalid lines are skipped and reported; valid lines are still ingested.
+func IngestStreamData(c *fiber.Ctx) error {
+	tp, err := NewTimeParser(c)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReaderSize(middleware.RequestBodyReader(c), 64*1024)
+	chunkSize := config.AppConfig.NDJSONChunkSize
+	maxLine := config.AppConfig.NDJSONMaxLineBytes
+
+	chunk := make([]SensorData, 0, chunkSize)
+	var accepted, rejectedCount, chunks int
+	var rejected []models.RejectedItem
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if _, err := Enqueue(c.Context(), chunk, false); err != nil {
+			return err
+		}
+		accepted += len(chunk)
+		chunks++
+		chunk = make([]SensorData, 0, chunkSize)
+		return nil
+	}
+
+	reject := func(line int, errs []models.ErrorDetail) {
+		rejectedCount++
+		if len(rejected) < maxReportedLineErrors {
+			rejected = append(rejected, models.RejectedItem{Index: line, Errors: errs})
+		}
+	}
+
+	for lineNo := 1; ; lineNo++ {
+		line, tooLong, readErr := readLine(reader, maxLine)
+		if errors.Is(readErr, middleware.ErrBodyTooLarge) {
+			return fiber.NewError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Request body is too large; %d points were queued before the limit", accepted))
+		}
+		if readErr != nil && readErr != io.EOF {
+			log.Printf("[Ingest] NDJSON stream read error after %d lines: %v", lineNo-1, readErr)
+			return fiber.NewError(http.StatusBadRequest, "Failed to read request body")
+		}
+
+		if tooLong {
+			reject(lineNo, []models.ErrorDetail{{
+				Loc:  []string{fmt.Sprintf("line %d", lineNo)},
+				Msg:  fmt.Sprintf("Line exceeds %d bytes", maxLine),
+				Type: "line_too_long",
+			}})
+		} else if line = bytes.TrimSpace(line); len(line) > 0 {
+			decoded, err := decodeSingle(encodingJSON, line, tp)
+			if err != nil {
+				reject(lineNo, []models.ErrorDetail{{
+					Loc:  []string{fmt.Sprintf("line %d", lineNo)},
+					Msg:  "Invalid JSON: " + err.Error(),
+					Type: "json_invalid",
+				}})
+			} else if rows, rejectedItems := decoded.split(); len(rejectedItems) > 0 {
+				reject(lineNo, flattenRejected(rejectedItems))
+			} else {
+				chunk = append(chunk, rows...)
+				if len(chunk) >= chunkSize {
+					if err := flush(); err != nil {
+						return fiber.NewError(http.StatusInternalServerError,
+							fmt.Sprintf("Failed to queue data after %d accepted points", accepted))
+					}
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := flush(); err != nil {
+		return fiber.NewError(http.StatusInternalServerError,
+			fmt.Sprintf("Failed to queue data after %d accepted points", accepted))
+	}
+
+	if accepted == 0 && rejectedCount == 0 {
+		return fiber.NewError(http.StatusBadRequest, "Stream cannot be empty")
+	}
+
+	status := http.StatusOK
+	if rejectedCount > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"status":         "Stream data received and queued",
+		"accepted":       accepted,
+		"rejected_count": rejectedCount,
+		"rejected":       rejected,
+		"chunks":         chunks,
+	})
+}
+
+// readLine reads the next newline-terminated line from r without buffering
+// more than max bytes of it. A longer line is consumed up to its newline and
+// reported with tooLong set, so one newline-free body cannot exhaust memory.
+func readLine(r *bufio.Reader, max int) (line []byte, tooLong bool, err error) {
+	for {
+		frag, err := r.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(frag) > max {
+				tooLong, line = true, nil
+			} else {
+				line = append(line, frag...)
+			}
+		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
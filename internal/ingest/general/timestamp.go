@@ -0,0 +1,162 @@
+package general
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// epochPrecisions maps the `precision` query parameter onto the unit of
+// numeric timestamps. The short InfluxDB spellings are accepted as well.
+var epochPrecisions = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"u":  time.Microsecond,
+	"ns": time.Nanosecond,
+	"n":  time.Nanosecond,
+}
+
+// TimeParser converts the timestamps of one request. Missing timestamps are
+// stamped with the time the request was received; numeric timestamps are
+// epoch values in the requested precision, or detected from their magnitude.
+type TimeParser struct {
+	Precision time.Duration
+	Now       time.Time
+}
+
+// NewTimeParser reads the `precision` query parameter of a request.
+func NewTimeParser(c *fiber.Ctx) (*TimeParser, error) {
+	precision, err := ParsePrecision(c.Query("precision"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+	return &TimeParser{Precision: precision, Now: time.Now().UTC()}, nil
+}
+
+// ParsePrecision returns the unit named by a `precision` parameter; an empty
+// value means the unit is detected from each timestamp.
+func ParsePrecision(precision string) (time.Duration, error) {
+	if precision == "" {
+		return 0, nil
+	}
+	unit, ok := epochPrecisions[strings.ToLower(precision)]
+	if !ok {
+		return 0, fmt.Errorf("Invalid precision %q: use s, ms, us or ns", precision)
+	}
+	return unit, nil
+}
+
+// Parse converts a wire timestamp: nil (missing), a native time, an RFC3339
+// string or an epoch number.
+func (p *TimeParser) Parse(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case nil:
+		return p.Now, nil
+	case time.Time:
+		return t.UTC(), nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time %q is neither RFC3339 nor an epoch number", t)
+		}
+		return parsed.UTC(), nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return p.fromEpochInt(n), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time %q is not a valid number", t.String())
+		}
+		return p.fromEpochFloat(f)
+	case int64:
+		return p.fromEpochInt(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("time %d is out of range", t)
+		}
+		return p.fromEpochInt(int64(t)), nil
+	}
+
+	f, ok := toFloat64(v)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unsupported time type %T", v)
+	}
+	return p.fromEpochFloat(f)
+}
+
+// unit returns the configured precision, or guesses it from the magnitude:
+// values below 1e11 are seconds (up to the year 5138), below 1e14
+// milliseconds, below 1e17 microseconds and anything larger nanoseconds.
+func (p *TimeParser) unit(magnitude float64) time.Duration {
+	if p.Precision != 0 {
+		return p.Precision
+	}
+	switch magnitude = math.Abs(magnitude); {
+	case magnitude < 1e11:
+		return time.Second
+	case magnitude < 1e14:
+		return time.Millisecond
+	case magnitude < 1e17:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+// fromEpochInt converts integers exactly, which matters for nanoseconds.
+func (p *TimeParser) fromEpochInt(n int64) time.Time {
+	unit := p.unit(float64(n))
+	perSecond := int64(time.Second / unit)
+	return time.Unix(n/perSecond, (n%perSecond)*int64(unit)).UTC()
+}
+
+func (p *TimeParser) fromEpochFloat(f float64) (time.Time, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, fmt.Errorf("time must be a finite number")
+	}
+	// Dividing by the units per second keeps fractional seconds exact.
+	secs := f / float64(time.Second/p.unit(f))
+	if math.Abs(secs) > math.MaxInt64/float64(time.Second) {
+		return time.Time{}, fmt.Errorf("time %v is out of range", f)
+	}
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(math.Round(frac*float64(time.Second)))).UTC(), nil
+}
+
+// rawJSONTime prepares a raw JSON time field for Parse.
+func rawJSONTime(raw json.RawMessage) (interface{}, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil, nil
+	}
+	if trimmed[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+		return nil, fmt.Errorf("time must be an RFC3339 string or an epoch number")
+	}
+	return json.Number(trimmed), nil
+}
+
+// timeError reports an unparsable timestamp as a validation error on the Time field.
+func timeError(loc string, err error) models.ErrorDetail {
+	return models.ErrorDetail{
+		Loc:  []string{loc},
+		Msg:  err.Error(),
+		Type: "validation_error.time_format",
+	}
+}
@@ -0,0 +1,121 @@
+package general
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimeParserMagnitude(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	sec := time.Unix(1700000000, 0).UTC()
+	tests := []struct {
+		in   interface{}
+		want time.Time
+	}{
+		{nil, now},
+		{json.Number("1700000000"), sec},
+		{json.Number("1700000000123"), sec.Add(123 * time.Millisecond)},
+		{json.Number("1700000000123456"), sec.Add(123456 * time.Microsecond)},
+		{json.Number("1700000000123456789"), sec.Add(123456789)},
+		{json.Number("1700000000.5"), sec.Add(500 * time.Millisecond)},
+		{json.Number("-1"), time.Unix(-1, 0).UTC()},
+		// The boundaries between units.
+		{json.Number("99999999999"), time.Unix(99999999999, 0).UTC()},
+		{json.Number("100000000000"), time.Unix(1e8, 0).UTC()},
+		{json.Number("100000000000000"), time.Unix(1e8, 0).UTC()},
+		{json.Number("100000000000000000"), time.Unix(1e8, 0).UTC()},
+		{int64(1700000000000), sec},
+		{uint64(1700000000), sec},
+		{float64(1700000000), sec},
+		{int(1700000000), sec},
+		{"2023-11-14T22:13:20.5+00:00", sec.Add(500 * time.Millisecond)},
+		{sec.In(time.FixedZone("CET", 3600)), sec},
+	}
+	p := &TimeParser{Now: now}
+	for _, tt := range tests {
+		got, err := p.Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%#v) = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("Parse(%#v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTimeParserPrecision(t *testing.T) {
+	tests := []struct {
+		precision string
+		in        interface{}
+		want      time.Time
+	}{
+		// An explicit precision overrides the magnitude.
+		{"ms", json.Number("1700000000"), time.Unix(1700000, 0)},
+		{"s", json.Number("1700000000123"), time.Unix(1700000000123, 0)},
+		{"us", json.Number("1700000000"), time.Unix(1700, 0)},
+		{"u", json.Number("1700000000"), time.Unix(1700, 0)},
+		{"ns", json.Number("1700000000"), time.Unix(1, 700000000)},
+		{"N", json.Number("1700000000"), time.Unix(1, 700000000)},
+		{"s", json.Number("1700000000.25"), time.Unix(1700000000, 250000000)},
+		{"s", int64(-90), time.Unix(-90, 0)},
+	}
+	for _, tt := range tests {
+		precision, err := ParsePrecision(tt.precision)
+		if err != nil {
+			t.Fatalf("ParsePrecision(%q) = %v", tt.precision, err)
+		}
+		p := &TimeParser{Precision: precision}
+		got, err := p.Parse(tt.in)
+		if err != nil {
+			t.Errorf("precision %s: Parse(%#v) = %v", tt.precision, tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("precision %s: Parse(%#v) = %v, want %v", tt.precision, tt.in, got, tt.want.UTC())
+		}
+	}
+}
+
+func TestTimeParserErrors(t *testing.T) {
+	if _, err := ParsePrecision("minutes"); err == nil {
+		t.Error("ParsePrecision(minutes) succeeded, want error")
+	}
+	p := &TimeParser{}
+	for _, in := range []interface{}{
+		"yesterday",
+		json.Number("1e400"),
+		math.NaN(),
+		math.Inf(1),
+		uint64(math.MaxUint64),
+		float64(1e30),
+		true,
+	} {
+		if got, err := p.Parse(in); err == nil {
+			t.Errorf("Parse(%#v) = %v, want error", in, got)
+		}
+	}
+}
+
+func TestRawJSONTime(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{``, nil, false},
+		{`null`, nil, false},
+		{`"2024-06-01T12:00:00Z"`, "2024-06-01T12:00:00Z", false},
+		{` 1700000000 `, json.Number("1700000000"), false},
+		{`true`, nil, true},
+		{`{}`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := rawJSONTime(json.RawMessage(tt.raw))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("rawJSONTime(%q) = %#v, %v, want %#v", tt.raw, got, err, tt.want)
+		}
+	}
+}
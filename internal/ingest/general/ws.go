@@ -0,0 +1,180 @@
+package general
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/models"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// wsMaxFrameBytes caps the size of a single WebSocket message.
+	wsMaxFrameBytes = 1 << 20
+	// wsPingInterval is how often a paused connection is pinged to detect that
+	// the client has gone away.
+	wsPingInterval = 5 * time.Second
+	// wsWriteWait bounds how long a control frame may take to send.
+	wsWriteWait = 10 * time.Second
+)
+
+// wsMessage is sent from the server to a WebSocket client.
+//   - "ack":   a frame was queued; Accepted is the number of points in it.
+//   - "error": a frame was rejected; nothing from it was queued.
+//   - "flow":  State is "pause" when the ingest queue is over the backpressure
+//     threshold and "resume" once it has drained.
+type wsMessage struct {
+	Type        string               `json:"type"`
+	Seq         uint64               `json:"seq,omitempty"`
+	Accepted    int                  `json:"accepted,omitempty"`
+	MessageID   string               `json:"message_id,omitempty"`
+	Message     string               `json:"message,omitempty"`
+	Errors      []models.ErrorDetail `json:"errors,omitempty"`
+	State       string               `json:"state,omitempty"`
+	QueueLength int64                `json:"queue_length,omitempty"`
+}
+
+// RequireWebSocketUpgrade rejects plain HTTP requests to WebSocket routes.
+func RequireWebSocketUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.NewError(http.StatusUpgradeRequired, "WebSocket upgrade required")
+	}
+	return c.Next()
+}
+
+// IngestWebSocket keeps a persistent ingest channel open. Each text frame is a
+// JSON data point or array of points; binary frames use MessagePack, or CBOR
+// when connecting with `?encoding=cbor`. Frames are numbered from 1 in the
+// order received and every frame is answered with an ack or error carrying
+// that sequence number. Connections stop waiting on backpressure once ctx is
+// cancelled at server shutdown.
+func IngestWebSocket(ctx context.Context) fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		serveWebSocket(ctx, conn)
+	})
+}
+
+func serveWebSocket(ctx context.Context, conn *websocket.Conn) {
+	binaryEncoding := encodingMsgpack
+	if conn.Query("encoding") == "cbor" {
+		binaryEncoding = encodingCBOR
+	}
+	precision, err := ParsePrecision(conn.Query("precision"))
+	if err != nil {
+		conn.WriteJSON(wsMessage{Type: "error", Message: err.Error()})
+		return
+	}
+	conn.SetReadLimit(wsMaxFrameBytes)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	flow := &QueueBackpressure{Threshold: config.AppConfig.WSBackpressureThreshold}
+	log.Printf("[WS] Ingest connection opened from %s", conn.IP())
+
+	for seq := uint64(1); ; seq++ {
+		if err := waitForQueue(ctx, cancel, conn, flow); err != nil {
+			break
+		}
+
+		msgType, payload, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("[WS] Read error from %s: %v", conn.IP(), err)
+			}
+			break
+		}
+
+		encoding := encodingJSON
+		if msgType == websocket.BinaryMessage {
+			encoding = binaryEncoding
+		}
+		tp := &TimeParser{Precision: precision, Now: time.Now().UTC()}
+		if err := conn.WriteJSON(handleFrame(ctx, seq, encoding, tp, payload)); err != nil {
+			log.Printf("[WS] Write error to %s: %v", conn.IP(), err)
+			break
+		}
+	}
+	log.Printf("[WS] Ingest connection closed from %s", conn.IP())
+}
+
+// handleFrame decodes, validates and queues a single frame, returning the reply for it.
+func handleFrame(ctx context.Context, seq uint64, encoding string, tp *TimeParser, payload []byte) wsMessage {
+	decoded, err := decodeFrame(encoding, tp, payload)
+	if err != nil {
+		return wsMessage{Type: "error", Seq: seq, Message: "Invalid frame payload"}
+	}
+	if len(decoded.items) == 0 {
+		return wsMessage{Type: "error", Seq: seq, Message: "Batch cannot be empty"}
+	}
+	batch, rejected := decoded.split()
+	if len(rejected) > 0 {
+		return wsMessage{Type: "error", Seq: seq, Message: "Validation Error", Errors: flattenRejected(rejected)}
+	}
+
+	id, err := Enqueue(ctx, batch, false)
+	if err != nil {
+		log.Printf("[WS] Failed to queue frame %d: %v", seq, err)
+		return wsMessage{Type: "error", Seq: seq, Message: "Failed to queue data for ingestion"}
+	}
+	return wsMessage{Type: "ack", Seq: seq, Accepted: len(batch), MessageID: id}
+}
+
+// decodeFrame accepts either a single data point or an array of them.
+func decodeFrame(encoding string, tp *TimeParser, payload []byte) (*decodedPoints, error) {
+	if encoding == encodingJSON {
+		if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && trimmed[0] == '[' {
+			return decodeBatch(encoding, payload, tp)
+		}
+		return decodeSingle(encoding, payload, tp)
+	}
+
+	if decoded, err := decodeBatch(encoding, payload, tp); err == nil {
+		return decoded, nil
+	}
+	return decodeSingle(encoding, payload, tp)
+}
+
+// waitForQueue tells the client to pause and stops reading while the ingest
+// queue is over the backpressure threshold, then tells it to resume. Nothing
+// is read from a paused connection, so it is pinged to notice a disconnect,
+// which cancels ctx and ends the wait.
+func waitForQueue(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, flow *QueueBackpressure) error {
+	length, over := flow.Over(ctx)
+	if !over {
+		return nil
+	}
+	if err := conn.WriteJSON(wsMessage{Type: "flow", State: "pause", QueueLength: length}); err != nil {
+		return err
+	}
+
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pingDone:
+				return
+			case <-ticker.C:
+				// WriteControl may be called concurrently with other writes.
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	length, err := flow.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.WriteJSON(wsMessage{Type: "flow", State: "resume", QueueLength: length})
+}
@@ -0,0 +1,83 @@
+// Package graphite implements the Graphite plaintext protocol
+// ("path value timestamp" lines) over TCP and UDP.
+package graphite
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ingest/listener"
+)
+
+// Server receives Graphite plaintext metrics and feeds them into a batcher.
+type Server struct {
+	Addr        string
+	Templates   []listener.PathTemplate
+	DefaultShip string
+	Batcher     *listener.Batcher
+}
+
+// ListenAndServe serves TCP and UDP on the same address until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	errChan := make(chan error, 2)
+	go func() { errChan <- listener.ServeTCP(ctx, "Graphite", s.Addr, s.handleLine) }()
+	go func() { errChan <- listener.ServeUDP(ctx, "Graphite", s.Addr, s.handleLine) }()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errChan; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleLine(line string) {
+	data, err := s.parseLine(line, time.Now())
+	if err != nil {
+		log.Printf("[Graphite] Skipping line %q: %v", line, err)
+		return
+	}
+	if data != nil {
+		s.Batcher.Add(*data)
+	}
+}
+
+// parseLine parses "path value [timestamp]". Graphite tags ("path;tag=value")
+// are ignored. A missing or negative timestamp means now; NaN values are
+// skipped and return nil.
+func (s *Server) parseLine(line string, now time.Time) (*general.SensorData, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("expected 'path value timestamp'")
+	}
+
+	metric := strings.SplitN(fields[0], ";", 2)[0]
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", fields[1])
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, nil
+	}
+
+	ts := now.UTC()
+	if len(fields) == 3 {
+		secs, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", fields[2])
+		}
+		if secs >= 0 {
+			whole, frac := math.Modf(secs)
+			ts = time.Unix(int64(whole), int64(frac*float64(time.Second))).UTC()
+		}
+	}
+
+	shipID, cargoID := listener.MapPath(s.Templates, metric, s.DefaultShip)
+	return &general.SensorData{Time: ts, ShipID: shipID, CargoID: cargoID, Value: &value}, nil
+}
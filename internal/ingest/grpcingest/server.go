@@ -0,0 +1,190 @@
+// Package grpcingest exposes the ingest API over gRPC. Points are validated
+// with the same rules as the HTTP handlers and pushed onto the same Redis queue.
+package grpcingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ingest/grpcingest/ingestpb"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxReportedStreamErrors caps how many rejections are listed in a stream response.
+const maxReportedStreamErrors = 100
+
+// Server implements ingestpb.IngestServiceServer.
+type Server struct {
+	ingestpb.UnimplementedIngestServiceServer
+}
+
+// NewServer creates a gRPC server with API key authentication and the ingest service registered.
+func NewServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryAuth),
+		grpc.StreamInterceptor(streamAuth),
+	)
+	ingestpb.RegisterIngestServiceServer(srv, &Server{})
+	return srv
+}
+
+// Ingest queues a single data point.
+func (s *Server) Ingest(ctx context.Context, req *ingestpb.IngestRequest) (*ingestpb.IngestResponse, error) {
+	data := fromProto(req.GetData())
+	if errs := utils.ValidateStruct(&data); len(errs) > 0 {
+		return nil, validationStatus(errs)
+	}
+
+	id, err := general.Enqueue(ctx, []general.SensorData{data}, false)
+	if err != nil {
+		log.Printf("[gRPC] Failed to queue data: %v", err)
+		return nil, status.Error(codes.Unavailable, "Failed to queue data for ingestion")
+	}
+	return &ingestpb.IngestResponse{Status: "Data received and queued", MessageId: id, Count: 1}, nil
+}
+
+// IngestBatch queues a batch as a single message, rejecting it if any point is invalid.
+func (s *Server) IngestBatch(ctx context.Context, req *ingestpb.IngestBatchRequest) (*ingestpb.IngestResponse, error) {
+	if len(req.GetData()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Batch cannot be empty")
+	}
+
+	batch := make([]general.SensorData, len(req.GetData()))
+	for i, d := range req.GetData() {
+		batch[i] = fromProto(d)
+	}
+	if errs := utils.ValidateBatch(batch); len(errs) > 0 {
+		return nil, validationStatus(errs)
+	}
+
+	id, err := general.Enqueue(ctx, batch, false)
+	if err != nil {
+		log.Printf("[gRPC] Failed to queue batch: %v", err)
+		return nil, status.Error(codes.Unavailable, "Failed to queue batch data")
+	}
+	return &ingestpb.IngestResponse{Status: "Batch data received and queued", MessageId: id, Count: int64(len(batch))}, nil
+}
+
+// IngestStream queues points in chunks as they arrive. Invalid points are
+// skipped and reported in the final response.
+func (s *Server) IngestStream(stream ingestpb.IngestService_IngestStreamServer) error {
+	ctx := stream.Context()
+	chunkSize := config.AppConfig.GRPCStreamChunkSize
+	chunk := make([]general.SensorData, 0, chunkSize)
+	resp := &ingestpb.IngestStreamResponse{}
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if _, err := general.Enqueue(ctx, chunk, false); err != nil {
+			log.Printf("[gRPC] Failed to queue stream chunk after %d points: %v", resp.Accepted, err)
+			return status.Errorf(codes.Unavailable, "Failed to queue data after %d accepted points", resp.Accepted)
+		}
+		resp.Accepted += int64(len(chunk))
+		chunk = make([]general.SensorData, 0, chunkSize)
+		return nil
+	}
+
+	for index := int64(0); ; index++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		data := fromProto(req.GetData())
+		if errs := utils.ValidateStruct(&data); len(errs) > 0 {
+			resp.Rejected++
+			for _, e := range errs {
+				if len(resp.Errors) >= maxReportedStreamErrors {
+					break
+				}
+				resp.Errors = append(resp.Errors, &ingestpb.ValidationError{Index: index, Field: strings.Join(e.Loc, "."), Msg: e.Msg})
+			}
+			continue
+		}
+
+		chunk = append(chunk, data)
+		if len(chunk) >= chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+// fromProto converts a protobuf data point into the queue representation.
+// Points without a time are stamped with the time they were received.
+func fromProto(d *ingestpb.SensorData) general.SensorData {
+	data := general.SensorData{
+		Time:    time.Now().UTC(),
+		ShipID:  d.GetShipId(),
+		CargoID: d.GetCargoId(),
+	}
+	if d.GetTime() != nil {
+		data.Time = d.GetTime().AsTime()
+	}
+	if d != nil && d.Value != nil {
+		v := d.GetValue()
+		data.Value = &v
+	}
+	return data
+}
+
+// validationStatus turns validation errors into an InvalidArgument status.
+func validationStatus(errs []models.ErrorDetail) error {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", strings.Join(e.Loc, "."), e.Msg))
+	}
+	return status.Error(codes.InvalidArgument, "Validation Error: "+strings.Join(msgs, "; "))
+}
+
+// checkAPIKey verifies the x-api-key metadata entry, mirroring middleware.APIKeyAuth.
+func checkAPIKey(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get("x-api-key")
+	if len(keys) == 0 || keys[0] == "" {
+		log.Printf("[gRPC] API key missing for %s.", method)
+		return status.Error(codes.PermissionDenied, "API key is missing")
+	}
+	if keys[0] != config.AppConfig.APIKey {
+		log.Printf("[gRPC] Invalid API key provided for %s.", method)
+		return status.Error(codes.Unauthenticated, "Invalid API key")
+	}
+	return nil
+}
+
+func unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := checkAPIKey(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkAPIKey(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,89 @@
+// Package listener contains shared plumbing for the non-HTTP protocol listeners
+// (StatsD, Graphite, ...): batching points onto the ingest queue and mapping
+// dotted metric paths to ship and cargo IDs.
+package listener
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/utils"
+)
+
+// Batcher collects validated points from listeners and queues them as a single
+// message whenever the batch is full or the flush interval elapses.
+type Batcher struct {
+	name      string
+	size      int
+	interval  time.Duration
+	mu        sync.Mutex
+	pending   []general.SensorData
+	flushChan chan struct{}
+}
+
+// NewBatcher creates a batcher; name is used as the log prefix.
+func NewBatcher(name string, size int, interval time.Duration) *Batcher {
+	return &Batcher{
+		name:      name,
+		size:      size,
+		interval:  interval,
+		pending:   make([]general.SensorData, 0, size),
+		flushChan: make(chan struct{}, 1),
+	}
+}
+
+// Add validates a point and adds it to the pending batch. Invalid points are
+// logged and dropped, as there is no client to report them to.
+func (b *Batcher) Add(data general.SensorData) bool {
+	if errs := utils.ValidateStruct(&data); len(errs) > 0 {
+		log.Printf("[%s] Dropping invalid point %s/%s: %s", b.name, data.ShipID, data.CargoID, errs[0].Msg+" ("+errs[0].Loc[0]+")")
+		return false
+	}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, data)
+	full := len(b.pending) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.flushChan <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Run flushes the batch periodically until ctx is cancelled, then flushes once more.
+func (b *Batcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			b.flush(context.Background())
+			return
+		case <-ticker.C:
+			b.flush(ctx)
+		case <-b.flushChan:
+			b.flush(ctx)
+		}
+	}
+}
+
+func (b *Batcher) flush(ctx context.Context) {
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = make([]general.SensorData, 0, b.size)
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if _, err := general.Enqueue(ctx, batch, false); err != nil {
+		log.Printf("[%s] Failed to queue %d points: %v", b.name, len(batch), err)
+	}
+}
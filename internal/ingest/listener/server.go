@@ -0,0 +1,120 @@
+package listener
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+// maxUDPPacket is large enough for any datagram a sender can emit.
+const maxUDPPacket = 65535
+
+// LineHandler processes a single line of a line-oriented plaintext protocol.
+type LineHandler func(line string)
+
+// SourceLineHandler is a LineHandler that is also given the address of the
+// sender, for protocols whose lines depend on earlier lines of the same sender.
+type SourceLineHandler func(from net.Addr, line string)
+
+// ServeUDP reads datagrams on addr and passes each line to handle until ctx is cancelled.
+func ServeUDP(ctx context.Context, name, addr string, handle LineHandler) error {
+	return ServeUDPFrom(ctx, name, addr, func(_ net.Addr, line string) { handle(line) })
+}
+
+// ServeUDPFrom is ServeUDP passing the sender of each datagram to handle.
+func ServeUDPFrom(ctx context.Context, name, addr string, handle SourceLineHandler) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	log.Printf("[%s] Listening on udp %s", name, addr)
+	buf := make([]byte, maxUDPPacket)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			log.Printf("[%s] UDP read error: %v", name, err)
+			continue
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				handle(from, line)
+			}
+		}
+	}
+}
+
+// ServeTCP accepts connections on addr and passes each received line to handle
+// until ctx is cancelled.
+func ServeTCP(ctx context.Context, name, addr string, handle LineHandler) error {
+	return ServeTCPFrom(ctx, name, addr, func(_ net.Addr, line string) { handle(line) })
+}
+
+// ServeTCPFrom is ServeTCP passing the remote address of each connection to handle.
+func ServeTCPFrom(ctx context.Context, name, addr string, handle SourceLineHandler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+		mu.Lock()
+		for c := range conns {
+			c.Close()
+		}
+		mu.Unlock()
+	}()
+
+	log.Printf("[%s] Listening on tcp %s", name, addr)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				wg.Wait()
+				return nil
+			}
+			log.Printf("[%s] TCP accept error: %v", name, err)
+			continue
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+				conn.Close()
+			}()
+
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					handle(conn.RemoteAddr(), line)
+				}
+			}
+			if err := scanner.Err(); err != nil && ctx.Err() == nil {
+				log.Printf("[%s] TCP read error from %s: %v", name, conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
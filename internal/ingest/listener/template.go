@@ -0,0 +1,117 @@
+package listener
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PathTemplate maps a dotted metric path such as "vessel1.engine.rpm" onto a
+// ship_id and cargo_id. Templates are written in the Graphite style used by
+// InfluxDB and Telegraf, one name per path segment:
+//
+//	ship.cargo*          -> ship "vessel1", cargo "engine.rpm"
+//	_.ship.cargo.cargo   -> first segment ignored, two cargo segments
+//
+// "ship" and "cargo" collect a segment, "_" skips one, and a trailing "*"
+// collects all remaining segments. Segments collected for the same field are
+// joined with dots. An optional filter in front of the template (separated by
+// a space) restricts it to matching paths, e.g. "plc.* _.ship.cargo*".
+type PathTemplate struct {
+	filter string
+	parts  []string
+}
+
+// ParseTemplates parses a comma-separated list of templates. Templates are
+// tried in order and the first whose filter matches is used.
+func ParseTemplates(spec string) ([]PathTemplate, error) {
+	var templates []PathTemplate
+	for _, entry := range strings.Split(spec, ",") {
+		fields := strings.Fields(entry)
+		var t PathTemplate
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			t.parts = strings.Split(fields[0], ".")
+		case 2:
+			if _, err := path.Match(fields[0], ""); err != nil {
+				return nil, fmt.Errorf("invalid template filter %q: %w", fields[0], err)
+			}
+			t.filter = fields[0]
+			t.parts = strings.Split(fields[1], ".")
+		default:
+			return nil, fmt.Errorf("invalid template %q", entry)
+		}
+
+		for i, part := range t.parts {
+			name := strings.TrimSuffix(part, "*")
+			if name != "ship" && name != "cargo" && name != "_" {
+				return nil, fmt.Errorf("invalid template segment %q in %q", part, entry)
+			}
+			if strings.HasSuffix(part, "*") && i != len(t.parts)-1 {
+				return nil, fmt.Errorf("wildcard segment %q must be last in %q", part, entry)
+			}
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// matches reports whether the template's filter accepts the path. Filters are
+// matched segment by segment so "*" never crosses a dot.
+func (t PathTemplate) matches(metric string) bool {
+	if t.filter == "" {
+		return true
+	}
+	filterParts := strings.Split(t.filter, ".")
+	metricParts := strings.Split(metric, ".")
+	if len(metricParts) < len(filterParts) {
+		return false
+	}
+	for i, fp := range filterParts {
+		if ok, _ := path.Match(fp, metricParts[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// apply splits a path according to the template.
+func (t PathTemplate) apply(metric string) (shipID, cargoID string) {
+	segments := strings.Split(metric, ".")
+	var ship, cargo []string
+	for i, part := range t.parts {
+		if i >= len(segments) {
+			break
+		}
+		take := segments[i : i+1]
+		if strings.HasSuffix(part, "*") {
+			take = segments[i:]
+		}
+		switch strings.TrimSuffix(part, "*") {
+		case "ship":
+			ship = append(ship, take...)
+		case "cargo":
+			cargo = append(cargo, take...)
+		}
+	}
+	return strings.Join(ship, "."), strings.Join(cargo, ".")
+}
+
+// MapPath resolves a metric path to ship and cargo IDs using the first matching
+// template. If no template matches, the whole path becomes the cargo_id.
+// defaultShip is used when no ship_id could be derived.
+func MapPath(templates []PathTemplate, metric, defaultShip string) (shipID, cargoID string) {
+	cargoID = metric
+	for _, t := range templates {
+		if t.matches(metric) {
+			shipID, cargoID = t.apply(metric)
+			break
+		}
+	}
+	if shipID == "" {
+		shipID = defaultShip
+	}
+	return shipID, cargoID
+}
@@ -0,0 +1,49 @@
+package listener
+
+import "testing"
+
+func TestMapPath(t *testing.T) {
+	tests := []struct {
+		spec, metric        string
+		wantShip, wantCargo string
+	}{
+		{"ship.cargo*", "vessel1.engine.rpm", "vessel1", "engine.rpm"},
+		{"ship.cargo", "vessel1.engine.rpm", "vessel1", "engine"},
+		{"_.ship.cargo.cargo", "fleet.vessel1.engine.rpm", "vessel1", "engine.rpm"},
+		{"ship.ship.cargo*", "fleet.vessel1.engine.rpm", "fleet.vessel1", "engine.rpm"},
+		{"cargo.ship", "rpm.vessel1", "vessel1", "rpm"},
+		// The first template whose filter matches is used.
+		{"plc.* _.ship.cargo*, ship.cargo*", "plc.vessel1.temp", "vessel1", "temp"},
+		{"plc.* _.ship.cargo*, ship.cargo*", "vessel1.temp", "vessel1", "temp"},
+		// Filters match segment by segment, so * does not cross a dot.
+		{"plc.line?.* _._.ship.cargo*", "plc.line1.vessel1.temp", "vessel1", "temp"},
+		{"plc.*.* _.cargo*", "plc.temp", "default", "plc.temp"},
+		// Without a matching template the whole path is the cargo_id.
+		{"", "engine.rpm", "default", "engine.rpm"},
+		{"cargo*", "engine.rpm", "default", "engine.rpm"},
+	}
+	for _, tt := range tests {
+		templates, err := ParseTemplates(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseTemplates(%q) = %v", tt.spec, err)
+		}
+		ship, cargo := MapPath(templates, tt.metric, "default")
+		if ship != tt.wantShip || cargo != tt.wantCargo {
+			t.Errorf("MapPath(%q, %q) = %q, %q, want %q, %q", tt.spec, tt.metric, ship, cargo, tt.wantShip, tt.wantCargo)
+		}
+	}
+}
+
+func TestParseTemplatesErrors(t *testing.T) {
+	tests := []string{
+		"ship.value",
+		"ship*.cargo",
+		"plc.* ship.cargo extra",
+		"[ ship.cargo",
+	}
+	for _, spec := range tests {
+		if _, err := ParseTemplates(spec); err == nil {
+			t.Errorf("ParseTemplates(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package nmea
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+)
+
+// Unit conversion factors to knots.
+const (
+	kmhToKnots = 1 / 1.852
+	msToKnots  = 3600 / 1852.0
+	mphToKnots = 1.609344 / 1.852
+)
+
+// Decode converts a sentence into data points for shipID. Positions are
+// written as "latitude"/"longitude" in decimal degrees, speeds in knots and
+// angles in degrees. now is the receipt time, used for sentences that carry
+// no timestamp of their own.
+//
+// Cargo IDs by sentence:
+//
+//	GGA: latitude, longitude, gps_quality, satellites, hdop, altitude
+//	RMC: latitude, longitude, sog, cog, magnetic_variation
+//	VTG: cog, cog_magnetic, sog
+//	HDT: heading
+//	MWV: wind_angle, wind_speed (relative) or true_wind_angle, true_wind_speed
+//	DPT: depth, depth_offset
+//	XDR: xdr.<name> for each transducer measurement
+func Decode(s Sentence, shipID string, now time.Time) ([]general.SensorData, error) {
+	p := &points{shipID: shipID, ts: now.UTC()}
+	switch s.Type {
+	case "GGA":
+		if t, ok := timeOfDay(s.field(0), now); ok {
+			p.ts = t
+		}
+		if s.field(5) == "0" {
+			return nil, nil // No fix.
+		}
+		p.position(s, 1)
+		p.addField(s, "gps_quality", 5)
+		p.addField(s, "satellites", 6)
+		p.addField(s, "hdop", 7)
+		p.addField(s, "altitude", 8)
+	case "RMC":
+		if s.field(1) != "A" {
+			return nil, nil // Receiver warning; data is not valid.
+		}
+		if t, ok := dateTime(s.field(8), s.field(0)); ok {
+			p.ts = t
+		}
+		p.position(s, 2)
+		p.addField(s, "sog", 6)
+		p.addField(s, "cog", 7)
+		if v, ok := s.float(9); ok {
+			if s.field(10) == "W" {
+				v = -v
+			}
+			p.add("magnetic_variation", v)
+		}
+	case "VTG":
+		p.addField(s, "cog", 0)
+		p.addField(s, "cog_magnetic", 2)
+		if v, ok := s.float(4); ok {
+			p.add("sog", v)
+		} else if v, ok := s.float(6); ok {
+			p.add("sog", v*kmhToKnots)
+		}
+	case "HDT":
+		p.addField(s, "heading", 0)
+	case "MWV":
+		if s.field(4) != "A" {
+			return nil, nil
+		}
+		prefix := "wind_"
+		if s.field(1) == "T" {
+			prefix = "true_wind_"
+		}
+		p.addField(s, prefix+"angle", 0)
+		if v, ok := s.float(2); ok {
+			switch s.field(3) {
+			case "K":
+				v *= kmhToKnots
+			case "M":
+				v *= msToKnots
+			case "S":
+				v *= mphToKnots
+			}
+			p.add(prefix+"speed", v)
+		}
+	case "DPT":
+		p.addField(s, "depth", 0)
+		p.addField(s, "depth_offset", 1)
+	case "XDR":
+		// Repeating groups of type, value, unit, transducer name.
+		for i := 0; i+1 < len(s.Fields); i += 4 {
+			name := strings.ToLower(s.field(i + 3))
+			if name == "" {
+				name = strings.ToLower(s.field(i)) + strconv.Itoa(i/4)
+			}
+			p.addField(s, "xdr."+name, i+1)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, s.Type)
+	}
+	return p.data, nil
+}
+
+// points accumulates data points sharing a ship and timestamp.
+type points struct {
+	shipID string
+	ts     time.Time
+	data   []general.SensorData
+}
+
+func (p *points) add(cargoID string, v float64) {
+	p.data = append(p.data, general.SensorData{Time: p.ts, ShipID: p.shipID, CargoID: cargoID, Value: &v})
+}
+
+// addField adds the i-th field if it holds a number.
+func (p *points) addField(s Sentence, cargoID string, i int) {
+	if v, ok := s.float(i); ok {
+		p.add(cargoID, v)
+	}
+}
+
+// position adds latitude and longitude from the four fields starting at i
+// (lat, N/S, lon, E/W).
+func (p *points) position(s Sentence, i int) {
+	lat, okLat := coordinate(s.field(i), s.field(i+1), 2)
+	lon, okLon := coordinate(s.field(i+2), s.field(i+3), 3)
+	if okLat && okLon {
+		p.add("latitude", lat)
+		p.add("longitude", lon)
+	}
+}
+
+// coordinate converts NMEA "ddmm.mmmm" (or "dddmm.mmmm") and a hemisphere to decimal degrees.
+func coordinate(value, hemisphere string, degreeDigits int) (float64, bool) {
+	if len(value) < degreeDigits+2 {
+		return 0, false
+	}
+	deg, err1 := strconv.ParseFloat(value[:degreeDigits], 64)
+	minutes, err2 := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	v := deg + minutes/60
+	switch hemisphere {
+	case "S", "W":
+		v = -v
+	case "N", "E":
+	default:
+		return 0, false
+	}
+	return v, true
+}
+
+// timeOfDay combines an "hhmmss.ss" field with the receipt date. If that puts the
+// fix well after the receipt time, it was taken just before midnight.
+func timeOfDay(value string, now time.Time) (time.Time, bool) {
+	now = now.UTC()
+	tod, err := time.Parse("150405.999999999", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), tod.Hour(), tod.Minute(), tod.Second(), tod.Nanosecond(), time.UTC)
+	if t.Sub(now) > 12*time.Hour {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t, true
+}
+
+// dateTime combines RMC "ddmmyy" and "hhmmss.ss" fields.
+func dateTime(date, tod string) (time.Time, bool) {
+	t, err := time.Parse("020106150405.999999999", date+tod)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
@@ -0,0 +1,179 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// withChecksum frames a sentence body with its checksum.
+func withChecksum(body string) string {
+	return fmt.Sprintf("$%s*%02X", body, Checksum(body))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw          string
+		talker, typ  string
+		fields       int
+		wantErr      bool
+		wantChecksum bool
+	}{
+		{raw: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", talker: "GP", typ: "GGA", fields: 14},
+		{raw: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A", talker: "GP", typ: "RMC", fields: 11},
+		{raw: "  $IIHDT,274.07,T  ", talker: "II", typ: "HDT", fields: 2},
+		{raw: withChecksum("PGRME,15.0,M,45.0,M,25.0,M"), typ: "PGRME", fields: 6},
+		{raw: "!AIVDM,1,1,,A,13aEOK?P00PD2wVMdLDRhgvL289?,0*26", talker: "AI", typ: "VDM", fields: 6},
+		{raw: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48", wantErr: true, wantChecksum: true},
+		{raw: "$GPHDT,274.07,T*Z1", wantErr: true},
+		{raw: "$GPHDT,274.07,T*1", wantErr: true},
+		{raw: "GPHDT,274.07,T", wantErr: true},
+		{raw: "$GP,1", wantErr: true},
+		{raw: "$GPH,274.07", wantErr: true},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", tt.raw)
+			} else if tt.wantChecksum && !errors.Is(err, ErrChecksum) {
+				t.Errorf("Parse(%q) = %v, want ErrChecksum", tt.raw, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) = %v", tt.raw, err)
+			continue
+		}
+		if s.Talker != tt.talker || s.Type != tt.typ || len(s.Fields) != tt.fields {
+			t.Errorf("Parse(%q) = talker %q type %q with %d fields, want %q %q with %d",
+				tt.raw, s.Talker, s.Type, len(s.Fields), tt.talker, tt.typ, tt.fields)
+		}
+	}
+}
+
+func TestCoordinate(t *testing.T) {
+	tests := []struct {
+		value, hemisphere string
+		digits            int
+		want              float64
+		ok                bool
+	}{
+		{"4807.038", "N", 2, 48.1173, true},
+		{"4807.038", "S", 2, -48.1173, true},
+		{"01131.000", "E", 3, 11.516666, true},
+		{"01131.000", "W", 3, -11.516666, true},
+		{"0000.000", "N", 2, 0, true},
+		{"4807.038", "X", 2, 0, false},
+		{"4807.038", "", 2, 0, false},
+		{"480", "N", 2, 0, false},
+		{"48a7.038", "N", 2, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := coordinate(tt.value, tt.hemisphere, tt.digits)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("coordinate(%q, %q, %d) = %v, %v, want %v, %v", tt.value, tt.hemisphere, tt.digits, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	now := time.Date(2024, 3, 10, 0, 5, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"000412", time.Date(2024, 3, 10, 0, 4, 12, 0, time.UTC), true},
+		{"000412.50", time.Date(2024, 3, 10, 0, 4, 12, 500e6, time.UTC), true},
+		// A fix from just before midnight received just after it.
+		{"235958", time.Date(2024, 3, 9, 23, 59, 58, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"25xx00", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := timeOfDay(tt.value, now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("timeOfDay(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Date(1994, 3, 23, 12, 40, 0, 0, time.UTC)
+	fix := time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC)
+	tests := []struct {
+		raw  string
+		time time.Time
+		want map[string]float64
+	}{
+		{
+			raw:  "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47",
+			time: fix,
+			want: map[string]float64{"latitude": 48.1173, "longitude": 11.516666, "gps_quality": 1, "satellites": 8, "hdop": 0.9, "altitude": 545.4},
+		},
+		{
+			raw:  withChecksum("GPGGA,123519,4807.038,N,01131.000,E,0,00,,,M,,M,,"),
+			want: map[string]float64{},
+		},
+		{
+			raw:  "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A",
+			time: fix,
+			want: map[string]float64{"latitude": 48.1173, "longitude": 11.516666, "sog": 22.4, "cog": 84.4, "magnetic_variation": -3.1},
+		},
+		{
+			raw:  withChecksum("GPRMC,123519,V,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W"),
+			want: map[string]float64{},
+		},
+		{
+			raw:  withChecksum("GPVTG,054.7,T,034.4,M,,N,18.52,K"),
+			time: now,
+			want: map[string]float64{"cog": 54.7, "cog_magnetic": 34.4, "sog": 10},
+		},
+		{
+			raw:  withChecksum("IIMWV,045.0,T,10.0,M,A"),
+			time: now,
+			want: map[string]float64{"true_wind_angle": 45, "true_wind_speed": 10 * msToKnots},
+		},
+		{
+			raw:  withChecksum("IIMWV,045.0,R,10.0,N,V"),
+			want: map[string]float64{},
+		},
+		{
+			raw:  withChecksum("IIXDR,C,19.5,C,AIRTEMP,P,1.013,B,"),
+			time: now,
+			want: map[string]float64{"xdr.airtemp": 19.5, "xdr.p1": 1.013},
+		},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q) = %v", tt.raw, err)
+		}
+		data, err := Decode(s, "vessel_1", now)
+		if err != nil {
+			t.Errorf("Decode(%q) = %v", tt.raw, err)
+			continue
+		}
+		if len(data) != len(tt.want) {
+			t.Errorf("Decode(%q) returned %d points, want %d", tt.raw, len(data), len(tt.want))
+			continue
+		}
+		for _, d := range data {
+			want, ok := tt.want[d.CargoID]
+			if !ok || math.Abs(*d.Value-want) > 1e-6 {
+				t.Errorf("Decode(%q) %s = %v, want %v", tt.raw, d.CargoID, *d.Value, want)
+			}
+			if d.ShipID != "vessel_1" || !d.Time.Equal(tt.time) {
+				t.Errorf("Decode(%q) %s at %s/%v, want vessel_1/%v", tt.raw, d.CargoID, d.ShipID, d.Time, tt.time)
+			}
+		}
+	}
+
+	s, _ := Parse(withChecksum("GPGSV,3,1,11,03,03,111,00"))
+	if _, err := Decode(s, "vessel_1", now); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Decode(GSV) = %v, want ErrUnsupported", err)
+	}
+}
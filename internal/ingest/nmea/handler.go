@@ -0,0 +1,103 @@
+package nmea
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/middleware"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxReportedErrors caps how many rejected sentences are listed in a response.
+const maxReportedErrors = 100
+
+// IngestNMEA accepts a body of NMEA 0183 sentences, one per line, for the ship
+// given by the `ship_id` query parameter. Sentences with bad checksums are
+// reported; sentence types that are not mapped are counted as ignored.
+func IngestNMEA(c *fiber.Ctx) error {
+	shipID := c.Query("ship_id")
+	if shipID == "" {
+		return fiber.NewError(http.StatusBadRequest, "ship_id query parameter is required")
+	}
+
+	now := time.Now()
+	var batch []general.SensorData
+	var rejected []models.RejectedItem
+	var sentences, ignored, rejectedCount int
+
+	scanner := bufio.NewScanner(middleware.RequestBodyReader(c))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		sentences++
+
+		points, err := decodeLine(line, shipID, now)
+		if errors.Is(err, ErrUnsupported) {
+			ignored++
+			continue
+		}
+		if err != nil {
+			rejectedCount++
+			if len(rejected) < maxReportedErrors {
+				rejected = append(rejected, models.RejectedItem{Index: lineNo, Errors: []models.ErrorDetail{{
+					Loc:  []string{fmt.Sprintf("line %d", lineNo)},
+					Msg:  err.Error(),
+					Type: "nmea_invalid",
+				}}})
+			}
+			continue
+		}
+		batch = append(batch, points...)
+	}
+	if err := scanner.Err(); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Failed to read request body")
+	}
+
+	if len(batch) > 0 {
+		if _, err := general.Enqueue(c.Context(), batch, false); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to queue NMEA data")
+		}
+	}
+
+	status := http.StatusOK
+	if rejectedCount > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"status":         "NMEA data received and queued",
+		"sentences":      sentences,
+		"points":         len(batch),
+		"ignored":        ignored,
+		"rejected_count": rejectedCount,
+		"rejected":       rejected,
+	})
+}
+
+// decodeLine parses one sentence and returns its valid points.
+func decodeLine(line, shipID string, now time.Time) ([]general.SensorData, error) {
+	s, err := Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	points, err := Decode(s, shipID, now)
+	if err != nil {
+		return nil, err
+	}
+	valid := points[:0]
+	for _, p := range points {
+		if errs := utils.ValidateStruct(&p); len(errs) > 0 {
+			return nil, fmt.Errorf("%s (%s)", errs[0].Msg, errs[0].Loc[0])
+		}
+		valid = append(valid, p)
+	}
+	return valid, nil
+}
@@ -0,0 +1,84 @@
+// Package nmea parses NMEA 0183 sentences from vessel instruments and turns
+// them into telemetry points.
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrChecksum is returned when a sentence's checksum does not match its contents.
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrUnsupported is returned for well-formed sentences that carry no telemetry we map.
+	ErrUnsupported = errors.New("unsupported sentence type")
+)
+
+// Sentence is a parsed NMEA 0183 sentence such as "$GPGGA,...*47".
+type Sentence struct {
+	Talker string   // e.g. "GP", "II"
+	Type   string   // e.g. "GGA"
+	Fields []string // data fields after the address field
+	Raw    string
+}
+
+// Parse validates the framing and checksum of a sentence and splits its fields.
+// Sentences without a checksum are accepted, as some instruments omit it.
+func Parse(raw string) (Sentence, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) < 6 || (raw[0] != '$' && raw[0] != '!') {
+		return Sentence{}, fmt.Errorf("sentence must start with '$' or '!'")
+	}
+
+	body := raw[1:]
+	if star := strings.LastIndexByte(body, '*'); star >= 0 {
+		want, err := strconv.ParseUint(body[star+1:], 16, 8)
+		if err != nil || len(body[star+1:]) != 2 {
+			return Sentence{}, fmt.Errorf("invalid checksum field %q", body[star+1:])
+		}
+		body = body[:star]
+		if uint64(Checksum(body)) != want {
+			return Sentence{}, ErrChecksum
+		}
+	}
+
+	fields := strings.Split(body, ",")
+	address := fields[0]
+	if len(address) < 5 {
+		return Sentence{}, fmt.Errorf("invalid address field %q", address)
+	}
+
+	s := Sentence{Fields: fields[1:], Raw: raw}
+	if address[0] == 'P' {
+		// Proprietary sentences have no talker ID.
+		s.Type = address
+	} else {
+		s.Talker, s.Type = address[:2], address[2:]
+	}
+	return s, nil
+}
+
+// Checksum is the XOR of all characters between the start delimiter and '*'.
+func Checksum(body string) byte {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return sum
+}
+
+// field returns the i-th data field, or "" if the sentence is shorter.
+func (s Sentence) field(i int) string {
+	if i < len(s.Fields) {
+		return strings.TrimSpace(s.Fields[i])
+	}
+	return ""
+}
+
+// float parses the i-th field. ok is false for empty or malformed fields.
+func (s Sentence) float(i int) (float64, bool) {
+	v, err := strconv.ParseFloat(s.field(i), 64)
+	return v, err == nil
+}
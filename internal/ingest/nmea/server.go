@@ -0,0 +1,50 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go-ingest-service/internal/ingest/listener"
+)
+
+// Server receives NMEA 0183 sentences over TCP and UDP for a single ship,
+// e.g. from a multiplexer or instrument gateway on board.
+type Server struct {
+	Addr    string
+	ShipID  string
+	Batcher *listener.Batcher
+}
+
+// ListenAndServe serves TCP and UDP on the same address until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	errChan := make(chan error, 2)
+	go func() { errChan <- listener.ServeTCP(ctx, "NMEA", s.Addr, s.handleLine) }()
+	go func() { errChan <- listener.ServeUDP(ctx, "NMEA", s.Addr, s.handleLine) }()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errChan; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleLine(line string) {
+	sentence, err := Parse(line)
+	if err != nil {
+		log.Printf("[NMEA] Skipping sentence %q: %v", line, err)
+		return
+	}
+	points, err := Decode(sentence, s.ShipID, time.Now())
+	if err != nil {
+		if !errors.Is(err, ErrUnsupported) {
+			log.Printf("[NMEA] Skipping sentence %q: %v", line, err)
+		}
+		return
+	}
+	for _, p := range points {
+		s.Batcher.Add(p)
+	}
+}
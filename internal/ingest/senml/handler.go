@@ -0,0 +1,63 @@
+package senml
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IngestSenML accepts a SenML pack as `application/senml+json` or
+// `application/senml+cbor`. The optional `ship_id` query parameter is used
+// when the pack has no base name, and `units=suffix` appends units to cargo IDs.
+func IngestSenML(c *fiber.Ctx) error {
+	var pack []Record
+	var err error
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(c.Get(fiber.HeaderContentType), ";")[0]))
+	switch contentType {
+	case "application/senml+cbor", "application/cbor":
+		pack, err = DecodeCBOR(c.Body())
+	default:
+		pack, err = DecodeJSON(c.Body())
+	}
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid SenML pack")
+	}
+	if len(pack) == 0 {
+		return fiber.NewError(http.StatusBadRequest, "SenML pack cannot be empty")
+	}
+
+	result, err := Resolve(pack, Options{
+		DefaultShipID: c.Query("ship_id"),
+		UnitSuffix:    c.Query("units") == "suffix",
+	}, time.Now())
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError([]models.ErrorDetail{{
+			Loc:  []string{"pack"},
+			Msg:  err.Error(),
+			Type: "senml_invalid",
+		}}))
+	}
+
+	if validationErrors := utils.ValidateBatch(result.Points); len(validationErrors) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(validationErrors))
+	}
+
+	if len(result.Points) > 0 {
+		if _, err := general.Enqueue(c.Context(), result.Points, false); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to queue SenML data")
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"status":  "SenML data received and queued",
+		"records": len(pack),
+		"points":  len(result.Points),
+		"skipped": result.Skipped,
+	})
+}
@@ -0,0 +1,162 @@
+// Package senml parses Sensor Measurement Lists (RFC 8428) in JSON and CBOR
+// and resolves them into telemetry points.
+package senml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// relativeTimeLimit is 2**28 seconds: resolved times below it are relative to now (RFC 8428 §4.5.3).
+const relativeTimeLimit = 1 << 28
+
+// Record is a single SenML record. JSON uses the string labels and CBOR the
+// integer labels from RFC 8428 §6.
+type Record struct {
+	BaseVersion *int     `json:"bver,omitempty" cbor:"-1,keyasint,omitempty"`
+	BaseName    *string  `json:"bn,omitempty"   cbor:"-2,keyasint,omitempty"`
+	BaseTime    *float64 `json:"bt,omitempty"   cbor:"-3,keyasint,omitempty"`
+	BaseUnit    *string  `json:"bu,omitempty"   cbor:"-4,keyasint,omitempty"`
+	BaseValue   *float64 `json:"bv,omitempty"   cbor:"-5,keyasint,omitempty"`
+	BaseSum     *float64 `json:"bs,omitempty"   cbor:"-6,keyasint,omitempty"`
+	Name        string   `json:"n,omitempty"    cbor:"0,keyasint,omitempty"`
+	Unit        string   `json:"u,omitempty"    cbor:"1,keyasint,omitempty"`
+	Value       *float64 `json:"v,omitempty"    cbor:"2,keyasint,omitempty"`
+	StringValue *string  `json:"vs,omitempty"   cbor:"3,keyasint,omitempty"`
+	BoolValue   *bool    `json:"vb,omitempty"   cbor:"4,keyasint,omitempty"`
+	Sum         *float64 `json:"s,omitempty"    cbor:"5,keyasint,omitempty"`
+	Time        float64  `json:"t,omitempty"    cbor:"6,keyasint,omitempty"`
+	UpdateTime  float64  `json:"ut,omitempty"   cbor:"7,keyasint,omitempty"`
+	DataValue   *string  `json:"vd,omitempty"   cbor:"8,keyasint,omitempty"`
+}
+
+// Options controls how resolved records are mapped to data points.
+type Options struct {
+	// DefaultShipID is used when the pack has no base name.
+	DefaultShipID string
+	// UnitSuffix appends the unit to the cargo_id ("temp_Cel"), since
+	// cargo_data has no unit column.
+	UnitSuffix bool
+}
+
+// Result is the outcome of resolving a pack.
+type Result struct {
+	Points []general.SensorData
+	// Skipped counts records whose value cannot be stored as a number (vs, vd)
+	// or that carry no value at all.
+	Skipped int
+}
+
+// DecodeJSON parses a SenML JSON pack.
+func DecodeJSON(body []byte) ([]Record, error) {
+	var pack []Record
+	err := json.Unmarshal(body, &pack)
+	return pack, err
+}
+
+// DecodeCBOR parses a SenML CBOR pack.
+func DecodeCBOR(body []byte) ([]Record, error) {
+	var pack []Record
+	err := cbor.Unmarshal(body, &pack)
+	return pack, err
+}
+
+// Resolve applies base fields to each record and converts it into data points.
+// The resolved base name becomes the ship_id (without a trailing ':' or '/'
+// separator) and the record name the cargo_id. Boolean values are stored as
+// 1 or 0 and sums as "<name>.sum".
+func Resolve(pack []Record, opts Options, now time.Time) (*Result, error) {
+	result := &Result{}
+	var baseName, baseUnit string
+	var baseTime, baseValue, baseSum float64
+
+	for i, r := range pack {
+		if r.BaseVersion != nil && *r.BaseVersion > 10 {
+			return nil, fmt.Errorf("record %d: unsupported SenML version %d", i, *r.BaseVersion)
+		}
+		if r.BaseName != nil {
+			baseName = *r.BaseName
+		}
+		if r.BaseTime != nil {
+			baseTime = *r.BaseTime
+		}
+		if r.BaseUnit != nil {
+			baseUnit = *r.BaseUnit
+		}
+		if r.BaseValue != nil {
+			baseValue = *r.BaseValue
+		}
+		if r.BaseSum != nil {
+			baseSum = *r.BaseSum
+		}
+
+		shipID := strings.TrimRight(baseName, ":/")
+		if shipID == "" {
+			shipID = opts.DefaultShipID
+		}
+		if shipID == "" {
+			return nil, fmt.Errorf("record %d: no base name and no default ship_id", i)
+		}
+		if r.Name == "" {
+			// Base-only records (e.g. carrying just "bn"/"bt") have no measurement.
+			if r.Value == nil && r.BoolValue == nil && r.Sum == nil && r.StringValue == nil && r.DataValue == nil {
+				continue
+			}
+			return nil, fmt.Errorf("record %d: missing name", i)
+		}
+
+		cargoID := r.Name
+		unit := r.Unit
+		if unit == "" {
+			unit = baseUnit
+		}
+		if opts.UnitSuffix && unit != "" {
+			cargoID += "_" + unit
+		}
+		ts := resolveTime(baseTime+r.Time, now)
+
+		stored := false
+		add := func(cargo string, v float64) {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return
+			}
+			result.Points = append(result.Points, general.SensorData{Time: ts, ShipID: shipID, CargoID: cargo, Value: &v})
+			stored = true
+		}
+		switch {
+		case r.Value != nil:
+			add(cargoID, baseValue+*r.Value)
+		case r.BoolValue != nil:
+			v := 0.0
+			if *r.BoolValue {
+				v = 1
+			}
+			add(cargoID, v)
+		}
+		if r.Sum != nil {
+			add(cargoID+".sum", baseSum+*r.Sum)
+		}
+		if !stored {
+			result.Skipped++
+		}
+	}
+	return result, nil
+}
+
+// resolveTime converts a resolved SenML time (seconds) to an absolute timestamp.
+// Zero means "now"; values below 2**28 are relative to now.
+func resolveTime(t float64, now time.Time) time.Time {
+	if t < relativeTimeLimit {
+		return now.Add(time.Duration(t * float64(time.Second))).UTC()
+	}
+	// Round to microseconds to hide float64 precision loss on epoch values.
+	whole, frac := math.Modf(t)
+	return time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3).UTC()
+}
@@ -0,0 +1,171 @@
+package senml
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+type point struct {
+	ship, cargo string
+	time        time.Time
+	value       float64
+}
+
+func TestResolve(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	epoch := time.Unix(1.32e9, 0).UTC()
+	tests := []struct {
+		name    string
+		json    string
+		opts    Options
+		want    []point
+		skipped int
+		wantErr bool
+	}{
+		{
+			name: "base fields apply to later records",
+			json: `[{"bn":"vessel_1/","bt":1.32e9,"bu":"Cel","bv":10,"n":"temp","v":1.5},{"n":"temp","t":60,"v":2},{"n":"hum","u":"%RH","v":40}]`,
+			opts: Options{UnitSuffix: true},
+			want: []point{
+				{"vessel_1", "temp_Cel", epoch, 11.5},
+				{"vessel_1", "temp_Cel", epoch.Add(time.Minute), 12},
+				{"vessel_1", "hum_%RH", epoch, 50},
+			},
+		},
+		{
+			name: "relative times and default ship",
+			json: `[{"n":"rpm","v":900},{"n":"rpm","t":-5,"v":880}]`,
+			opts: Options{DefaultShipID: "vessel_2"},
+			want: []point{
+				{"vessel_2", "rpm", now, 900},
+				{"vessel_2", "rpm", now.Add(-5 * time.Second), 880},
+			},
+		},
+		{
+			name: "booleans, sums and unstorable values",
+			json: `[{"bn":"pump:","bs":100,"n":"on","vb":true},{"n":"off","vb":false},{"n":"flow","s":5},{"n":"label","vs":"A"},{"n":"blob","vd":"AQID"},{"n":"empty"}]`,
+			want: []point{
+				{"pump", "on", now, 1},
+				{"pump", "off", now, 0},
+				{"pump", "flow.sum", now, 105},
+			},
+			skipped: 3,
+		},
+		{
+			name: "value and sum in one record",
+			json: `[{"bn":"meter","n":"energy","v":2.5,"s":1000}]`,
+			want: []point{
+				{"meter", "energy", now, 2.5},
+				{"meter", "energy.sum", now, 1000},
+			},
+		},
+		{
+			name: "base-only record",
+			json: `[{"bn":"vessel_3","bt":1.32e9},{"n":"depth","v":12}]`,
+			want: []point{{"vessel_3", "depth", epoch, 12}},
+		},
+		{
+			name: "base name overrides the default ship",
+			json: `[{"bn":"vessel_4:","n":"a","v":1},{"bn":"vessel_5","n":"a","v":2}]`,
+			opts: Options{DefaultShipID: "vessel_0"},
+			want: []point{
+				{"vessel_4", "a", now, 1},
+				{"vessel_5", "a", now, 2},
+			},
+		},
+		{
+			name:    "value without a name",
+			json:    `[{"bn":"vessel_1","v":20}]`,
+			wantErr: true,
+		},
+		{
+			name:    "no ship",
+			json:    `[{"n":"a","v":1}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported version",
+			json:    `[{"bver":11,"bn":"vessel_1","n":"a","v":1}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		pack, err := DecodeJSON([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: DecodeJSON = %v", tt.name, err)
+		}
+		res, err := Resolve(pack, tt.opts, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Resolve succeeded, want error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Resolve = %v", tt.name, err)
+			continue
+		}
+		if res.Skipped != tt.skipped {
+			t.Errorf("%s: skipped %d, want %d", tt.name, res.Skipped, tt.skipped)
+		}
+		if len(res.Points) != len(tt.want) {
+			t.Errorf("%s: %d points, want %d", tt.name, len(res.Points), len(tt.want))
+			continue
+		}
+		for i, d := range res.Points {
+			got := point{d.ShipID, d.CargoID, d.Time, *d.Value}
+			if got.ship != tt.want[i].ship || got.cargo != tt.want[i].cargo || !got.time.Equal(tt.want[i].time) || math.Abs(got.value-tt.want[i].value) > 1e-9 {
+				t.Errorf("%s: point %d = %+v, want %+v", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestResolveTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    float64
+		want time.Time
+	}{
+		{0, now},
+		{-60, now.Add(-time.Minute)},
+		{1.5, now.Add(1500 * time.Millisecond)},
+		{relativeTimeLimit - 1, now.Add((relativeTimeLimit - 1) * time.Second)},
+		{relativeTimeLimit, time.Unix(relativeTimeLimit, 0).UTC()},
+		{1714564800.123456, time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := resolveTime(tt.t, now); !got.Equal(tt.want) {
+			t.Errorf("resolveTime(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	name, value := "vessel_1", 21.5
+	pack := []Record{{BaseName: &name, Name: "temp", Unit: "Cel", Value: &value, Time: -10}}
+	body, err := cbor.Marshal(pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeCBOR(body)
+	if err != nil {
+		t.Fatalf("DecodeCBOR = %v", err)
+	}
+	if !reflect.DeepEqual(got, pack) {
+		t.Errorf("DecodeCBOR = %+v, want %+v", got, pack)
+	}
+
+	// RFC 8428 labels base fields with negative integers.
+	var labels []map[int]interface{}
+	if err := cbor.Unmarshal(body, &labels); err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0][-2] != "vessel_1" || labels[0][0] != "temp" || labels[0][1] != "Cel" {
+		t.Errorf("CBOR labels = %v", labels)
+	}
+}
@@ -0,0 +1,167 @@
+package sparkplug
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Definition is a metric as announced in a birth certificate.
+type Definition struct {
+	Device   string
+	Name     string
+	Alias    uint64
+	HasAlias bool
+	Datatype uint32
+}
+
+// nodeTable holds the metric definitions of one edge node. Aliases are
+// unique per edge node, names only per device.
+type nodeTable struct {
+	aliases map[uint64]Definition
+	types   map[string]uint32
+}
+
+func newNodeTable() *nodeTable {
+	return &nodeTable{aliases: make(map[uint64]Definition), types: make(map[string]uint32)}
+}
+
+func (t *nodeTable) add(def Definition) {
+	t.types[definitionField(def.Device, def.Name)] = def.Datatype
+	if def.HasAlias {
+		t.aliases[def.Alias] = def
+	}
+}
+
+// AliasStore keeps the metric definitions (aliases and datatypes) announced
+// in birth certificates, since data messages usually carry neither names nor
+// datatypes. Definitions are stored in one Redis hash per edge node, so they
+// survive restarts, and cached in memory once read.
+type AliasStore struct {
+	mu     sync.RWMutex
+	tables map[string]*nodeTable
+}
+
+// NewAliasStore creates an empty alias store.
+func NewAliasStore() *AliasStore {
+	return &AliasStore{tables: make(map[string]*nodeTable)}
+}
+
+func aliasKey(node string) string {
+	return config.AppConfig.IngestQueueName + "_sparkplug_alias:" + node
+}
+
+// definitionField is the hash field of a metric: `<device>/<name>`, with an
+// empty device for node metrics. Device IDs cannot contain '/'.
+func definitionField(device, name string) string {
+	return device + "/" + name
+}
+
+// Birth records the definitions of a birth certificate. An NBIRTH replaces the
+// edge node's table; a DBIRTH adds its device's metrics to it.
+func (s *AliasStore) Birth(ctx context.Context, node string, defs []Definition, replace bool) error {
+	fields := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		value := strconv.FormatUint(uint64(def.Datatype), 10)
+		if def.HasAlias {
+			value += ":" + strconv.FormatUint(def.Alias, 10)
+		}
+		fields[definitionField(def.Device, def.Name)] = value
+	}
+
+	key := aliasKey(node)
+	pipe := cache.RedisClient.TxPipeline()
+	if replace {
+		pipe.Del(ctx, key)
+	}
+	if len(fields) > 0 {
+		pipe.HSet(ctx, key, fields)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	table := s.tables[node]
+	if replace || table == nil {
+		table = newNodeTable()
+		s.tables[node] = table
+	}
+	for _, def := range defs {
+		table.add(def)
+	}
+	return nil
+}
+
+// ByAlias resolves an alias of an edge node.
+func (s *AliasStore) ByAlias(ctx context.Context, node string, alias uint64) (Definition, bool, error) {
+	table, err := s.table(ctx, node)
+	if err != nil {
+		return Definition{}, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	def, ok := table.aliases[alias]
+	return def, ok, nil
+}
+
+// Datatype returns the datatype announced for a named metric.
+func (s *AliasStore) Datatype(ctx context.Context, node, device, name string) (uint32, bool, error) {
+	table, err := s.table(ctx, node)
+	if err != nil {
+		return 0, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	datatype, ok := table.types[definitionField(device, name)]
+	return datatype, ok, nil
+}
+
+// table returns the cached table of an edge node, loading it from Redis on
+// first use.
+func (s *AliasStore) table(ctx context.Context, node string) (*nodeTable, error) {
+	s.mu.RLock()
+	table, ok := s.tables[node]
+	s.mu.RUnlock()
+	if ok {
+		return table, nil
+	}
+
+	values, err := cache.RedisClient.HGetAll(ctx, aliasKey(node)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	table = newNodeTable()
+	for field, value := range values {
+		device, name, ok := strings.Cut(field, "/")
+		if !ok {
+			continue
+		}
+		def := Definition{Device: device, Name: name}
+		datatype, alias, hasAlias := strings.Cut(value, ":")
+		if dt, err := strconv.ParseUint(datatype, 10, 32); err == nil {
+			def.Datatype = uint32(dt)
+		}
+		if hasAlias {
+			if a, err := strconv.ParseUint(alias, 10, 64); err == nil {
+				def.Alias, def.HasAlias = a, true
+			}
+		}
+		table.add(def)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.tables[node]; ok {
+		return existing, nil
+	}
+	s.tables[node] = table
+	return table, nil
+}
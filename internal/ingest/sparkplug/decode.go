@@ -0,0 +1,170 @@
+package sparkplug
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ingest/sparkplug/sparkplugpb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Sparkplug B metric datatypes that can be stored as numbers.
+const (
+	DataTypeInt8    = 1
+	DataTypeInt16   = 2
+	DataTypeInt32   = 3
+	DataTypeInt64   = 4
+	DataTypeUInt8   = 5
+	DataTypeUInt16  = 6
+	DataTypeUInt32  = 7
+	DataTypeUInt64  = 8
+	DataTypeFloat   = 9
+	DataTypeDouble  = 10
+	DataTypeBoolean = 11
+)
+
+// OnlineCargoID is the cargo ID of the point written for birth (1) and death
+// (0) certificates, so that Grafana can show when a node or device was online.
+const OnlineCargoID = "sparkplug.online"
+
+// Result is the outcome of decoding one message.
+type Result struct {
+	Points []general.SensorData
+	// Skipped counts metrics that were null, transient or not numeric.
+	Skipped int
+	// UnknownAliases counts metrics whose alias was never announced; the edge
+	// node should be asked to send a new birth certificate.
+	UnknownAliases int
+}
+
+// Decoder converts Sparkplug B payloads into data points.
+type Decoder struct {
+	Aliases *AliasStore
+}
+
+// Decode decodes the payload of a message published on topic. Birth
+// certificates update the alias store before their metrics are converted.
+// now is used for metrics without a timestamp in either the metric or the payload.
+func (d *Decoder) Decode(ctx context.Context, topic Topic, body []byte, now time.Time) (*Result, error) {
+	var payload sparkplugpb.Payload
+	if err := proto.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	ts := now.UTC()
+	if payload.Timestamp != nil {
+		ts = time.UnixMilli(int64(payload.GetTimestamp())).UTC()
+	}
+	result := &Result{}
+	shipID := topic.ShipID()
+
+	if topic.IsDeath() {
+		// Death certificates only carry the bdSeq of the session.
+		result.Points = append(result.Points, point(shipID, OnlineCargoID, ts, 0))
+		return result, nil
+	}
+
+	if topic.IsBirth() {
+		defs := make([]Definition, 0, len(payload.Metrics))
+		for _, m := range payload.Metrics {
+			if m.Name == nil {
+				continue
+			}
+			defs = append(defs, Definition{
+				Device:   topic.Device,
+				Name:     m.GetName(),
+				Alias:    m.GetAlias(),
+				HasAlias: m.Alias != nil,
+				Datatype: m.GetDatatype(),
+			})
+		}
+		if err := d.Aliases.Birth(ctx, topic.NodeKey(), defs, topic.Type == TypeNBirth); err != nil {
+			return nil, fmt.Errorf("storing birth certificate: %w", err)
+		}
+		result.Points = append(result.Points, point(shipID, OnlineCargoID, ts, 1))
+	}
+
+	for _, m := range payload.Metrics {
+		name, datatype := m.GetName(), m.GetDatatype()
+		if m.Name == nil {
+			def, ok, err := d.Aliases.ByAlias(ctx, topic.NodeKey(), m.GetAlias())
+			if err != nil {
+				return nil, fmt.Errorf("resolving alias: %w", err)
+			}
+			if !ok || m.Alias == nil {
+				result.UnknownAliases++
+				continue
+			}
+			name = def.Name
+			if m.Datatype == nil {
+				datatype = def.Datatype
+			}
+		} else if m.Datatype == nil {
+			dt, _, err := d.Aliases.Datatype(ctx, topic.NodeKey(), topic.Device, name)
+			if err != nil {
+				return nil, fmt.Errorf("resolving datatype: %w", err)
+			}
+			datatype = dt
+		}
+
+		if m.GetIsNull() || m.GetIsTransient() || name == "" {
+			result.Skipped++
+			continue
+		}
+		value, ok := metricValue(m, datatype)
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		metricTime := ts
+		if m.Timestamp != nil {
+			metricTime = time.UnixMilli(int64(m.GetTimestamp())).UTC()
+		}
+		result.Points = append(result.Points, point(shipID, name, metricTime, value))
+	}
+	return result, nil
+}
+
+// metricValue converts a metric value to a float. Integer values are sent as
+// unsigned fields, so signed datatypes are sign-extended. Without a known
+// datatype the value is interpreted by the field it was sent in.
+func metricValue(m *sparkplugpb.Payload_Metric, datatype uint32) (float64, bool) {
+	switch v := m.GetValue().(type) {
+	case *sparkplugpb.Payload_Metric_IntValue:
+		switch datatype {
+		case DataTypeInt8:
+			return float64(int8(v.IntValue)), true
+		case DataTypeInt16:
+			return float64(int16(v.IntValue)), true
+		case DataTypeInt32:
+			return float64(int32(v.IntValue)), true
+		case 0, DataTypeUInt8, DataTypeUInt16, DataTypeUInt32:
+			return float64(v.IntValue), true
+		}
+	case *sparkplugpb.Payload_Metric_LongValue:
+		switch datatype {
+		case DataTypeInt64:
+			return float64(int64(v.LongValue)), true
+		case 0, DataTypeUInt64, DataTypeUInt32:
+			return float64(v.LongValue), true
+		}
+	case *sparkplugpb.Payload_Metric_FloatValue:
+		return float64(v.FloatValue), true
+	case *sparkplugpb.Payload_Metric_DoubleValue:
+		return v.DoubleValue, true
+	case *sparkplugpb.Payload_Metric_BooleanValue:
+		if v.BooleanValue {
+			return 1, true
+		}
+		return 0, true
+	}
+	// Strings, bytes, DateTime, datasets and templates cannot be stored.
+	return 0, false
+}
+
+func point(shipID, cargoID string, ts time.Time, value float64) general.SensorData {
+	return general.SensorData{Time: ts, ShipID: shipID, CargoID: cargoID, Value: &value}
+}
@@ -0,0 +1,170 @@
+package sparkplug
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-ingest-service/internal/ingest/sparkplug/sparkplugpb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type sample struct {
+	ship, cargo string
+	time        time.Time
+	value       float64
+}
+
+// cachedStore returns an alias store that already holds the definitions of an
+// edge node, so decoding data messages needs no Redis.
+func cachedStore(node string, defs ...Definition) *AliasStore {
+	s := NewAliasStore()
+	table := newNodeTable()
+	for _, def := range defs {
+		table.add(def)
+	}
+	s.tables[node] = table
+	return s
+}
+
+func metric(name string, alias uint64, value any) *sparkplugpb.Payload_Metric {
+	m := &sparkplugpb.Payload_Metric{}
+	if name != "" {
+		m.Name = proto.String(name)
+	}
+	if alias != 0 {
+		m.Alias = proto.Uint64(alias)
+	}
+	switch v := value.(type) {
+	case uint32:
+		m.Value = &sparkplugpb.Payload_Metric_IntValue{IntValue: v}
+	case uint64:
+		m.Value = &sparkplugpb.Payload_Metric_LongValue{LongValue: v}
+	case float64:
+		m.Value = &sparkplugpb.Payload_Metric_DoubleValue{DoubleValue: v}
+	case bool:
+		m.Value = &sparkplugpb.Payload_Metric_BooleanValue{BooleanValue: v}
+	case string:
+		m.Value = &sparkplugpb.Payload_Metric_StringValue{StringValue: v}
+	}
+	return m
+}
+
+func TestDecodeAliases(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	sent := time.UnixMilli(1717243200000).UTC()
+	store := cachedStore("plant/node1",
+		Definition{Name: "temperature", Alias: 1, HasAlias: true, Datatype: DataTypeDouble},
+		Definition{Name: "offset", Alias: 2, HasAlias: true, Datatype: DataTypeInt16},
+		Definition{Name: "level", Datatype: DataTypeInt8},
+		Definition{Device: "pump_3", Name: "running", Alias: 3, HasAlias: true, Datatype: DataTypeBoolean},
+		Definition{Device: "pump_3", Name: "rpm", Alias: 4, HasAlias: true, Datatype: DataTypeUInt32},
+	)
+	timed := metric("", 1, 21.5)
+	timed.Timestamp = proto.Uint64(1717243260000)
+	overridden := metric("", 2, uint32(5))
+	overridden.Datatype = proto.Uint32(DataTypeUInt16)
+	null := metric("", 1, nil)
+	null.IsNull = proto.Bool(true)
+
+	tests := []struct {
+		name           string
+		topic          string
+		timestamp      *uint64
+		metrics        []*sparkplugpb.Payload_Metric
+		want           []sample
+		skipped        int
+		unknownAliases int
+	}{
+		{
+			name:    "alias resolves name and datatype",
+			topic:   "spBv1.0/plant/NDATA/node1",
+			metrics: []*sparkplugpb.Payload_Metric{metric("", 1, 20.5), metric("", 2, uint32(0xfffe))},
+			want: []sample{
+				{"node1", "temperature", now, 20.5},
+				{"node1", "offset", now, -2},
+			},
+		},
+		{
+			name:    "named metric takes the announced datatype",
+			topic:   "spBv1.0/plant/NDATA/node1",
+			metrics: []*sparkplugpb.Payload_Metric{metric("level", 0, uint32(0xff))},
+			want:    []sample{{"node1", "level", now, -1}},
+		},
+		{
+			name:    "datatype in the message wins",
+			topic:   "spBv1.0/plant/NDATA/node1",
+			metrics: []*sparkplugpb.Payload_Metric{overridden},
+			want:    []sample{{"node1", "offset", now, 5}},
+		},
+		{
+			name:      "payload and metric timestamps",
+			topic:     "spBv1.0/plant/NDATA/node1",
+			timestamp: proto.Uint64(uint64(sent.UnixMilli())),
+			metrics:   []*sparkplugpb.Payload_Metric{metric("", 1, 21.0), timed},
+			want: []sample{
+				{"node1", "temperature", sent, 21},
+				{"node1", "temperature", sent.Add(time.Minute), 21.5},
+			},
+		},
+		{
+			name:    "device aliases are unique per edge node",
+			topic:   "spBv1.0/plant/DDATA/node1/pump_3",
+			metrics: []*sparkplugpb.Payload_Metric{metric("", 3, true), metric("", 4, uint32(1450))},
+			want: []sample{
+				{"node1/pump_3", "running", now, 1},
+				{"node1/pump_3", "rpm", now, 1450},
+			},
+		},
+		{
+			name:           "unknown alias",
+			topic:          "spBv1.0/plant/NDATA/node1",
+			metrics:        []*sparkplugpb.Payload_Metric{metric("", 9, 1.0), metric("", 1, 22.0)},
+			want:           []sample{{"node1", "temperature", now, 22}},
+			unknownAliases: 1,
+		},
+		{
+			name:    "null and string metrics are skipped",
+			topic:   "spBv1.0/plant/NDATA/node1",
+			metrics: []*sparkplugpb.Payload_Metric{null, metric("label", 0, "A")},
+			skipped: 2,
+		},
+		{
+			name:  "death certificate",
+			topic: "spBv1.0/plant/NDEATH/node1",
+			want:  []sample{{"node1", OnlineCargoID, now, 0}},
+		},
+	}
+	d := &Decoder{Aliases: store}
+	for _, tt := range tests {
+		topic, err := ParseTopic(tt.topic)
+		if err != nil {
+			t.Fatalf("%s: ParseTopic() = %v", tt.name, err)
+		}
+		body, err := proto.Marshal(&sparkplugpb.Payload{Timestamp: tt.timestamp, Metrics: tt.metrics})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := d.Decode(context.Background(), topic, body, now)
+		if err != nil {
+			t.Fatalf("%s: Decode() = %v", tt.name, err)
+		}
+		if res.Skipped != tt.skipped || res.UnknownAliases != tt.unknownAliases {
+			t.Errorf("%s: skipped %d, unknown aliases %d, want %d, %d", tt.name, res.Skipped, res.UnknownAliases, tt.skipped, tt.unknownAliases)
+		}
+		var got []sample
+		for _, p := range res.Points {
+			got = append(got, sample{p.ShipID, p.CargoID, p.Time, *p.Value})
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Decode() = %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].ship != tt.want[i].ship || got[i].cargo != tt.want[i].cargo || !got[i].time.Equal(tt.want[i].time) || got[i].value != tt.want[i].value {
+				t.Errorf("%s: point %d = %+v, want %+v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
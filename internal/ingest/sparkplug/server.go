@@ -0,0 +1,142 @@
+package sparkplug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/ingest/listener"
+	"go-ingest-service/internal/ingest/sparkplug/sparkplugpb"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"google.golang.org/protobuf/proto"
+)
+
+// rebirthInterval limits how often an edge node is asked for a new birth
+// certificate after sending an unknown alias.
+const rebirthInterval = 30 * time.Second
+
+// rebirthMetric is the node control metric that requests a new NBIRTH.
+const rebirthMetric = "Node Control/Rebirth"
+
+// Server subscribes to Sparkplug B topics on an MQTT broker and queues the
+// decoded metrics.
+type Server struct {
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	Topic    string
+	Batcher  *listener.Batcher
+	// Rebirth enables NCMD rebirth requests for unknown aliases.
+	Rebirth bool
+
+	decoder     *Decoder
+	client      mqtt.Client
+	mu          sync.Mutex
+	lastRebirth map[string]time.Time
+}
+
+// ListenAndServe connects to the broker and processes messages until ctx is
+// cancelled. The client reconnects and resubscribes on its own after a
+// connection loss.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.decoder = &Decoder{Aliases: NewAliasStore()}
+	s.lastRebirth = make(map[string]time.Time)
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.Broker).
+		SetClientID(s.ClientID).
+		SetUsername(s.Username).
+		SetPassword(s.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(true).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("[Sparkplug] Connection to %s lost: %v", s.Broker, err)
+		}).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			token := c.Subscribe(s.Topic, 1, func(_ mqtt.Client, msg mqtt.Message) {
+				s.handleMessage(ctx, msg.Topic(), msg.Payload())
+			})
+			if token.Wait() && token.Error() != nil {
+				log.Printf("[Sparkplug] Failed to subscribe to %s: %v", s.Topic, token.Error())
+				return
+			}
+			log.Printf("[Sparkplug] Subscribed to %s on %s", s.Topic, s.Broker)
+		})
+
+	s.client = mqtt.NewClient(opts)
+	token := s.client.Connect()
+	select {
+	case <-ctx.Done():
+	case <-token.Done():
+		// With SetConnectRetry the token only completes once connected.
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("connecting to %s: %w", s.Broker, err)
+		}
+		<-ctx.Done()
+	}
+
+	s.client.Disconnect(250)
+	return nil
+}
+
+func (s *Server) handleMessage(ctx context.Context, topicName string, body []byte) {
+	topic, err := ParseTopic(topicName)
+	if errors.Is(err, ErrUnsupported) {
+		return
+	}
+	if err != nil {
+		log.Printf("[Sparkplug] Skipping message: %v", err)
+		return
+	}
+
+	result, err := s.decoder.Decode(ctx, topic, body, time.Now())
+	if err != nil {
+		log.Printf("[Sparkplug] Skipping %s message from %s: %v", topic.Type, topic.ShipID(), err)
+		return
+	}
+	for _, p := range result.Points {
+		s.Batcher.Add(p)
+	}
+	if result.UnknownAliases > 0 {
+		log.Printf("[Sparkplug] %d metric(s) from %s use unknown aliases", result.UnknownAliases, topic.ShipID())
+		s.requestRebirth(topic)
+	}
+}
+
+// requestRebirth asks an edge node to publish a new NBIRTH, at most once per
+// rebirthInterval.
+func (s *Server) requestRebirth(topic Topic) {
+	if !s.Rebirth {
+		return
+	}
+	s.mu.Lock()
+	if time.Since(s.lastRebirth[topic.NodeKey()]) < rebirthInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastRebirth[topic.NodeKey()] = time.Now()
+	s.mu.Unlock()
+
+	payload := &sparkplugpb.Payload{
+		Timestamp: proto.Uint64(uint64(time.Now().UnixMilli())),
+		Metrics: []*sparkplugpb.Payload_Metric{{
+			Name:     proto.String(rebirthMetric),
+			Datatype: proto.Uint32(DataTypeBoolean),
+			Value:    &sparkplugpb.Payload_Metric_BooleanValue{BooleanValue: true},
+		}},
+	}
+	body, err := proto.Marshal(payload)
+	if err != nil {
+		log.Printf("[Sparkplug] Failed to encode rebirth request: %v", err)
+		return
+	}
+	cmdTopic := Namespace + "/" + topic.Group + "/" + TypeNCmd + "/" + topic.Node
+	s.client.Publish(cmdTopic, 0, false, body)
+	log.Printf("[Sparkplug] Requested rebirth from %s", topic.NodeKey())
+}
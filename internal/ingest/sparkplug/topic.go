@@ -0,0 +1,96 @@
+// Package sparkplug decodes Sparkplug B payloads received over MQTT into
+// general sensor points. Edge nodes (and their devices) map to ship IDs and
+// metric names to cargo IDs; metric aliases announced in birth certificates
+// are kept in Redis so they survive restarts of the API.
+package sparkplug
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Namespace is the topic namespace of Sparkplug B.
+const Namespace = "spBv1.0"
+
+// Message types used in the topic.
+const (
+	TypeNBirth = "NBIRTH"
+	TypeNDeath = "NDEATH"
+	TypeNData  = "NDATA"
+	TypeNCmd   = "NCMD"
+	TypeDBirth = "DBIRTH"
+	TypeDDeath = "DDEATH"
+	TypeDData  = "DDATA"
+	TypeDCmd   = "DCMD"
+)
+
+// ErrUnsupported is returned for topics that carry no data, e.g. commands or
+// host application STATE messages.
+var ErrUnsupported = errors.New("unsupported sparkplug message")
+
+// Topic is a parsed `spBv1.0/<group>/<type>/<edge node>[/<device>]` topic.
+type Topic struct {
+	Group  string
+	Type   string
+	Node   string
+	Device string
+}
+
+// ParseTopic splits a Sparkplug B topic into its parts.
+func ParseTopic(topic string) (Topic, error) {
+	parts := strings.Split(topic, "/")
+	if len(parts) < 2 || parts[0] != Namespace {
+		return Topic{}, fmt.Errorf("not a %s topic: %q", Namespace, topic)
+	}
+	if parts[1] == "STATE" {
+		return Topic{}, ErrUnsupported
+	}
+	if len(parts) < 4 || len(parts) > 5 {
+		return Topic{}, fmt.Errorf("malformed topic %q", topic)
+	}
+
+	t := Topic{Group: parts[1], Type: parts[2], Node: parts[3]}
+	if len(parts) == 5 {
+		t.Device = parts[4]
+	}
+	switch t.Type {
+	case TypeNBirth, TypeNDeath, TypeNData:
+		if t.Device != "" {
+			return Topic{}, fmt.Errorf("%s topic must not name a device: %q", t.Type, topic)
+		}
+	case TypeDBirth, TypeDDeath, TypeDData:
+		if t.Device == "" {
+			return Topic{}, fmt.Errorf("%s topic must name a device: %q", t.Type, topic)
+		}
+	case TypeNCmd, TypeDCmd:
+		return Topic{}, ErrUnsupported
+	default:
+		return Topic{}, fmt.Errorf("unknown message type %q", t.Type)
+	}
+	return t, nil
+}
+
+// NodeKey identifies an edge node; aliases are unique per edge node.
+func (t Topic) NodeKey() string {
+	return t.Group + "/" + t.Node
+}
+
+// ShipID is the edge node ID for node messages and `<node>/<device>` for
+// device messages.
+func (t Topic) ShipID() string {
+	if t.Device == "" {
+		return t.Node
+	}
+	return t.Node + "/" + t.Device
+}
+
+// IsBirth reports whether the message is a birth certificate.
+func (t Topic) IsBirth() bool {
+	return t.Type == TypeNBirth || t.Type == TypeDBirth
+}
+
+// IsDeath reports whether the message is a death certificate.
+func (t Topic) IsDeath() bool {
+	return t.Type == TypeNDeath || t.Type == TypeDDeath
+}
@@ -0,0 +1,182 @@
+// Package statsd implements a StatsD UDP listener. Gauges, counters and timers
+// are aggregated in memory and written as data points once per flush window.
+package statsd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ingest/listener"
+)
+
+// Server receives StatsD metrics and flushes aggregates into a batcher.
+//
+// Each flush writes, per metric path:
+//   - gauges:   the last value, if it was updated during the window
+//   - counters: the sum of (sample-rate corrected) increments in the window
+//   - timers:   "<path>.count", ".min", ".max", ".mean" and ".p95"
+type Server struct {
+	Addr          string
+	FlushInterval time.Duration
+	Templates     []listener.PathTemplate
+	DefaultShip   string
+	Batcher       *listener.Batcher
+
+	mu       sync.Mutex
+	gauges   map[string]float64
+	dirty    map[string]bool // gauges updated since the last flush
+	counters map[string]float64
+	timers   map[string][]float64
+}
+
+// ListenAndServe listens for StatsD datagrams until ctx is cancelled. It
+// returns once the aggregates left in the last window have been flushed into
+// the batcher.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.mu.Lock()
+	s.gauges = make(map[string]float64)
+	s.dirty = make(map[string]bool)
+	s.counters = make(map[string]float64)
+	s.timers = make(map[string][]float64)
+	s.mu.Unlock()
+
+	// The flush loop outlives the listener so that its final flush covers
+	// every datagram that was read.
+	flushCtx, stopFlush := context.WithCancel(context.Background())
+	flushDone := make(chan struct{})
+	go func() {
+		defer close(flushDone)
+		s.flushLoop(flushCtx)
+	}()
+
+	err := listener.ServeUDP(ctx, "StatsD", s.Addr, s.handleLine)
+	stopFlush()
+	<-flushDone
+	return err
+}
+
+func (s *Server) handleLine(line string) {
+	if err := s.parseLine(line); err != nil {
+		log.Printf("[StatsD] Skipping line %q: %v", line, err)
+	}
+}
+
+// parseLine parses "name:value|type[|@rate][|#tags]" and updates the aggregates.
+// Tags are accepted but ignored.
+func (s *Server) parseLine(line string) error {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("missing metric name")
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return fmt.Errorf("missing metric type")
+	}
+	rawValue, metricType := parts[0], parts[1]
+
+	sampleRate := 1.0
+	for _, p := range parts[2:] {
+		if strings.HasPrefix(p, "@") {
+			rate, err := strconv.ParseFloat(p[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return fmt.Errorf("invalid sample rate %q", p)
+			}
+			sampleRate = rate
+		}
+	}
+
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("invalid value %q", rawValue)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch metricType {
+	case "g":
+		// A leading sign makes the gauge value relative to its current value.
+		if strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-") {
+			value += s.gauges[name]
+		}
+		s.gauges[name] = value
+		s.dirty[name] = true
+	case "c":
+		s.counters[name] += value / sampleRate
+	case "ms", "h", "d":
+		s.timers[name] = append(s.timers[name], value)
+	default:
+		return fmt.Errorf("unsupported metric type %q", metricType)
+	}
+	return nil
+}
+
+func (s *Server) flushLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(time.Now())
+			return
+		case now := <-ticker.C:
+			s.flush(now)
+		}
+	}
+}
+
+// flush writes the aggregates for the window ending at now and resets them.
+func (s *Server) flush(now time.Time) {
+	s.mu.Lock()
+	counters, timers, dirty := s.counters, s.timers, s.dirty
+	s.counters = make(map[string]float64)
+	s.timers = make(map[string][]float64)
+	s.dirty = make(map[string]bool)
+	gauges := make(map[string]float64, len(dirty))
+	for name := range dirty {
+		gauges[name] = s.gauges[name]
+	}
+	s.mu.Unlock()
+
+	ts := now.UTC().Truncate(time.Millisecond)
+	for name, v := range gauges {
+		s.add(ts, name, v)
+	}
+	for name, v := range counters {
+		s.add(ts, name, v)
+	}
+	for name, values := range timers {
+		sort.Float64s(values)
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		s.add(ts, name+".count", float64(len(values)))
+		s.add(ts, name+".min", values[0])
+		s.add(ts, name+".max", values[len(values)-1])
+		s.add(ts, name+".mean", sum/float64(len(values)))
+		s.add(ts, name+".p95", percentile(values, 0.95))
+	}
+}
+
+func (s *Server) add(ts time.Time, metric string, value float64) {
+	shipID, cargoID := listener.MapPath(s.Templates, metric, s.DefaultShip)
+	s.Batcher.Add(general.SensorData{Time: ts, ShipID: shipID, CargoID: cargoID, Value: &value})
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -10,18 +10,36 @@ import (
 
 // APIKeyAuth extracts and verifies the X-API-Key header.
 func APIKeyAuth(c *fiber.Ctx) error {
-	log.Printf("[Auth] APIKeyAuth: Starting for %s %s", c.Method(), c.OriginalURL())
-	apiKey := c.Get("X-API-Key")
+	return verifyAPIKey(c, c.Get("X-API-Key"))
+}
+
+// APIKeyAuthFrom verifies the API key from a protocol's native header, falling
+// back to a query parameter if queryParam is not empty. It lets compatibility
+// endpoints accept agents configured for other backends without changes.
+func APIKeyAuthFrom(header, queryParam string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey := c.Get(header)
+		if apiKey == "" && queryParam != "" {
+			apiKey = c.Query(queryParam)
+		}
+		return verifyAPIKey(c, apiKey)
+	}
+}
+
+// verifyAPIKey compares the supplied key with the configured one. Only the
+// path is logged, as some protocols pass the key in the query string.
+func verifyAPIKey(c *fiber.Ctx, apiKey string) error {
+	log.Printf("[Auth] APIKeyAuth: Starting for %s %s", c.Method(), c.Path())
 
 	// Check if the API key is missing
 	if apiKey == "" {
-		log.Printf("[Auth] APIKeyAuth: API key missing for %s %s. Returning 403 Forbidden.", c.Method(), c.OriginalURL())
+		log.Printf("[Auth] APIKeyAuth: API key missing for %s %s. Returning 403 Forbidden.", c.Method(), c.Path())
 		return fiber.NewError(http.StatusForbidden, "API key is missing")
 	}
 
 	// Verify the API key against the one from the configuration
 	if apiKey != config.AppConfig.APIKey {
-		log.Printf("[Auth] APIKeyAuth: Invalid API key provided for %s %s. Returning 401 Unauthorized.", c.Method(), c.OriginalURL())
+		log.Printf("[Auth] APIKeyAuth: Invalid API key provided for %s %s. Returning 401 Unauthorized.", c.Method(), c.Path())
 		return fiber.NewError(http.StatusUnauthorized, "Invalid API key")
 	}
 
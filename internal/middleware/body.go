@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrBodyTooLarge is returned when a request body is read past its size limit.
+var ErrBodyTooLarge = errors.New("request body exceeds limit")
+
+// streamBodyKey holds the *streamBody of a streaming route in c.Locals.
+const streamBodyKey = "streamBody"
+
+// streamBody is the body of a streaming route: a reader that fails after
+// limit bytes.
+type streamBody struct {
+	r     io.Reader
+	limit int64
+}
+
+// BodyLimit reads the request body into memory, rejecting bodies larger than
+// limit bytes with 413. The app streams request bodies so that streaming
+// routes can consume them incrementally, which disables fasthttp's own limit;
+// every other route must pass through this so c.Body() never reads an
+// unbounded stream. Requests for which skip returns true are left streaming.
+func BodyLimit(limit int64, skip func(*fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stream := c.Context().RequestBodyStream()
+		if stream == nil || (skip != nil && skip(c)) {
+			return c.Next()
+		}
+		body, err := io.ReadAll(&limitedReader{r: stream, n: limit})
+		if errors.Is(err, ErrBodyTooLarge) {
+			return fiber.NewError(http.StatusRequestEntityTooLarge, "Request body is too large")
+		}
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "Failed to read request body")
+		}
+		c.Request().SetBody(body)
+		return c.Next()
+	}
+}
+
+// StreamingBody marks a route as consuming its body incrementally through
+// RequestBodyReader. Reads fail with ErrBodyTooLarge after limit bytes.
+func StreamingBody(limit int64) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(streamBodyKey, &streamBody{r: &limitedReader{r: rawBody(c), n: limit}, limit: limit})
+		return c.Next()
+	}
+}
+
+// RequestBodyReader returns the request body as a reader. On streaming routes
+// this is the capped stream set up by StreamingBody; elsewhere it is the
+// buffered body.
+func RequestBodyReader(c *fiber.Ctx) io.Reader {
+	if stream, ok := c.Locals(streamBodyKey).(*streamBody); ok {
+		return stream.r
+	}
+	return bytes.NewReader(c.Body())
+}
+
+// rawBody returns the request body without Fiber's automatic decoding.
+func rawBody(c *fiber.Ctx) io.Reader {
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.Request().Body())
+}
+
+// limitedReader is like io.LimitedReader, but reports ErrBodyTooLarge instead
+// of a silent EOF when the limit is exceeded.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// Allow one byte past the limit so an exactly-sized body still sees EOF.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
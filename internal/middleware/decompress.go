@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-ingest-service/internal/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// snappyStreamMagic is the stream identifier chunk that starts a framed snappy stream.
+var snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+// RequestDecompression decodes request bodies sent with a Content-Encoding of
+// gzip, deflate, zstd or snappy. Output is capped at MAX_DECOMPRESSED_BODY_MB
+// to protect against decompression bombs, and the Content-Encoding header is
+// removed so handlers see a plain body. On streaming routes the body is
+// decoded on the fly as the handler reads it, capped at the route's limit
+// instead, so compressed uploads are never held in memory.
+func RequestDecompression(c *fiber.Ctx) error {
+	encodingHeader := strings.TrimSpace(c.Get(fiber.HeaderContentEncoding))
+	if encodingHeader == "" || strings.EqualFold(encodingHeader, "identity") {
+		return c.Next()
+	}
+
+	stream, streaming := c.Locals(streamBodyKey).(*streamBody)
+	limit := config.AppConfig.MaxDecompressedBodyBytes
+	var r io.Reader
+	if streaming {
+		limit = stream.limit
+		r = stream.r
+	} else {
+		// The compressed body can never be larger than the decompressed limit
+		// either. Read it raw, as c.Body() would already decode some encodings.
+		body := c.Request().Body()
+		if int64(len(body)) > limit {
+			return fiber.NewError(http.StatusRequestEntityTooLarge, "Request body is too large")
+		}
+		r = bytes.NewReader(body)
+	}
+
+	// Encodings are listed in the order they were applied, so undo them in reverse.
+	var decoders []io.Closer
+	defer func() {
+		for _, d := range decoders {
+			d.Close()
+		}
+	}()
+	encodings := strings.Split(encodingHeader, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		decoded, err := newDecoder(encoding, r, limit)
+		if err != nil {
+			return decodeError(c, encoding, err)
+		}
+		if closer, ok := decoded.(io.Closer); ok {
+			decoders = append(decoders, closer)
+		}
+		r = &limitedReader{r: decoded, n: limit}
+	}
+	c.Request().Header.Del(fiber.HeaderContentEncoding)
+
+	if streaming {
+		stream.r = r
+		return c.Next()
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return decodeError(c, encodingHeader, err)
+	}
+	c.Request().SetBody(body)
+	return c.Next()
+}
+
+// decodeError maps a decoding failure to an HTTP error.
+func decodeError(c *fiber.Ctx, encoding string, err error) error {
+	log.Printf("[Decompress] Failed to decode %s body for %s %s: %v", encoding, c.Method(), c.Path(), err)
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return fiber.NewError(http.StatusRequestEntityTooLarge, "Decompressed request body is too large")
+	case errors.Is(err, errors.ErrUnsupported):
+		return fiber.NewError(http.StatusUnsupportedMediaType, "Unsupported Content-Encoding: "+encoding)
+	default:
+		return fiber.NewError(http.StatusBadRequest, "Invalid "+encoding+" request body")
+	}
+}
+
+// newDecoder wraps r to remove a single content coding. limit bounds the
+// memory a decoder may allocate up front.
+func newDecoder(encoding string, r io.Reader, limit int64) (io.Reader, error) {
+	switch encoding {
+	case "identity", "":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// HTTP deflate is zlib-wrapped, but many clients send raw DEFLATE.
+		br := bufio.NewReader(r)
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "zstd":
+		// The window a frame may ask for is bounded by the buffered body limit
+		// even on streaming routes.
+		maxMemory := min(limit, config.AppConfig.MaxDecompressedBodyBytes)
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxMemory)))
+		if err != nil {
+			return nil, err
+		}
+		return zstdReader{zr.IOReadCloser()}, nil
+	case "snappy", "x-snappy-framed":
+		br := bufio.NewReader(r)
+		if magic, err := br.Peek(len(snappyStreamMagic)); err == nil && bytes.Equal(magic, snappyStreamMagic) {
+			return snappy.NewReader(br), nil
+		}
+		// Block format cannot be streamed, but the decoded length is in the
+		// header, so check it before allocating.
+		body, err := io.ReadAll(&limitedReader{r: br, n: limit})
+		if err != nil {
+			return nil, err
+		}
+		n, err := snappy.DecodedLen(body)
+		if err != nil {
+			return nil, err
+		}
+		if int64(n) > limit {
+			return nil, ErrBodyTooLarge
+		}
+		decoded, err := snappy.Decode(nil, body)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(decoded), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errors.ErrUnsupported, encoding)
+	}
+}
+
+// zstdReader reports frames that need more memory than the decoder allows as
+// ErrBodyTooLarge, like every other decoder that exceeds the limit.
+type zstdReader struct {
+	io.ReadCloser
+}
+
+func (z zstdReader) Read(p []byte) (int, error) {
+	n, err := z.ReadCloser.Read(p)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		err = fmt.Errorf("%w: %v", ErrBodyTooLarge, err)
+	}
+	return n, err
+}
+
+// isZlibHeader reports whether b starts a zlib stream (RFC 1950) using DEFLATE.
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
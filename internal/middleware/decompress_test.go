@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-ingest-service/internal/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+const testLimit = 1 << 10
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func zlibbed(b []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func deflated(b []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func zstded(b []byte) []byte {
+	w, _ := zstd.NewWriter(nil)
+	defer w.Close()
+	return w.EncodeAll(b, nil)
+}
+
+func snappyFramed(b []byte) []byte {
+	var buf bytes.Buffer
+	w := snappy.NewBufferedWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestRequestDecompression(t *testing.T) {
+	config.AppConfig = &config.Config{MaxDecompressedBodyBytes: testLimit}
+	small := []byte(`{"ship_id":"vessel_1","cargo_id":"temperature","value":25.5}`)
+	exact := bytes.Repeat([]byte("a"), testLimit)
+	bomb := make([]byte, 64*testLimit) // Compresses to far less than the limit.
+	// A snappy block whose header claims 1 GiB, followed by a few literal bytes.
+	lyingSnappy := append(binary.AppendUvarint(nil, 1<<30), 0x08, 'a', 'b', 'c')
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		want     []byte
+	}{
+		{"no encoding", "", small, http.StatusOK, small},
+		{"identity", "identity", small, http.StatusOK, small},
+		{"gzip", "gzip", gzipped(small), http.StatusOK, small},
+		{"gzip at the limit", "gzip", gzipped(exact), http.StatusOK, exact},
+		{"gzip bomb", "gzip", gzipped(bomb), http.StatusRequestEntityTooLarge, nil},
+		{"zlib deflate", "deflate", zlibbed(small), http.StatusOK, small},
+		{"raw deflate", "deflate", deflated(small), http.StatusOK, small},
+		{"deflate bomb", "deflate", zlibbed(bomb), http.StatusRequestEntityTooLarge, nil},
+		{"zstd", "zstd", zstded(small), http.StatusOK, small},
+		{"zstd bomb", "zstd", zstded(bomb), http.StatusRequestEntityTooLarge, nil},
+		{"snappy block", "snappy", snappy.Encode(nil, small), http.StatusOK, small},
+		{"snappy block bomb", "snappy", snappy.Encode(nil, bomb), http.StatusRequestEntityTooLarge, nil},
+		{"snappy block claiming more than the limit", "snappy", lyingSnappy, http.StatusRequestEntityTooLarge, nil},
+		{"snappy framed", "x-snappy-framed", snappyFramed(small), http.StatusOK, small},
+		{"snappy framed bomb", "snappy", snappyFramed(bomb), http.StatusRequestEntityTooLarge, nil},
+		{"stacked encodings", "deflate, gzip", gzipped(zlibbed(small)), http.StatusOK, small},
+		{"stacked bomb", "gzip, gzip", gzipped(gzipped(bomb)), http.StatusRequestEntityTooLarge, nil},
+		{"compressed body over the limit", "gzip", bytes.Repeat([]byte{0}, testLimit+1), http.StatusRequestEntityTooLarge, nil},
+		{"unsupported", "br", small, http.StatusUnsupportedMediaType, nil},
+		{"corrupt", "gzip", small, http.StatusBadRequest, nil},
+	}
+
+	app := fiber.New()
+	app.Post("/", RequestDecompression, func(c *fiber.Ctx) error {
+		if enc := c.Get(fiber.HeaderContentEncoding); enc != "" && enc != "identity" {
+			t.Errorf("Content-Encoding %q left for the handler", enc)
+		}
+		return c.Send(c.Body())
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set(fiber.HeaderContentEncoding, tt.encoding)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", resp.StatusCode, tt.status, strings.TrimSpace(string(got)))
+			}
+			if tt.want != nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		size    int
+		wantErr bool
+	}{
+		{0, false},
+		{testLimit - 1, false},
+		{testLimit, false},
+		{testLimit + 1, true},
+		{10 * testLimit, true},
+	}
+	for _, tt := range tests {
+		r := &limitedReader{r: bytes.NewReader(make([]byte, tt.size)), n: testLimit}
+		got, err := io.ReadAll(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("size %d: err = %v, want error %v", tt.size, err, tt.wantErr)
+		}
+		if !tt.wantErr && len(got) != tt.size {
+			t.Errorf("size %d: read %d bytes", tt.size, len(got))
+		}
+	}
+}
@@ -4,9 +4,24 @@ import "net/http"
 
 // QueuedData is the generic wrapper for any data pushed to the queue.
 type QueuedData struct {
+	ID         string      `json:"id,omitempty"`
 	RetryCount int         `json:"retry_count"`
 	Type       string      `json:"type"` // e.g., "general", "gps"
 	Data       interface{} `json:"data"`
+	AwaitAck   bool        `json:"await_ack,omitempty"` // Set for synchronous ingest requests.
+}
+
+// IngestAck is published by the worker once a queued message has been committed
+// (or given up on) so that synchronous ingest requests can report the outcome.
+type IngestAck struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"` // "committed" or "failed"
+	Inserted    int64  `json:"inserted"`
+	Skipped     int64  `json:"skipped"`     // Duplicates and points without a value.
+	Quarantined int64  `json:"quarantined"` // Failed validation.
+	Dropped     int64  `json:"dropped"`     // Discarded by a transform rule.
+	Compressed  int64  `json:"compressed"`  // Left out or held back by compression.
+	Error       string `json:"error,omitempty"`
 }
 
 // ErrorDetail and ErrorResponse are for structured API error messages.
@@ -17,13 +32,31 @@ type ErrorDetail struct {
 }
 
 type ErrorResponse struct {
-	StatusCode int    `json:"status_code"`
-	Message    string `json:"message"`
+	StatusCode int           `json:"status_code"`
+	Message    string        `json:"message"`
+	Details    []ErrorDetail `json:"details,omitempty"`
 }
 
 func NewValidationError(details []ErrorDetail) ErrorResponse {
 	return ErrorResponse{
 		StatusCode: http.StatusBadRequest,
 		Message:    "Validation Error",
+		Details:    details,
 	}
 }
+
+// RejectedItem describes a batch element that was dropped in partial ingest mode.
+type RejectedItem struct {
+	Index  int           `json:"index"`
+	Errors []ErrorDetail `json:"errors"`
+}
+
+// PartialIngestResponse is returned with 207 Multi-Status when only part of a batch was accepted.
+type PartialIngestResponse struct {
+	StatusCode int            `json:"status_code"`
+	Message    string         `json:"message"`
+	Accepted   int            `json:"accepted"`
+	Rejected   []RejectedItem `json:"rejected"`
+	MessageID  string         `json:"message_id,omitempty"`
+	Commit     *IngestAck     `json:"commit,omitempty"` // Set for synchronous requests once committed.
+}
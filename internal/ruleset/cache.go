@@ -0,0 +1,63 @@
+// Package ruleset holds what the worker's rule engines share: an in-memory
+// cache of their rules, reloaded from Postgres periodically, and a Redis store
+// for the state they keep per rule and series.
+package ruleset
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Cache holds the value an engine loads from Postgres, typically its enabled
+// rules. Engines embed it to get Refresh and Run.
+type Cache[T any] struct {
+	name string
+	what string
+	load func(context.Context) (T, error)
+
+	mu      sync.RWMutex
+	current T
+}
+
+// NewCache creates an empty cache; call Refresh to load it. name is the log
+// prefix of the engine and what names the loaded value in its log messages.
+func NewCache[T any](name, what string, load func(context.Context) (T, error)) *Cache[T] {
+	return &Cache[T]{name: name, what: what, load: load}
+}
+
+// Current returns the value last loaded. It must not be modified.
+func (c *Cache[T]) Current() T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+// Refresh reloads the value from Postgres.
+func (c *Cache[T]) Refresh(ctx context.Context) error {
+	v, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.current = v
+	c.mu.Unlock()
+	return nil
+}
+
+// Run reloads the value every interval until ctx is cancelled.
+func (c *Cache[T]) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[%s] Failed to load %s: %v", c.name, c.what, err)
+			}
+		}
+	}
+}
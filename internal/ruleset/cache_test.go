@@ -0,0 +1,48 @@
+package ruleset
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCacheRefresh(t *testing.T) {
+	errLoad := errors.New("database unavailable")
+	tests := []struct {
+		name  string
+		loads [][]string // nil loads fail
+		want  []string
+	}{
+		{"empty before loading", nil, nil},
+		{"loaded", [][]string{{"a", "b"}}, []string{"a", "b"}},
+		{"replaced", [][]string{{"a"}, {"b", "c"}}, []string{"b", "c"}},
+		{"failed load keeps the last value", [][]string{{"a"}, nil}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var next []string
+			c := NewCache("Test", "rules", func(context.Context) ([]string, error) {
+				if next == nil {
+					return nil, errLoad
+				}
+				return next, nil
+			})
+			for _, load := range tt.loads {
+				next = load
+				err := c.Refresh(context.Background())
+				if (load == nil) != errors.Is(err, errLoad) {
+					t.Fatalf("Refresh() = %v", err)
+				}
+			}
+			got := c.Current()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Current() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Current() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package ruleset
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// StateKey locates the state of a rule for a series: the Redis hash of the
+// rule and the field of the series within it.
+type StateKey struct {
+	RuleID int64
+	Series string
+}
+
+// Series identifies a series within a state hash, e.g. by ship and cargo ID.
+func Series(ids ...string) string {
+	return strings.Join(ids, "\x00")
+}
+
+// StateStore keeps the state S of each rule and series in Redis, so that it
+// survives restarts. R is the engine's reference to a series of a rule; key
+// maps it to where its state is stored. Callers must serialize the batches of
+// a series between Load and Save, as Save overwrites whatever is stored.
+type StateStore[R comparable, S any] struct {
+	name   string
+	prefix string
+	ttl    time.Duration
+	key    func(R) StateKey
+}
+
+// NewStateStore creates a store whose hashes are named after the ingest queue
+// followed by prefix and the rule ID. Hashes expire ttl after their last
+// save, dropping the state of series that stopped reporting. name is the log
+// prefix of the engine.
+func NewStateStore[R comparable, S any](name, prefix string, ttl time.Duration, key func(R) StateKey) *StateStore[R, S] {
+	return &StateStore[R, S]{name: name, prefix: prefix, ttl: ttl, key: key}
+}
+
+func (s *StateStore[R, S]) hash(ruleID int64) string {
+	return config.AppConfig.IngestQueueName + s.prefix + strconv.FormatInt(ruleID, 10)
+}
+
+// Load returns the state of each ref. Series without a stored state, or with
+// one that cannot be read, get a zero state.
+func (s *StateStore[R, S]) Load(ctx context.Context, refs []R) (map[R]*S, error) {
+	cmds := make([]*redis.StringCmd, len(refs))
+	_, err := cache.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, ref := range refs {
+			k := s.key(ref)
+			cmds[i] = pipe.HGet(ctx, s.hash(k.RuleID), k.Series)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	states := make(map[R]*S, len(refs))
+	for i, ref := range refs {
+		st := new(S)
+		if raw, err := cmds[i].Bytes(); err == nil {
+			if err := json.Unmarshal(raw, st); err != nil {
+				k := s.key(ref)
+				log.Printf("[%s] Discarding unreadable state of rule %d for %s: %v", s.name, k.RuleID, strings.ReplaceAll(k.Series, "\x00", "/"), err)
+				st = new(S)
+			}
+		}
+		states[ref] = st
+	}
+	return states, nil
+}
+
+// Save stores the given states.
+func (s *StateStore[R, S]) Save(ctx context.Context, states map[R]*S) error {
+	if len(states) == 0 {
+		return nil
+	}
+	_, err := cache.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for ref, st := range states {
+			raw, err := json.Marshal(st)
+			if err != nil {
+				return err
+			}
+			k := s.key(ref)
+			pipe.HSet(ctx, s.hash(k.RuleID), k.Series, raw)
+			pipe.Expire(ctx, s.hash(k.RuleID), s.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// Reset drops the state of a rule for all series, e.g. once it changed.
+func (s *StateStore[R, S]) Reset(ctx context.Context, ruleID int64) error {
+	return cache.RedisClient.Del(ctx, s.hash(ruleID)).Err()
+}
@@ -0,0 +1,21 @@
+package ruleset
+
+import "testing"
+
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		ids  []string
+		want string
+	}{
+		{[]string{"vessel_1"}, "vessel_1"},
+		{[]string{"vessel_1", "engine_temp"}, "vessel_1\x00engine_temp"},
+		// IDs cannot contain NUL, which Postgres text rejects, so splits never collide.
+		{[]string{"a/b", "c"}, "a/b\x00c"},
+		{[]string{"a", "b/c"}, "a\x00b/c"},
+	}
+	for _, tt := range tests {
+		if got := Series(tt.ids...); got != tt.want {
+			t.Errorf("Series(%q) = %q, want %q", tt.ids, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package sink
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sinkRequest is the body of POST and PUT /sinks.
+type sinkRequest struct {
+	Name    string  `json:"name"     validate:"required,max=200"`
+	Kind    string  `json:"kind"     validate:"required,oneof=http mqtt kafka"`
+	URL     string  `json:"url"      validate:"required,max=2000"`
+	Topic   string  `json:"topic"    validate:"max=500"`
+	Secret  *string `json:"secret"   validate:"omitempty,max=200"`
+	ShipID  string  `json:"ship_id"  validate:"max=100"`
+	CargoID string  `json:"cargo_id" validate:"max=100"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// parseSinkRequest validates a sink body; ship_id and cargo_id default to
+// every series. If the sink is nil the error response has already been
+// written and the returned error is the one to pass back to Fiber.
+func parseSinkRequest(c *fiber.Ctx) (*Sink, *string, error) {
+	var req sinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	if req.CargoID == "" {
+		req.CargoID = "*"
+	}
+	for _, f := range []struct{ field, pattern string }{{"ShipID", req.ShipID}, {"CargoID", req.CargoID}} {
+		if err := glob.Validate(f.pattern); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{f.field}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+		}
+	}
+	if msg := checkTarget(req.Kind, req.URL, req.Topic); msg != "" {
+		errs = append(errs, models.ErrorDetail{Loc: []string{"URL"}, Msg: msg, Type: "validation_error.target"})
+	}
+	if len(errs) > 0 {
+		return nil, nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	s := &Sink{
+		Name:    req.Name,
+		Kind:    req.Kind,
+		URL:     req.URL,
+		Topic:   req.Topic,
+		ShipID:  req.ShipID,
+		CargoID: req.CargoID,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if req.Secret != nil {
+		s.Secret = *req.Secret
+	}
+	return s, req.Secret, nil
+}
+
+// checkTarget describes what is wrong with the URL and topic of a sink kind,
+// or returns "" if they are usable.
+func checkTarget(kind, rawURL, topic string) string {
+	switch kind {
+	case KindHTTP:
+		if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "url must be an http or https URL"
+		}
+	case KindMQTT:
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" {
+			return "url must be a broker URL such as tcp://host:1883"
+		}
+		switch u.Scheme {
+		case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+		default:
+			return "url must use tcp, mqtt, ssl, tls, mqtts, ws or wss"
+		}
+		if topic == "" || strings.ContainsAny(topic, "+#") {
+			return "topic is required and must not contain wildcards"
+		}
+	case KindKafka:
+		if _, err := splitBrokers(rawURL); err != nil {
+			return "url must be a comma-separated list of host:port brokers"
+		}
+		if topic == "" || strings.ContainsAny(topic, "{}/ ") {
+			return "topic is required and must be a plain Kafka topic name"
+		}
+	}
+	return ""
+}
+
+// ListOutboundSinks returns all sinks. Secrets are never returned.
+func ListOutboundSinks(c *fiber.Ctx) error {
+	sinks, err := ListSinks(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load sinks")
+	}
+	return c.JSON(sinks)
+}
+
+// GetOutboundSink returns one sink with the status of its queue.
+func GetOutboundSink(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid sink ID")
+	}
+	s, err := GetSink(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load sink")
+	}
+	if s == nil {
+		return fiber.NewError(http.StatusNotFound, "Sink not found")
+	}
+	status, err := Status(c.Context(), s.ID)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load sink queue status")
+	}
+	return c.JSON(fiber.Map{"sink": s, "queue": status})
+}
+
+// CreateOutboundSink stores a new sink. Workers start sending to it within
+// SINK_REFRESH_SEC.
+func CreateOutboundSink(c *fiber.Ctx) error {
+	s, _, err := parseSinkRequest(c)
+	if s == nil {
+		return err
+	}
+	if err := CreateSink(c.Context(), s); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create sink")
+	}
+	return c.Status(http.StatusCreated).JSON(s)
+}
+
+// UpdateOutboundSink replaces a sink; without a secret the current one is
+// kept. Queued deliveries are sent to the new target.
+func UpdateOutboundSink(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid sink ID")
+	}
+	s, secret, err := parseSinkRequest(c)
+	if s == nil {
+		return err
+	}
+	s.ID = int64(id)
+	found, err := UpdateSink(c.Context(), s, secret)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update sink")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Sink not found")
+	}
+	return c.JSON(s)
+}
+
+// DeleteOutboundSink removes a sink. Its queued and dead-lettered deliveries
+// are dropped.
+func DeleteOutboundSink(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid sink ID")
+	}
+	found, err := DeleteSink(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete sink")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Sink not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ReplayOutboundSink moves a sink's dead-lettered deliveries back onto its queue.
+func ReplayOutboundSink(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid sink ID")
+	}
+	s, err := GetSink(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load sink")
+	}
+	if s == nil {
+		return fiber.NewError(http.StatusNotFound, "Sink not found")
+	}
+	n, err := Replay(c.Context(), s.ID)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to replay dead-lettered deliveries")
+	}
+	return c.JSON(fiber.Map{"replayed": n})
+}
@@ -0,0 +1,36 @@
+package sink
+
+import "testing"
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		kind, url, topic string
+		wantErr          bool
+	}{
+		{KindHTTP, "https://example.com/ingest", "", false},
+		{KindHTTP, "http://10.0.0.5:8080", "", false},
+		{KindHTTP, "ftp://example.com", "", true},
+		{KindHTTP, "https://", "", true},
+		{KindHTTP, "example.com/ingest", "", true},
+		{KindMQTT, "tcp://broker:1883", "fleet/{ship_id}/{cargo_id}", false},
+		{KindMQTT, "wss://broker/mqtt", "fleet", false},
+		{KindMQTT, "http://broker:1883", "fleet", true},
+		{KindMQTT, "broker:1883", "fleet", true},
+		{KindMQTT, "tcp://broker:1883", "", true},
+		{KindMQTT, "tcp://broker:1883", "fleet/+/temp", true},
+		{KindMQTT, "tcp://broker:1883", "fleet/#", true},
+		{KindKafka, "kafka1:9092", "telemetry", false},
+		{KindKafka, "kafka1:9092, kafka2:9092", "telemetry.raw", false},
+		{KindKafka, "kafka1", "telemetry", true},
+		{KindKafka, "kafka1:9092,", "telemetry", true},
+		{KindKafka, "kafka1:9092", "", true},
+		{KindKafka, "kafka1:9092", "{ship_id}", true},
+		{KindKafka, "kafka1:9092", "fleet/telemetry", true},
+	}
+	for _, tt := range tests {
+		msg := checkTarget(tt.kind, tt.url, tt.topic)
+		if (msg != "") != tt.wantErr {
+			t.Errorf("checkTarget(%s, %q, %q) = %q, want error %v", tt.kind, tt.url, tt.topic, msg, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaSender produces each point as a JSON record keyed by ship ID, so the
+// points of a ship go to one partition. They are not necessarily in order:
+// several workers deliver from the same queue, and a failed delivery is
+// retried after newer ones. The manager retries failed deliveries, so the
+// writer makes a single attempt and waits for all in-sync replicas to
+// acknowledge it.
+type kafkaSender struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSender(s Sink, timeout time.Duration) (*kafkaSender, error) {
+	brokers, err := splitBrokers(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaSender{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        s.Topic,
+		Balancer:     &kafka.Hash{},
+		MaxAttempts:  1,
+		BatchTimeout: 10 * time.Millisecond,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		RequiredAcks: kafka.RequireAll,
+	}}, nil
+}
+
+func (k *kafkaSender) send(ctx context.Context, d delivery) error {
+	msgs := make([]kafka.Message, 0, len(d.Points))
+	for _, p := range d.Points {
+		value, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{Key: []byte(p.ShipID), Value: value, Time: p.Time})
+	}
+	return k.writer.WriteMessages(ctx, msgs...)
+}
+
+func (k *kafkaSender) close() {
+	k.writer.Close()
+}
+
+// splitBrokers parses a comma-separated list of host:port addresses.
+func splitBrokers(s string) ([]string, error) {
+	var brokers []string
+	for _, addr := range strings.Split(s, ",") {
+		addr = strings.TrimSpace(addr)
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid broker address %q", addr)
+		}
+		brokers = append(brokers, addr)
+	}
+	return brokers, nil
+}
@@ -0,0 +1,329 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Manager queues committed points for the enabled sinks and runs a sender
+// for each of them. Sinks are cached in memory and reloaded periodically.
+type Manager struct {
+	timeout     time.Duration
+	maxAttempts int
+	retryBase   time.Duration
+	queueMax    int64
+
+	mu      sync.RWMutex
+	sinks   []Sink
+	runners map[int64]*runner
+}
+
+// runner delivers the queue of one sink until it is stopped.
+type runner struct {
+	sink   Sink
+	sender sender
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewManager creates a manager using the SINK_* settings; call Refresh to
+// load the sinks and start their senders.
+func NewManager() *Manager {
+	return &Manager{
+		timeout:     config.AppConfig.SinkTimeout,
+		maxAttempts: config.AppConfig.SinkMaxAttempts,
+		retryBase:   config.AppConfig.SinkRetryBase,
+		queueMax:    int64(config.AppConfig.SinkQueueMax),
+		runners:     make(map[int64]*runner),
+	}
+}
+
+// Refresh reloads the enabled sinks from Postgres, starting senders for new
+// and changed sinks under ctx and stopping those of removed ones.
+func (m *Manager) Refresh(ctx context.Context) error {
+	sinks, err := ListSinks(ctx, true)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.sinks = sinks
+	current := make(map[int64]Sink, len(sinks))
+	for _, s := range sinks {
+		current[s.ID] = s
+	}
+	var stopped []*runner
+	for id, r := range m.runners {
+		if s, ok := current[id]; !ok || !s.UpdatedAt.Equal(r.sink.UpdatedAt) {
+			stopped = append(stopped, r)
+			delete(m.runners, id)
+		}
+	}
+	var started []*runner
+	for _, s := range sinks {
+		if _, ok := m.runners[s.ID]; ok {
+			continue
+		}
+		snd, err := newSender(s, m.timeout)
+		if err != nil {
+			log.Printf("[Sink] Cannot start sink %d (%s): %v", s.ID, s.Name, err)
+			continue
+		}
+		r := &runner{sink: s, sender: snd, done: make(chan struct{})}
+		r.ctx, r.cancel = context.WithCancel(ctx)
+		m.runners[s.ID] = r
+		started = append(started, r)
+	}
+	m.mu.Unlock()
+
+	// Stop replaced senders before starting their successors, without holding
+	// the lock Enqueue needs.
+	for _, r := range stopped {
+		r.stop()
+	}
+	for _, r := range started {
+		go m.run(r)
+	}
+	return nil
+}
+
+// Run reloads the sinks every interval until ctx is cancelled, then waits for
+// the senders to stop.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.mu.Lock()
+			runners := m.runners
+			m.runners = make(map[int64]*runner)
+			m.mu.Unlock()
+			for _, r := range runners {
+				r.stop()
+			}
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[Sink] Failed to load sinks: %v", err)
+			}
+		}
+	}
+}
+
+func (r *runner) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// Enqueue queues the points of a committed batch for every enabled sink that
+// selects some of them. A delivery that finds its sink's queue holding
+// SINK_QUEUE_MAX deliveries goes straight to the dead-letter list.
+func (m *Manager) Enqueue(ctx context.Context, points []general.SensorData) error {
+	m.mu.RLock()
+	sinks := m.sinks
+	m.mu.RUnlock()
+
+	type queued struct {
+		sinkID int64
+		d      delivery
+	}
+	var pending []queued
+	for i := range sinks {
+		var selected []general.SensorData
+		for _, p := range points {
+			if p.Value != nil && sinks[i].Selects(p.ShipID, p.CargoID) {
+				selected = append(selected, p)
+			}
+		}
+		if len(selected) > 0 {
+			pending = append(pending, queued{sinks[i].ID, delivery{ID: uuid.NewString(), Points: selected}})
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	lengths := make([]*redis.IntCmd, len(pending))
+	if _, err := cache.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, q := range pending {
+			lengths[i] = pipe.LLen(ctx, queueKey(q.sinkID))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	_, err := cache.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, q := range pending {
+			key := queueKey(q.sinkID)
+			if m.queueMax > 0 && lengths[i].Val() >= m.queueMax {
+				log.Printf("[Sink] Queue of sink %d is full, moving delivery %s to DLQ", q.sinkID, q.d.ID)
+				q.d.Error = "queue full"
+				key = dlqKey(q.sinkID)
+			}
+			body, err := json.Marshal(q.d)
+			if err != nil {
+				return err
+			}
+			pipe.RPush(ctx, key, body)
+		}
+		return nil
+	})
+	return err
+}
+
+// run delivers the sink's queue until ctx is cancelled, and requeues failed
+// deliveries once their backoff has elapsed. Deliveries popped are stored
+// back even while shutting down, so none are lost.
+func (m *Manager) run(r *runner) {
+	ctx := r.ctx
+	defer close(r.done)
+	defer r.sender.close()
+	store := context.WithoutCancel(ctx)
+	id := r.sink.ID
+	var lastRequeue time.Time
+
+	for ctx.Err() == nil {
+		if time.Since(lastRequeue) >= time.Second {
+			m.requeueDue(ctx, id)
+			lastRequeue = time.Now()
+		}
+		result, err := cache.RedisClient.BLPop(ctx, time.Second, queueKey(id)).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				log.Printf("[Sink] Error popping from Redis: %v", err)
+				time.Sleep(5 * time.Second)
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			if err := cache.RedisClient.LPush(store, queueKey(id), result[1]).Err(); err != nil {
+				log.Printf("[Sink] CRITICAL: Failed to return delivery to the queue of sink %d. DATA: %s", id, result[1])
+			}
+			return
+		}
+
+		var d delivery
+		if err := json.Unmarshal([]byte(result[1]), &d); err != nil {
+			log.Printf("[Sink] Dropping malformed delivery for sink %d: %v", id, err)
+			continue
+		}
+		if err := r.sender.send(ctx, d); err != nil {
+			log.Printf("[Sink] Delivery %s of %d point(s) to sink %d failed (attempt %d): %v", d.ID, len(d.Points), id, d.Attempt+1, err)
+			m.retry(store, id, d, err)
+		}
+	}
+}
+
+// requeueDue moves deliveries whose retry time has come back onto the queue.
+// ZREM decides which worker owns an entry when several poll the same set.
+func (m *Manager) requeueDue(ctx context.Context, id int64) {
+	due, err := cache.RedisClient.ZRangeByScore(ctx, retryKey(id), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[Sink] Failed to read retry set of sink %d: %v", id, err)
+		}
+		return
+	}
+	for _, member := range due {
+		if removed, err := cache.RedisClient.ZRem(ctx, retryKey(id), member).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if err := cache.RedisClient.RPush(ctx, queueKey(id), member).Err(); err != nil {
+			log.Printf("[Sink] CRITICAL: Failed to requeue delivery for sink %d: %v", id, err)
+		}
+	}
+}
+
+// retry schedules another attempt with exponential backoff, or moves the
+// delivery to the dead-letter list once SINK_MAX_ATTEMPTS is reached.
+func (m *Manager) retry(ctx context.Context, id int64, d delivery, cause error) {
+	d.Attempt++
+	d.Error = cause.Error()
+	body, err := json.Marshal(d)
+	if err != nil {
+		log.Printf("[Sink] CRITICAL: Failed to marshal delivery %s: %v", d.ID, err)
+		return
+	}
+
+	if d.Attempt >= m.maxAttempts {
+		log.Printf("[Sink] Delivery %s exceeded %d attempts, moving to the DLQ of sink %d", d.ID, m.maxAttempts, id)
+		if err := cache.RedisClient.RPush(ctx, dlqKey(id), body).Err(); err != nil {
+			log.Printf("[Sink] CRITICAL: Failed to move delivery to DLQ. DATA: %s", string(body))
+		}
+		return
+	}
+
+	next := time.Now().Add(utils.Backoff(m.retryBase, d.Attempt))
+	if err := cache.RedisClient.ZAdd(ctx, retryKey(id), &redis.Z{Score: float64(next.UnixMilli()), Member: body}).Err(); err != nil {
+		log.Printf("[Sink] CRITICAL: Failed to schedule retry. DATA: %s", string(body))
+	}
+}
+
+// QueueStatus counts the deliveries of a sink that are queued, waiting for a
+// retry and dead-lettered.
+type QueueStatus struct {
+	Queued       int64 `json:"queued"`
+	Retrying     int64 `json:"retrying"`
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+// Status returns the queue status of a sink.
+func Status(ctx context.Context, id int64) (QueueStatus, error) {
+	var queued, retrying, dead *redis.IntCmd
+	_, err := cache.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		queued = pipe.LLen(ctx, queueKey(id))
+		retrying = pipe.ZCard(ctx, retryKey(id))
+		dead = pipe.LLen(ctx, dlqKey(id))
+		return nil
+	})
+	if err != nil {
+		return QueueStatus{}, err
+	}
+	return QueueStatus{Queued: queued.Val(), Retrying: retrying.Val(), DeadLettered: dead.Val()}, nil
+}
+
+// Replay moves the dead-lettered deliveries of a sink back onto its queue
+// with fresh attempts, returning how many were moved.
+func Replay(ctx context.Context, id int64) (int, error) {
+	n := 0
+	for {
+		raw, err := cache.RedisClient.LPop(ctx, dlqKey(id)).Bytes()
+		if err == redis.Nil {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		var d delivery
+		if err := json.Unmarshal(raw, &d); err != nil {
+			log.Printf("[Sink] Dropping malformed dead-lettered delivery for sink %d: %v", id, err)
+			continue
+		}
+		d.Attempt, d.Error = 0, ""
+		body, err := json.Marshal(d)
+		if err != nil {
+			return n, err
+		}
+		if err := cache.RedisClient.RPush(ctx, queueKey(id), body).Err(); err != nil {
+			log.Printf("[Sink] CRITICAL: Failed to replay delivery for sink %d. DATA: %s", id, string(raw))
+			return n, err
+		}
+		n++
+	}
+}
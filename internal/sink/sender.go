@@ -0,0 +1,149 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// sender delivers points to one sink.
+type sender interface {
+	send(ctx context.Context, d delivery) error
+	close()
+}
+
+// newSender creates the sender for a sink's kind.
+func newSender(s Sink, timeout time.Duration) (sender, error) {
+	switch s.Kind {
+	case KindHTTP:
+		return &httpSender{sink: s, client: &http.Client{Timeout: timeout}}, nil
+	case KindMQTT:
+		return newMQTTSender(s, timeout)
+	case KindKafka:
+		return newKafkaSender(s, timeout)
+	}
+	return nil, fmt.Errorf("unknown sink kind %q", s.Kind)
+}
+
+// httpSender posts the points of a delivery as a JSON array.
+type httpSender struct {
+	sink   Sink
+	client *http.Client
+}
+
+// send posts the points, signing the body with the sink secret if it has one.
+func (h *httpSender) send(ctx context.Context, d delivery) error {
+	body, err := json.Marshal(d.Points)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Harbor-Sink", strconv.FormatInt(h.sink.ID, 10))
+	req.Header.Set("X-Harbor-Delivery", d.ID)
+	if h.sink.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.sink.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Harbor-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (h *httpSender) close() {}
+
+// mqttSender publishes each point as a JSON message with QoS 1.
+type mqttSender struct {
+	topic   string
+	timeout time.Duration
+	client  mqtt.Client
+}
+
+// newMQTTSender creates the client; it connects, and reconnects, on its own.
+func newMQTTSender(s Sink, timeout time.Duration) (*mqttSender, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	opts := mqtt.NewClientOptions().
+		SetClientID(fmt.Sprintf("harbor-sink-%d-%s", s.ID, uuid.NewString()[:8])).
+		SetPassword(s.Secret).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true)
+	if u.User != nil {
+		opts.SetUsername(u.User.Username())
+		u.User = nil
+	}
+	opts.AddBroker(u.String())
+
+	m := &mqttSender{topic: s.Topic, timeout: timeout, client: mqtt.NewClient(opts)}
+	m.client.Connect()
+	return m, nil
+}
+
+// send publishes the points and waits for the broker to acknowledge them.
+func (m *mqttSender) send(ctx context.Context, d delivery) error {
+	if !m.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to the broker")
+	}
+	tokens := make([]mqtt.Token, 0, len(d.Points))
+	for _, p := range d.Points {
+		payload, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, m.client.Publish(expandTopic(m.topic, p), 1, false, payload))
+	}
+	deadline := time.Now().Add(m.timeout)
+	for _, token := range tokens {
+		if !token.WaitTimeout(time.Until(deadline)) {
+			return fmt.Errorf("timed out waiting for the broker")
+		}
+		if err := token.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mqttSender) close() {
+	m.client.Disconnect(250)
+}
+
+// topicWildcards are not allowed in the topic of a published message.
+var topicWildcards = strings.NewReplacer("+", "_", "#", "_")
+
+// expandTopic fills in the {ship_id} and {cargo_id} placeholders of an MQTT
+// topic, replacing wildcard characters in the IDs with underscores.
+func expandTopic(topic string, p general.SensorData) string {
+	return strings.NewReplacer(
+		"{ship_id}", topicWildcards.Replace(p.ShipID),
+		"{cargo_id}", topicWildcards.Replace(p.CargoID),
+	).Replace(topic)
+}
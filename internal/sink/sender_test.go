@@ -0,0 +1,26 @@
+package sink
+
+import (
+	"testing"
+
+	"go-ingest-service/internal/ingest/general"
+)
+
+func TestExpandTopic(t *testing.T) {
+	tests := []struct {
+		topic, ship, cargo string
+		want               string
+	}{
+		{"fleet/{ship_id}/{cargo_id}", "vessel_1", "temp", "fleet/vessel_1/temp"},
+		{"fleet/data", "vessel_1", "temp", "fleet/data"},
+		{"{cargo_id}/{cargo_id}", "vessel_1", "temp", "temp/temp"},
+		{"fleet/{ship_id}/{cargo_id}", "vessel+1", "engine/#rpm", "fleet/vessel_1/engine/_rpm"},
+		{"fleet/{ship}/{cargo_id}", "vessel_1", "temp", "fleet/{ship}/temp"},
+	}
+	for _, tt := range tests {
+		got := expandTopic(tt.topic, general.SensorData{ShipID: tt.ship, CargoID: tt.cargo})
+		if got != tt.want {
+			t.Errorf("expandTopic(%q, %q, %q) = %q, want %q", tt.topic, tt.ship, tt.cargo, got, tt.want)
+		}
+	}
+}
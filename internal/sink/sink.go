@@ -0,0 +1,164 @@
+// Package sink forwards committed points to other systems: HTTP endpoints,
+// MQTT topics and Kafka-protocol brokers. The worker queues the points of
+// each committed batch in Redis, one list per sink, and a sender per sink
+// delivers them, retrying failed deliveries with exponential backoff before
+// moving them to a dead-letter list. A slow or unreachable sink only backs up
+// its own queue.
+package sink
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/ingest/general"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Sink kinds.
+const (
+	KindHTTP  = "http"
+	KindMQTT  = "mqtt"
+	KindKafka = "kafka"
+)
+
+// Sink is a destination for the points whose ship and cargo IDs match its
+// glob patterns.
+//
+// URL is the endpoint for http sinks, the broker (e.g. tcp://host:1883, with
+// an optional user name) for mqtt sinks and a comma-separated list of
+// host:port bootstrap brokers for kafka sinks. Topic is the Kafka topic, or
+// the MQTT topic with {ship_id} and {cargo_id} placeholders. Secret signs
+// HTTP bodies and is the MQTT password.
+type Sink struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	URL       string    `json:"url"`
+	Topic     string    `json:"topic"`
+	Secret    string    `json:"-"`
+	HasSecret bool      `json:"has_secret"`
+	ShipID    string    `json:"ship_id"`
+	CargoID   string    `json:"cargo_id"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Selects reports whether the sink wants the points of a series.
+func (s *Sink) Selects(shipID, cargoID string) bool {
+	return glob.Match(s.ShipID, shipID) && glob.Match(s.CargoID, cargoID)
+}
+
+// delivery is a set of points on its way to one sink. Error is the cause of
+// the last failed attempt.
+type delivery struct {
+	ID      string               `json:"id"`
+	Points  []general.SensorData `json:"points"`
+	Attempt int                  `json:"attempt,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// queueKey is the Redis list of pending deliveries to a sink.
+func queueKey(id int64) string {
+	return config.AppConfig.IngestQueueName + "_sink:" + strconv.FormatInt(id, 10)
+}
+
+// retryKey is the Redis sorted set of failed deliveries to a sink, scored by
+// the time of their next attempt.
+func retryKey(id int64) string {
+	return queueKey(id) + "_retry"
+}
+
+// dlqKey is the Redis list of deliveries to a sink that exhausted their
+// attempts or found the queue full.
+func dlqKey(id int64) string {
+	return queueKey(id) + "_dlq"
+}
+
+const sinkColumns = `id, name, kind, url, topic, COALESCE(secret, ''), ship_id, cargo_id, enabled, created_at, updated_at`
+
+func scanSink(row pgx.Row) (Sink, error) {
+	var s Sink
+	err := row.Scan(&s.ID, &s.Name, &s.Kind, &s.URL, &s.Topic, &s.Secret, &s.ShipID, &s.CargoID, &s.Enabled, &s.CreatedAt, &s.UpdatedAt)
+	s.HasSecret = s.Secret != ""
+	return s, err
+}
+
+// ListSinks returns all sinks, or only the enabled ones.
+func ListSinks(ctx context.Context, enabledOnly bool) ([]Sink, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+sinkColumns+` FROM sinks WHERE enabled OR NOT $1 ORDER BY id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sinks := []Sink{}
+	for rows.Next() {
+		s, err := scanSink(rows)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, rows.Err()
+}
+
+// GetSink loads one sink; a nil sink means it does not exist.
+func GetSink(ctx context.Context, id int64) (*Sink, error) {
+	s, err := scanSink(db.Pool.QueryRow(ctx, `SELECT `+sinkColumns+` FROM sinks WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSink stores a new sink and fills in its ID and timestamps.
+func CreateSink(ctx context.Context, s *Sink) error {
+	s.HasSecret = s.Secret != ""
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO sinks (name, kind, url, topic, secret, ship_id, cargo_id, enabled)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		s.Name, s.Kind, s.URL, s.Topic, s.Secret, s.ShipID, s.CargoID, s.Enabled,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+}
+
+// UpdateSink replaces a sink, reporting whether it existed. A nil secret
+// keeps the current one; the sink's secret is filled in either way.
+func UpdateSink(ctx context.Context, s *Sink, secret *string) (bool, error) {
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE sinks
+		SET name = $2, kind = $3, url = $4, topic = $5, secret = CASE WHEN $6::text IS NULL THEN secret ELSE NULLIF($6, '') END,
+		    ship_id = $7, cargo_id = $8, enabled = $9, updated_at = now()
+		WHERE id = $1
+		RETURNING COALESCE(secret, ''), created_at, updated_at`,
+		s.ID, s.Name, s.Kind, s.URL, s.Topic, secret, s.ShipID, s.CargoID, s.Enabled,
+	).Scan(&s.Secret, &s.CreatedAt, &s.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	s.HasSecret = s.Secret != ""
+	return err == nil, err
+}
+
+// DeleteSink removes a sink and drops its queued, retrying and dead-lettered
+// deliveries, reporting whether it existed.
+func DeleteSink(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM sinks WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, cache.RedisClient.Del(ctx, queueKey(id), retryKey(id), dlqKey(id)).Err()
+}
@@ -0,0 +1,90 @@
+package transform
+
+import (
+	"context"
+	"log"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+)
+
+// Engine applies the latest configuration version, which is cached in memory
+// and reloaded periodically.
+type Engine struct {
+	*ruleset.Cache[*configuration]
+}
+
+// configuration is a configuration version with its rules compiled.
+type configuration struct {
+	version int64
+	rules   []Rule
+}
+
+// NewEngine creates an engine without rules; call Refresh to load them.
+func NewEngine() *Engine {
+	e := &Engine{}
+	e.Cache = ruleset.NewCache("Transform", "configuration", e.load)
+	return e
+}
+
+// load loads the latest configuration version from Postgres, keeping the
+// current one if it is still the latest.
+func (e *Engine) load(ctx context.Context) (*configuration, error) {
+	current := e.Current()
+	v, err := LatestVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || (current != nil && v.Version == current.version) {
+		return current, nil
+	}
+
+	rules := make([]Rule, 0, len(v.Rules))
+	for _, r := range v.Rules {
+		if err := r.compile(); err != nil {
+			// Rules are validated before they are stored.
+			log.Printf("[Transform] Skipping rule %d of version %d: %v", r.ID, v.Version, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	log.Printf("[Transform] Loaded configuration version %d with %d rule(s).", v.Version, len(rules))
+	return &configuration{version: v.Version, rules: rules}, nil
+}
+
+// Apply transforms a batch with the first matching rule of each point and
+// returns the points to write. Transformed points record the configuration
+// version; dropped points are left out.
+func (e *Engine) Apply(batch []general.SensorData) []general.SensorData {
+	cfg := e.Current()
+	if cfg == nil || len(cfg.rules) == 0 {
+		return batch
+	}
+	version, rules := cfg.version, cfg.rules
+
+	out := make([]general.SensorData, 0, len(batch))
+	for _, d := range batch {
+		if r := match(rules, d); r != nil {
+			value, cargoID, keep := r.apply(*d.Value, d.CargoID)
+			if !keep {
+				continue
+			}
+			d.Value, d.CargoID, d.TransformVersion = &value, cargoID, &version
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// match returns the first rule selecting a point.
+func match(rules []Rule, d general.SensorData) *Rule {
+	if d.Value == nil {
+		return nil
+	}
+	for i := range rules {
+		if rules[i].Selects(d.ShipID, d.CargoID) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package transform
+
+import (
+	"net/http"
+
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ruleRequest is the body of POST and PUT /transforms.
+type ruleRequest struct {
+	Name     string `json:"name"     validate:"required,max=200"`
+	ShipID   string `json:"ship_id"  validate:"max=100"`
+	CargoID  string `json:"cargo_id" validate:"required,max=100"`
+	Priority int    `json:"priority"`
+	Steps    []Step `json:"steps"    validate:"required,min=1,max=20"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+// ruleResponse is a rule along with the configuration version a change to it
+// created.
+type ruleResponse struct {
+	*Rule
+	ConfigVersion int64 `json:"config_version"`
+}
+
+// parseRuleRequest validates a rule body; ship_id defaults to every ship. If
+// the rule is nil the error response has already been written and the
+// returned error is the one to pass back to Fiber.
+func parseRuleRequest(c *fiber.Ctx) (*Rule, error) {
+	var req ruleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	for _, f := range []struct{ field, pattern string }{{"ShipID", req.ShipID}, {"CargoID", req.CargoID}} {
+		if err := glob.Validate(f.pattern); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{f.field}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+		}
+	}
+	rule := &Rule{
+		Name:     req.Name,
+		ShipID:   req.ShipID,
+		CargoID:  req.CargoID,
+		Priority: req.Priority,
+		Steps:    req.Steps,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+	if len(req.Steps) > 0 {
+		if err := rule.compile(); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{"Steps"}, Msg: err.Error(), Type: "validation_error.steps"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+	return rule, nil
+}
+
+// ListTransforms returns all rules in the order they are matched.
+func ListTransforms(c *fiber.Ctx) error {
+	rules, err := ListRules(c.Context())
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load transforms")
+	}
+	return c.JSON(rules)
+}
+
+// GetTransform returns one rule.
+func GetTransform(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid transform ID")
+	}
+	rule, err := GetRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load transform")
+	}
+	if rule == nil {
+		return fiber.NewError(http.StatusNotFound, "Transform not found")
+	}
+	return c.JSON(rule)
+}
+
+// CreateTransform stores a new rule. Workers pick up the new configuration
+// version within TRANSFORM_REFRESH_SEC.
+func CreateTransform(c *fiber.Ctx) error {
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	version, err := CreateRule(c.Context(), rule)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create transform")
+	}
+	return c.Status(http.StatusCreated).JSON(ruleResponse{rule, version})
+}
+
+// UpdateTransform replaces a rule.
+func UpdateTransform(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid transform ID")
+	}
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	rule.ID = int64(id)
+	version, err := UpdateRule(c.Context(), rule)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update transform")
+	}
+	if version == 0 {
+		return fiber.NewError(http.StatusNotFound, "Transform not found")
+	}
+	return c.JSON(ruleResponse{rule, version})
+}
+
+// DeleteTransform removes a rule.
+func DeleteTransform(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid transform ID")
+	}
+	version, err := DeleteRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete transform")
+	}
+	if version == 0 {
+		return fiber.NewError(http.StatusNotFound, "Transform not found")
+	}
+	return c.JSON(fiber.Map{"config_version": version})
+}
+
+// GetTransformVersion returns the rules of a configuration version, or of the
+// latest one for `latest`.
+func GetTransformVersion(c *fiber.Ctx) error {
+	var v *Version
+	var err error
+	if c.Params("version") == "latest" {
+		v, err = LatestVersion(c.Context())
+	} else {
+		n, perr := c.ParamsInt("version")
+		if perr != nil || n < 1 {
+			return fiber.NewError(http.StatusBadRequest, "Invalid version")
+		}
+		v, err = GetVersion(c.Context(), int64(n))
+	}
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load transform version")
+	}
+	if v == nil {
+		return fiber.NewError(http.StatusNotFound, "Transform version not found")
+	}
+	return c.JSON(v)
+}
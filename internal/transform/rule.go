@@ -0,0 +1,212 @@
+// Package transform converts raw values in the worker before they are
+// written, e.g. ADC counts to engineering units. Every change to the rules
+// creates a new configuration version, and each transformed point records the
+// version that produced it.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Rule transforms the points whose ship and cargo IDs match its glob
+// patterns. Only the first matching enabled rule applies, in priority order
+// (lowest first) and then by ID.
+type Rule struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ShipID    string    `json:"ship_id"`
+	CargoID   string    `json:"cargo_id"`
+	Priority  int       `json:"priority"`
+	Steps     []Step    `json:"steps"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	funcs []stepFunc
+}
+
+// Version is a snapshot of the enabled rules, in the order they are matched.
+type Version struct {
+	Version   int64     `json:"version"`
+	Rules     []Rule    `json:"rules"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Selects reports whether the rule applies to a series.
+func (r *Rule) Selects(shipID, cargoID string) bool {
+	return glob.Match(r.ShipID, shipID) && glob.Match(r.CargoID, cargoID)
+}
+
+// compile validates the steps of a rule.
+func (r *Rule) compile() error {
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("a rule needs at least one step")
+	}
+	r.funcs = make([]stepFunc, len(r.Steps))
+	for i, s := range r.Steps {
+		f, err := s.compile()
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+		r.funcs[i] = f
+	}
+	return nil
+}
+
+// apply runs the steps on a value; keep is false if the point is dropped.
+func (r *Rule) apply(value float64, cargoID string) (float64, string, bool) {
+	keep := true
+	for _, f := range r.funcs {
+		if value, cargoID, keep = f(value, cargoID); !keep {
+			break
+		}
+	}
+	return value, cargoID, keep
+}
+
+const ruleColumns = `id, name, ship_id, cargo_id, priority, steps, enabled, created_at, updated_at`
+
+func scanRule(row pgx.Row) (Rule, error) {
+	var r Rule
+	err := row.Scan(&r.ID, &r.Name, &r.ShipID, &r.CargoID, &r.Priority, &r.Steps, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+func queryRules(ctx context.Context, q querier, enabledOnly bool) ([]Rule, error) {
+	rows, err := q.Query(ctx, `SELECT `+ruleColumns+` FROM transform_rules WHERE enabled OR NOT $1 ORDER BY priority, id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// ListRules returns all rules in the order they are matched.
+func ListRules(ctx context.Context) ([]Rule, error) {
+	return queryRules(ctx, db.Pool, false)
+}
+
+// GetRule loads one rule; a nil rule means it does not exist.
+func GetRule(ctx context.Context, id int64) (*Rule, error) {
+	r, err := scanRule(db.Pool.QueryRow(ctx, `SELECT `+ruleColumns+` FROM transform_rules WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRule stores a new rule, fills in its ID and timestamps and returns
+// the new configuration version.
+func CreateRule(ctx context.Context, r *Rule) (int64, error) {
+	return changeRules(ctx, func(tx pgx.Tx) (bool, error) {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO transform_rules (name, ship_id, cargo_id, priority, steps, enabled)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at, updated_at`,
+			r.Name, r.ShipID, r.CargoID, r.Priority, r.Steps, r.Enabled,
+		).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+		return err == nil, err
+	})
+}
+
+// UpdateRule replaces a rule and returns the new configuration version, or 0
+// if the rule does not exist.
+func UpdateRule(ctx context.Context, r *Rule) (int64, error) {
+	return changeRules(ctx, func(tx pgx.Tx) (bool, error) {
+		err := tx.QueryRow(ctx, `
+			UPDATE transform_rules
+			SET name = $2, ship_id = $3, cargo_id = $4, priority = $5, steps = $6, enabled = $7, updated_at = now()
+			WHERE id = $1
+			RETURNING created_at, updated_at`,
+			r.ID, r.Name, r.ShipID, r.CargoID, r.Priority, r.Steps, r.Enabled,
+		).Scan(&r.CreatedAt, &r.UpdatedAt)
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	})
+}
+
+// DeleteRule removes a rule and returns the new configuration version, or 0
+// if the rule does not exist.
+func DeleteRule(ctx context.Context, id int64) (int64, error) {
+	return changeRules(ctx, func(tx pgx.Tx) (bool, error) {
+		tag, err := tx.Exec(ctx, `DELETE FROM transform_rules WHERE id = $1`, id)
+		return err == nil && tag.RowsAffected() > 0, err
+	})
+}
+
+// changeRules runs a change and, if it found its rule, stores a snapshot of
+// the enabled rules as a new version in the same transaction. Changes are
+// serialized so that every version reflects the ones before it.
+func changeRules(ctx context.Context, change func(pgx.Tx) (bool, error)) (int64, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('transform_rules'))`); err != nil {
+		return 0, err
+	}
+	found, err := change(tx)
+	if err != nil || !found {
+		return 0, err
+	}
+	rules, err := queryRules(ctx, tx, true)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	if err := tx.QueryRow(ctx, `INSERT INTO transform_versions (rules) VALUES ($1) RETURNING version`, rules).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit(ctx)
+}
+
+// LatestVersion loads the current configuration; a nil version means no rule
+// was ever created.
+func LatestVersion(ctx context.Context) (*Version, error) {
+	return scanVersion(db.Pool.QueryRow(ctx, `SELECT version, rules, created_at FROM transform_versions ORDER BY version DESC LIMIT 1`))
+}
+
+// GetVersion loads a configuration version; a nil version means it does not
+// exist.
+func GetVersion(ctx context.Context, version int64) (*Version, error) {
+	return scanVersion(db.Pool.QueryRow(ctx, `SELECT version, rules, created_at FROM transform_versions WHERE version = $1`, version))
+}
+
+func scanVersion(row pgx.Row) (*Version, error) {
+	var v Version
+	err := row.Scan(&v.Version, &v.Rules, &v.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
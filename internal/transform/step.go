@@ -0,0 +1,111 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Step types.
+const (
+	StepLinear = "linear"
+	StepLookup = "lookup"
+	StepUnit   = "unit"
+	StepRename = "rename"
+	StepDrop   = "drop"
+)
+
+// Step is one stage of a transform:
+//
+//	{"type": "linear", "scale": 0.01, "offset": -40}
+//	{"type": "lookup", "points": [[0, 0], [4095, 100]], "extrapolate": false}
+//	{"type": "unit", "from": "degF", "to": "degC"}
+//	{"type": "rename", "cargo_id": "engine_temp_c"}
+//	{"type": "drop"}
+type Step struct {
+	Type        string       `json:"type"`
+	Scale       *float64     `json:"scale,omitempty"`
+	Offset      *float64     `json:"offset,omitempty"`
+	Points      [][2]float64 `json:"points,omitempty"`
+	Extrapolate bool         `json:"extrapolate,omitempty"`
+	From        string       `json:"from,omitempty"`
+	To          string       `json:"to,omitempty"`
+	CargoID     string       `json:"cargo_id,omitempty"`
+}
+
+// stepFunc applies a step to a point. keep is false if the point is dropped.
+type stepFunc func(value float64, cargoID string) (float64, string, bool)
+
+// compile validates a step and returns its function.
+func (s Step) compile() (stepFunc, error) {
+	switch s.Type {
+	case StepLinear:
+		if s.Scale == nil && s.Offset == nil {
+			return nil, errors.New("linear needs a scale or an offset")
+		}
+		scale, offset := 1.0, 0.0
+		if s.Scale != nil {
+			scale = *s.Scale
+		}
+		if s.Offset != nil {
+			offset = *s.Offset
+		}
+		return func(v float64, id string) (float64, string, bool) { return v*scale + offset, id, true }, nil
+	case StepLookup:
+		return compileLookup(s.Points, s.Extrapolate)
+	case StepUnit:
+		scale, offset, err := conversion(s.From, s.To)
+		if err != nil {
+			return nil, err
+		}
+		return func(v float64, id string) (float64, string, bool) { return v*scale + offset, id, true }, nil
+	case StepRename:
+		if s.CargoID == "" || len(s.CargoID) > 100 {
+			return nil, errors.New("rename needs a cargo_id of 1 to 100 characters")
+		}
+		to := s.CargoID
+		return func(v float64, _ string) (float64, string, bool) { return v, to, true }, nil
+	case StepDrop:
+		return func(v float64, id string) (float64, string, bool) { return v, id, false }, nil
+	default:
+		return nil, fmt.Errorf("unknown step type %q (use linear, lookup, unit, rename or drop)", s.Type)
+	}
+}
+
+// compileLookup interpolates linearly between calibration points. Inputs
+// outside the table are clamped to its first or last output, or extrapolated
+// from the outermost segment.
+func compileLookup(points [][2]float64, extrapolate bool) (stepFunc, error) {
+	if len(points) < 2 {
+		return nil, errors.New("lookup needs at least 2 points")
+	}
+	pts := make([][2]float64, len(points))
+	copy(pts, points)
+	sort.Slice(pts, func(i, j int) bool { return pts[i][0] < pts[j][0] })
+	for i := 1; i < len(pts); i++ {
+		if pts[i][0] == pts[i-1][0] {
+			return nil, fmt.Errorf("lookup has two points for input %v", pts[i][0])
+		}
+	}
+
+	interpolate := func(a, b [2]float64, x float64) float64 {
+		return a[1] + (x-a[0])*(b[1]-a[1])/(b[0]-a[0])
+	}
+	last := len(pts) - 1
+	return func(v float64, id string) (float64, string, bool) {
+		switch {
+		case v <= pts[0][0]:
+			if extrapolate {
+				return interpolate(pts[0], pts[1], v), id, true
+			}
+			return pts[0][1], id, true
+		case v >= pts[last][0]:
+			if extrapolate {
+				return interpolate(pts[last-1], pts[last], v), id, true
+			}
+			return pts[last][1], id, true
+		}
+		i := sort.Search(len(pts), func(i int) bool { return pts[i][0] >= v })
+		return interpolate(pts[i-1], pts[i], v), id, true
+	}, nil
+}
@@ -0,0 +1,53 @@
+package transform
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompileLookup(t *testing.T) {
+	// 4-20 mA sensor with a non-linear top end, listed out of order.
+	points := [][2]float64{{20, 100}, {4, 0}, {12, 40}}
+	tests := []struct {
+		in, clamped, extrapolated float64
+	}{
+		{4, 0, 0},
+		{8, 20, 20},
+		{12, 40, 40},
+		{16, 70, 70},
+		{20, 100, 100},
+		{0, 0, -20},
+		{24, 100, 130},
+	}
+	clamp, err := compileLookup(points, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extrapolate, err := compileLookup(points, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if got, _, _ := clamp(tt.in, "level"); math.Abs(got-tt.clamped) > 1e-9 {
+			t.Errorf("clamped lookup(%v) = %v, want %v", tt.in, got, tt.clamped)
+		}
+		if got, _, _ := extrapolate(tt.in, "level"); math.Abs(got-tt.extrapolated) > 1e-9 {
+			t.Errorf("extrapolated lookup(%v) = %v, want %v", tt.in, got, tt.extrapolated)
+		}
+	}
+	if points[0][0] != 20 {
+		t.Errorf("compileLookup reordered the caller's points: %v", points)
+	}
+}
+
+func TestCompileLookupErrors(t *testing.T) {
+	for _, points := range [][][2]float64{
+		nil,
+		{{0, 0}},
+		{{0, 0}, {1, 1}, {0, 2}},
+	} {
+		if _, err := compileLookup(points, false); err == nil {
+			t.Errorf("compileLookup(%v) succeeded, want error", points)
+		}
+	}
+}
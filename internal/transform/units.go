@@ -0,0 +1,78 @@
+package transform
+
+import "fmt"
+
+// unit converts to the base unit of its dimension as value*factor + offset.
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// units are the names accepted by "unit" steps.
+var units = map[string]unit{
+	// Temperature, base kelvin.
+	"K":    {"temperature", 1, 0},
+	"degC": {"temperature", 1, 273.15},
+	"degF": {"temperature", 5.0 / 9, 273.15 - 32*5.0/9},
+
+	// Length, base metre.
+	"mm":  {"length", 0.001, 0},
+	"cm":  {"length", 0.01, 0},
+	"m":   {"length", 1, 0},
+	"km":  {"length", 1000, 0},
+	"in":  {"length", 0.0254, 0},
+	"ft":  {"length", 0.3048, 0},
+	"mi":  {"length", 1609.344, 0},
+	"nmi": {"length", 1852, 0},
+
+	// Speed, base metre per second.
+	"m/s":  {"speed", 1, 0},
+	"km/h": {"speed", 1000.0 / 3600, 0},
+	"kn":   {"speed", 1852.0 / 3600, 0},
+	"mph":  {"speed", 1609.344 / 3600, 0},
+
+	// Pressure, base pascal.
+	"Pa":   {"pressure", 1, 0},
+	"hPa":  {"pressure", 100, 0},
+	"kPa":  {"pressure", 1000, 0},
+	"MPa":  {"pressure", 1e6, 0},
+	"mbar": {"pressure", 100, 0},
+	"bar":  {"pressure", 1e5, 0},
+	"psi":  {"pressure", 6894.757293168, 0},
+
+	// Volume, base litre.
+	"mL":  {"volume", 0.001, 0},
+	"L":   {"volume", 1, 0},
+	"m3":  {"volume", 1000, 0},
+	"gal": {"volume", 3.785411784, 0},
+
+	// Mass, base kilogram.
+	"g":  {"mass", 0.001, 0},
+	"kg": {"mass", 1, 0},
+	"t":  {"mass", 1000, 0},
+	"lb": {"mass", 0.45359237, 0},
+
+	// Volume flow, base litre per hour.
+	"L/h":   {"flow", 1, 0},
+	"m3/h":  {"flow", 1000, 0},
+	"gal/h": {"flow", 3.785411784, 0},
+	"L/min": {"flow", 60, 0},
+}
+
+// conversion returns the scale and offset that convert from one unit to
+// another: to = from*scale + offset.
+func conversion(from, to string) (scale, offset float64, err error) {
+	f, ok := units[from]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.dimension != t.dimension {
+		return 0, 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, f.dimension, to, t.dimension)
+	}
+	return f.factor / t.factor, (f.offset - t.offset) / t.factor, nil
+}
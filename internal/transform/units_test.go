@@ -0,0 +1,52 @@
+package transform
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConversion(t *testing.T) {
+	tests := []struct {
+		from, to string
+		in, want float64
+	}{
+		{"degF", "degC", 32, 0},
+		{"degF", "degC", 212, 100},
+		{"degF", "degC", -40, -40},
+		{"degC", "degF", 37, 98.6},
+		{"degC", "K", -273.15, 0},
+		{"K", "degF", 0, -459.67},
+		{"kn", "m/s", 1, 1852.0 / 3600},
+		{"km/h", "kn", 1.852, 1},
+		{"bar", "psi", 1, 14.503773773},
+		{"mbar", "hPa", 1013.25, 1013.25},
+		{"gal", "L", 1, 3.785411784},
+		{"m3/h", "L/min", 6, 100},
+		{"nmi", "km", 1, 1.852},
+		{"lb", "kg", 1, 0.45359237},
+	}
+	for _, tt := range tests {
+		scale, offset, err := conversion(tt.from, tt.to)
+		if err != nil {
+			t.Errorf("conversion(%s, %s) = %v", tt.from, tt.to, err)
+			continue
+		}
+		if got := tt.in*scale + offset; math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%v %s = %v %s, want %v", tt.in, tt.from, got, tt.to, tt.want)
+		}
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	for _, tt := range [][2]string{
+		{"degF", "m"},
+		{"kn", "bar"},
+		{"furlong", "m"},
+		{"m", "furlong"},
+		{"degc", "degF"},
+	} {
+		if _, _, err := conversion(tt[0], tt[1]); err == nil {
+			t.Errorf("conversion(%s, %s) succeeded, want error", tt[0], tt[1])
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package utils
+
+import "time"
+
+// MaxRetryDelay caps the backoff between delivery attempts.
+const MaxRetryDelay = time.Hour
+
+// Backoff returns the delay before retrying after the given failed attempt:
+// base, doubling with every further attempt up to MaxRetryDelay.
+func Backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryDelay)
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		base    time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{5 * time.Second, 1, 5 * time.Second},
+		{5 * time.Second, 2, 10 * time.Second},
+		{5 * time.Second, 5, 80 * time.Second},
+		{5 * time.Second, 11, MaxRetryDelay},
+		{5 * time.Second, 64, MaxRetryDelay},
+		{5 * time.Second, 1000, MaxRetryDelay},
+		{2 * time.Hour, 1, MaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.base, tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%v, %d) = %v, want %v", tt.base, tt.attempt, got, tt.want)
+		}
+	}
+}
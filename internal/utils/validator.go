@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"reflect"
+	"time"
+	"go-ingest-service/internal/config"
 	"go-ingest-service/internal/models"
 	"github.com/go-playground/validator/v10"
 )
@@ -10,6 +12,38 @@ import (
 // Create a single, reusable validator instance.
 var validate = validator.New()
 
+func init() {
+	// "timewindow" rejects timestamps outside the configured acceptance window.
+	validate.RegisterValidation("timewindow", func(fl validator.FieldLevel) bool {
+		t, ok := fl.Field().Interface().(time.Time)
+		return !ok || timeWindowMessage(t, time.Now()) == ""
+	})
+}
+
+// timeWindowMessage explains why t is outside the window, or returns "" if it is accepted.
+// A zero limit disables that side of the window.
+func timeWindowMessage(t, now time.Time) string {
+	if limit := config.AppConfig.TimestampMaxFuture; limit > 0 && t.After(now.Add(limit)) {
+		return fmt.Sprintf("Timestamp %s is more than %s in the future", t.UTC().Format(time.RFC3339), limit)
+	}
+	if limit := config.AppConfig.TimestampMaxAge; limit > 0 && t.Before(now.Add(-limit)) {
+		return fmt.Sprintf("Timestamp %s is more than %s in the past", t.UTC().Format(time.RFC3339), limit)
+	}
+	return ""
+}
+
+// validationMessage describes a failed validation tag.
+func validationMessage(err validator.FieldError) string {
+	if err.Tag() == "timewindow" {
+		if t, ok := err.Value().(time.Time); ok {
+			if msg := timeWindowMessage(t, time.Now()); msg != "" {
+				return msg
+			}
+		}
+	}
+	return "Validation failed on tag '" + err.Tag() + "'"
+}
+
 // ValidateStruct validates a single struct that has `validate` tags.
 func ValidateStruct(s interface{}) []models.ErrorDetail {
 	var errors []models.ErrorDetail
@@ -19,7 +53,7 @@ func ValidateStruct(s interface{}) []models.ErrorDetail {
 		for _, err := range err.(validator.ValidationErrors) {
 			errors = append(errors, models.ErrorDetail{
 				Loc:  []string{err.Field()},
-				Msg:  "Validation failed on tag '" + err.Tag() + "'",
+				Msg:  validationMessage(err),
 				Type: "validation_error." + err.Tag(),
 			})
 		}
@@ -46,19 +80,24 @@ func ValidateBatch(batch interface{}) []models.ErrorDetail {
 
 	// Iterate over the slice using reflection.
 	for i := 0; i < slice.Len(); i++ {
-		item := slice.Index(i).Interface()
-		// Use the same validator instance to validate each item in the slice.
-		if err := validate.Struct(item); err != nil {
-			// If an item is invalid, add its errors to the list.
-			for _, validationErr := range err.(validator.ValidationErrors) {
-				allErrors = append(allErrors, models.ErrorDetail{
-					// Prepend the index to the location for clear error reporting.
-					Loc:  []string{fmt.Sprintf("[%d].%s", i, validationErr.Field())},
-					Msg:  "Validation failed on tag '" + validationErr.Tag() + "'",
-					Type: "validation_error." + validationErr.Tag(),
-				})
-			}
-		}
+		allErrors = append(allErrors, ValidateItem(i, slice.Index(i).Interface())...)
 	}
 	return allErrors
 }
+
+// ValidateItem validates a single batch element, prefixing error locations with its index.
+func ValidateItem(index int, item interface{}) []models.ErrorDetail {
+	var errors []models.ErrorDetail
+	// Use the same validator instance to validate each item in the slice.
+	if err := validate.Struct(item); err != nil {
+		for _, validationErr := range err.(validator.ValidationErrors) {
+			errors = append(errors, models.ErrorDetail{
+				// Prepend the index to the location for clear error reporting.
+				Loc:  []string{fmt.Sprintf("[%d].%s", index, validationErr.Field())},
+				Msg:  validationMessage(validationErr),
+				Type: "validation_error." + validationErr.Tag(),
+			})
+		}
+	}
+	return errors
+}
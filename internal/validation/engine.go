@@ -0,0 +1,216 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Violation is a point that failed validation, with the reasons and the IDs
+// of the rules it broke.
+type Violation struct {
+	Point   general.SensorData
+	Reasons []string
+	RuleIDs []int64
+}
+
+func (v *Violation) add(ruleID int64, format string, args ...interface{}) {
+	v.Reasons = append(v.Reasons, fmt.Sprintf(format, args...))
+	if ruleID != 0 {
+		v.RuleIDs = append(v.RuleIDs, ruleID)
+	}
+}
+
+type seriesKey struct {
+	shipID, cargoID string
+}
+
+type sample struct {
+	time  time.Time
+	value float64
+}
+
+// Engine checks points against the enabled rules. Rules are cached in memory
+// and reloaded periodically.
+type Engine struct {
+	*ruleset.Cache[[]Rule]
+	shipPattern *regexp.Regexp
+	latest      func(context.Context, map[seriesKey][]int) (map[seriesKey]sample, error)
+}
+
+// NewEngine creates an engine without rules; call Refresh to load them.
+// shipPattern restricts ship IDs if it is not nil.
+func NewEngine(shipPattern *regexp.Regexp) *Engine {
+	return &Engine{
+		Cache: ruleset.NewCache("Validation", "rules", func(ctx context.Context) ([]Rule, error) {
+			return ListRules(ctx, true)
+		}),
+		shipPattern: shipPattern,
+		latest:      latestValues,
+	}
+}
+
+// Check splits a batch into the points to write and the violations to
+// quarantine. Rates of change are measured against the previous accepted
+// point of the series, in the batch or else the latest one in cargo_data;
+// points older than that are not rate-checked.
+func (e *Engine) Check(ctx context.Context, batch []general.SensorData) ([]general.SensorData, []Violation) {
+	rules := e.Current()
+
+	accepted := make([]general.SensorData, 0, len(batch))
+	var violations []Violation
+	rated := make(map[seriesKey][]int) // Indexes into batch of points needing a rate check.
+	matched := make(map[seriesKey][]*Rule)
+	for i, d := range batch {
+		if d.Value == nil {
+			accepted = append(accepted, d)
+			continue
+		}
+		v := Violation{Point: d}
+		value := *d.Value
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			v.add(0, "value %v is not a finite number", value)
+		}
+		if e.shipPattern != nil && !e.shipPattern.MatchString(d.ShipID) {
+			v.add(0, "ship_id %q does not match SHIP_ID_PATTERN", d.ShipID)
+		}
+
+		key := seriesKey{d.ShipID, d.CargoID}
+		selected, ok := matched[key]
+		if !ok {
+			for j := range rules {
+				if rules[j].Selects(d.ShipID, d.CargoID) {
+					selected = append(selected, &rules[j])
+				}
+			}
+			matched[key] = selected
+		}
+		hasRate := false
+		for _, r := range selected {
+			if r.Min != nil && value < *r.Min {
+				v.add(r.ID, "value %v is below the minimum %v of rule %d", value, *r.Min, r.ID)
+			}
+			if r.Max != nil && value > *r.Max {
+				v.add(r.ID, "value %v is above the maximum %v of rule %d", value, *r.Max, r.ID)
+			}
+			hasRate = hasRate || r.MaxRate != nil
+		}
+
+		switch {
+		case len(v.Reasons) > 0:
+			violations = append(violations, v)
+		case hasRate:
+			rated[key] = append(rated[key], i)
+		default:
+			accepted = append(accepted, d)
+		}
+	}
+	if len(rated) == 0 {
+		return accepted, violations
+	}
+
+	previous, err := e.latest(ctx, rated)
+	if err != nil {
+		log.Printf("[Validation] Failed to load previous values, checking rates within the batch only: %v", err)
+	}
+	for key, idxs := range rated {
+		sort.SliceStable(idxs, func(a, b int) bool { return batch[idxs[a]].Time.Before(batch[idxs[b]].Time) })
+		prev, hasPrev := previous[key]
+		for _, i := range idxs {
+			d := batch[i]
+			value := *d.Value
+			v := Violation{Point: d}
+			if hasPrev && d.Time.After(prev.time) {
+				rate := math.Abs(value-prev.value) / d.Time.Sub(prev.time).Seconds()
+				for _, r := range matched[key] {
+					if r.MaxRate != nil && rate > *r.MaxRate {
+						v.add(r.ID, "change of %.6g/s exceeds the maximum rate %v of rule %d", rate, *r.MaxRate, r.ID)
+					}
+				}
+			}
+			if len(v.Reasons) > 0 {
+				violations = append(violations, v)
+				continue
+			}
+			accepted = append(accepted, d)
+			if !hasPrev || d.Time.After(prev.time) {
+				prev, hasPrev = sample{d.Time, value}, true
+			}
+		}
+	}
+	return accepted, violations
+}
+
+// latestValues loads the newest stored value of each series.
+func latestValues(ctx context.Context, series map[seriesKey][]int) (map[seriesKey]sample, error) {
+	ships := make([]string, 0, len(series))
+	cargos := make([]string, 0, len(series))
+	for key := range series {
+		ships = append(ships, key.shipID)
+		cargos = append(cargos, key.cargoID)
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT m.ship_id, m.cargo_id, l.time, l.value
+		FROM unnest($1::text[], $2::text[]) AS m (ship_id, cargo_id)
+		CROSS JOIN LATERAL (
+			SELECT time, value FROM cargo_data c
+			WHERE c.ship_id = m.ship_id AND c.cargo_id = m.cargo_id
+			ORDER BY time DESC LIMIT 1
+		) l`, ships, cargos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[seriesKey]sample, len(series))
+	for rows.Next() {
+		var key seriesKey
+		var s sample
+		if err := rows.Scan(&key.shipID, &key.cargoID, &s.time, &s.value); err != nil {
+			return latest, err
+		}
+		latest[key] = s
+	}
+	return latest, rows.Err()
+}
+
+// Quarantine stores violations. A point quarantined again replaces the
+// earlier entry, so retried batches do not duplicate it.
+func Quarantine(ctx context.Context, violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	b := &pgx.Batch{}
+	for _, v := range violations {
+		d := v.Point
+		ruleIDs := v.RuleIDs
+		if ruleIDs == nil {
+			ruleIDs = []int64{}
+		}
+		b.Queue(`
+			INSERT INTO quarantine (time, ship_id, cargo_id, value, transform_version, reasons, rule_ids)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (time, ship_id, cargo_id) DO UPDATE SET
+				value = EXCLUDED.value, transform_version = EXCLUDED.transform_version,
+				reasons = EXCLUDED.reasons, rule_ids = EXCLUDED.rule_ids, quarantined_at = now()`,
+			d.Time, d.ShipID, d.CargoID, *d.Value, d.TransformVersion, v.Reasons, ruleIDs)
+	}
+	results := db.Pool.SendBatch(ctx, b)
+	defer results.Close()
+	for range violations {
+		if _, err := results.Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
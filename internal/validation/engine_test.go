@@ -0,0 +1,145 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-ingest-service/internal/ingest/general"
+	"go-ingest-service/internal/ruleset"
+)
+
+func float(v float64) *float64 { return &v }
+
+// testEngine returns an engine with fixed rules and stored series values.
+func testEngine(t *testing.T, stored map[seriesKey]sample, loadErr error) *Engine {
+	rules := []Rule{
+		{ID: 1, ShipID: "*", CargoID: "temp*", Min: float(-50), Max: float(100)},
+		{ID: 2, ShipID: "vessel_*", CargoID: "level", MaxRate: float(1)},
+		{ID: 3, ShipID: "vessel_1", CargoID: "level", Min: float(0)},
+	}
+	e := &Engine{
+		Cache: ruleset.NewCache("Validation", "rules", func(context.Context) ([]Rule, error) {
+			return rules, nil
+		}),
+		shipPattern: regexp.MustCompile(`^vessel_\d+$`),
+		latest: func(_ context.Context, series map[seriesKey][]int) (map[seriesKey]sample, error) {
+			if loadErr != nil {
+				return nil, loadErr
+			}
+			found := make(map[seriesKey]sample)
+			for key := range series {
+				if s, ok := stored[key]; ok {
+					found[key] = s
+				}
+			}
+			return found, nil
+		},
+	}
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestCheck(t *testing.T) {
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+	point := func(ship, cargo string, sec int, value float64) general.SensorData {
+		return general.SensorData{ShipID: ship, CargoID: cargo, Time: at(sec), Value: float(value)}
+	}
+	level := seriesKey{"vessel_1", "level"}
+
+	tests := []struct {
+		name         string
+		stored       map[seriesKey]sample
+		loadErr      error
+		batch        []general.SensorData
+		wantAccepted int
+		wantRules    [][]int64 // Rule IDs of each violation, in order.
+	}{
+		{
+			name:         "ranges",
+			batch:        []general.SensorData{point("vessel_1", "temp", 0, 20), point("vessel_1", "temp_out", 0, 120), point("vessel_1", "temp", 1, -60)},
+			wantAccepted: 1,
+			wantRules:    [][]int64{{1}, {1}},
+		},
+		{
+			name: "values and ship IDs",
+			batch: []general.SensorData{
+				point("vessel_1", "rpm", 0, math.NaN()),
+				point("tug", "rpm", 0, 1),
+				{ShipID: "vessel_1", CargoID: "rpm", Time: at(0)},
+			},
+			wantAccepted: 1,
+			wantRules:    [][]int64{nil, nil},
+		},
+		{
+			name:         "range failures skip the rate check",
+			stored:       map[seriesKey]sample{level: {at(0), 10}},
+			batch:        []general.SensorData{point("vessel_1", "level", 1, -1)},
+			wantAccepted: 0,
+			wantRules:    [][]int64{{3}},
+		},
+		{
+			// 10 -> 15 in 10s is fine, 15 -> 40 in 10s is not, and the last
+			// point is measured against 15, the previous accepted value.
+			name:   "rate against the stored and accepted values",
+			stored: map[seriesKey]sample{level: {at(0), 10}},
+			batch: []general.SensorData{
+				point("vessel_1", "level", 30, 20),
+				point("vessel_1", "level", 10, 15),
+				point("vessel_1", "level", 20, 40),
+			},
+			wantAccepted: 2,
+			wantRules:    [][]int64{{2}},
+		},
+		{
+			name:         "points older than the stored value are not rate-checked",
+			stored:       map[seriesKey]sample{level: {at(60), 10}},
+			batch:        []general.SensorData{point("vessel_1", "level", 0, 90)},
+			wantAccepted: 1,
+		},
+		{
+			name:         "first point of a new series",
+			batch:        []general.SensorData{point("vessel_2", "level", 0, 90), point("vessel_2", "level", 1, 95)},
+			wantAccepted: 1,
+			wantRules:    [][]int64{{2}},
+		},
+		{
+			name:         "stored values unavailable",
+			stored:       map[seriesKey]sample{level: {at(0), 10}},
+			loadErr:      errors.New("database unavailable"),
+			batch:        []general.SensorData{point("vessel_1", "level", 10, 90), point("vessel_1", "level", 20, 90.5)},
+			wantAccepted: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted, violations := testEngine(t, tt.stored, tt.loadErr).Check(context.Background(), tt.batch)
+			if len(accepted) != tt.wantAccepted {
+				t.Errorf("accepted %d points, want %d: %+v", len(accepted), tt.wantAccepted, accepted)
+			}
+			if len(violations) != len(tt.wantRules) {
+				t.Fatalf("got %d violations, want %d: %+v", len(violations), len(tt.wantRules), violations)
+			}
+			for i, v := range violations {
+				if len(v.Reasons) == 0 {
+					t.Errorf("violation %d has no reason", i)
+				}
+				if len(v.RuleIDs) != len(tt.wantRules[i]) {
+					t.Errorf("violation %d broke rules %v, want %v", i, v.RuleIDs, tt.wantRules[i])
+					continue
+				}
+				for j := range v.RuleIDs {
+					if v.RuleIDs[j] != tt.wantRules[i][j] {
+						t.Errorf("violation %d broke rules %v, want %v", i, v.RuleIDs, tt.wantRules[i])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package validation
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ruleRequest is the body of POST and PUT /validation/rules.
+type ruleRequest struct {
+	Name    string   `json:"name"     validate:"required,max=200"`
+	ShipID  string   `json:"ship_id"  validate:"max=100"`
+	CargoID string   `json:"cargo_id" validate:"required,max=100"`
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	MaxRate *float64 `json:"max_rate" validate:"omitempty,gt=0"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// QuarantinedPoint is a quarantined point, as listed by GET /quarantine.
+// Value is null if it is not a finite number.
+type QuarantinedPoint struct {
+	Time             time.Time `json:"time"`
+	ShipID           string    `json:"ship_id"`
+	CargoID          string    `json:"cargo_id"`
+	Value            *float64  `json:"value"`
+	TransformVersion *int64    `json:"transform_version,omitempty"`
+	Reasons          []string  `json:"reasons"`
+	RuleIDs          []int64   `json:"rule_ids"`
+	QuarantinedAt    time.Time `json:"quarantined_at"`
+}
+
+// quarantineFilter selects quarantined points for release or discarding.
+type quarantineFilter struct {
+	ShipID  string     `json:"ship_id"  validate:"required,max=100"`
+	CargoID *string    `json:"cargo_id" validate:"omitempty,max=100"`
+	From    *time.Time `json:"from"`
+	To      *time.Time `json:"to"`
+}
+
+const filterWhere = `ship_id = $1 AND ($2::text IS NULL OR cargo_id = $2)
+	AND ($3::timestamptz IS NULL OR time >= $3) AND ($4::timestamptz IS NULL OR time < $4)`
+
+// parseRuleRequest validates a rule body; ship_id defaults to every ship. If
+// the rule is nil the error response has already been written and the
+// returned error is the one to pass back to Fiber.
+func parseRuleRequest(c *fiber.Ctx) (*Rule, error) {
+	var req ruleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	errs := utils.ValidateStruct(&req)
+	if req.ShipID == "" {
+		req.ShipID = "*"
+	}
+	for _, f := range []struct{ field, pattern string }{{"ShipID", req.ShipID}, {"CargoID", req.CargoID}} {
+		if err := glob.Validate(f.pattern); err != nil {
+			errs = append(errs, models.ErrorDetail{Loc: []string{f.field}, Msg: "Invalid pattern", Type: "validation_error.pattern"})
+		}
+	}
+	if req.Min == nil && req.Max == nil && req.MaxRate == nil {
+		errs = append(errs, models.ErrorDetail{Loc: []string{"Min"}, Msg: "A rule needs min, max or max_rate", Type: "validation_error.required"})
+	}
+	if req.Min != nil && req.Max != nil && *req.Min > *req.Max {
+		errs = append(errs, models.ErrorDetail{Loc: []string{"Max"}, Msg: "max must not be below min", Type: "validation_error.range"})
+	}
+	if len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+
+	return &Rule{
+		Name:    req.Name,
+		ShipID:  req.ShipID,
+		CargoID: req.CargoID,
+		Min:     req.Min,
+		Max:     req.Max,
+		MaxRate: req.MaxRate,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}, nil
+}
+
+// ListValidationRules returns all validation rules.
+func ListValidationRules(c *fiber.Ctx) error {
+	rules, err := ListRules(c.Context(), false)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load validation rules")
+	}
+	return c.JSON(rules)
+}
+
+// GetValidationRule returns one validation rule.
+func GetValidationRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	rule, err := GetRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load validation rule")
+	}
+	if rule == nil {
+		return fiber.NewError(http.StatusNotFound, "Validation rule not found")
+	}
+	return c.JSON(rule)
+}
+
+// CreateValidationRule stores a new rule. Workers pick it up within
+// VALIDATION_REFRESH_SEC.
+func CreateValidationRule(c *fiber.Ctx) error {
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	if err := CreateRule(c.Context(), rule); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create validation rule")
+	}
+	return c.Status(http.StatusCreated).JSON(rule)
+}
+
+// UpdateValidationRule replaces a rule.
+func UpdateValidationRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	rule, err := parseRuleRequest(c)
+	if rule == nil {
+		return err
+	}
+	rule.ID = int64(id)
+	found, err := UpdateRule(c.Context(), rule)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to update validation rule")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Validation rule not found")
+	}
+	return c.JSON(rule)
+}
+
+// DeleteValidationRule removes a rule.
+func DeleteValidationRule(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid rule ID")
+	}
+	found, err := DeleteRule(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete validation rule")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Validation rule not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ListQuarantine returns the newest quarantined points, optionally filtered
+// by `?ship_id=` and `?cargo_id=`. `?limit=` defaults to 100.
+func ListQuarantine(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		return fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 1000")
+	}
+	var shipID, cargoID *string
+	if s := c.Query("ship_id"); s != "" {
+		shipID = &s
+	}
+	if s := c.Query("cargo_id"); s != "" {
+		cargoID = &s
+	}
+
+	rows, err := db.Pool.Query(c.Context(), `
+		SELECT time, ship_id, cargo_id, value, transform_version, reasons, rule_ids, quarantined_at
+		FROM quarantine
+		WHERE ($1::text IS NULL OR ship_id = $1) AND ($2::text IS NULL OR cargo_id = $2)
+		ORDER BY quarantined_at DESC, time DESC
+		LIMIT $3`, shipID, cargoID, limit)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load quarantine")
+	}
+	defer rows.Close()
+
+	points := []QuarantinedPoint{}
+	for rows.Next() {
+		var p QuarantinedPoint
+		var value float64
+		if err := rows.Scan(&p.Time, &p.ShipID, &p.CargoID, &value, &p.TransformVersion, &p.Reasons, &p.RuleIDs, &p.QuarantinedAt); err != nil {
+			return fiber.NewError(http.StatusInternalServerError, "Failed to load quarantine")
+		}
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			p.Value = &value
+		}
+		points = append(points, p)
+	}
+	if rows.Err() != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load quarantine")
+	}
+	return c.JSON(points)
+}
+
+// parseFilter validates a release or discard body. If the filter is nil the
+// error response has already been written and the returned error is the one
+// to pass back to Fiber.
+func parseFilter(c *fiber.Ctx) (*quarantineFilter, error) {
+	var f quarantineFilter
+	if err := c.BodyParser(&f); err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	if errs := utils.ValidateStruct(&f); len(errs) > 0 {
+		return nil, c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+	return &f, nil
+}
+
+// ReleaseQuarantine writes the selected quarantined points with finite
+// values to cargo_data without validating them again, and removes them from
+// quarantine. Non-finite values stay quarantined.
+func ReleaseQuarantine(c *fiber.Ctx) error {
+	f, err := parseFilter(c)
+	if f == nil {
+		return err
+	}
+	var released, inserted int64
+	err = db.Pool.QueryRow(c.Context(), `
+		WITH released AS (
+			DELETE FROM quarantine
+			WHERE `+filterWhere+` AND value NOT IN ('NaN', 'Infinity', '-Infinity')
+			RETURNING time, ship_id, cargo_id, value, transform_version
+		), inserted AS (
+			INSERT INTO cargo_data (time, ship_id, cargo_id, value, transform_version)
+			SELECT time, ship_id, cargo_id, value, transform_version FROM released
+			ON CONFLICT (time, ship_id, cargo_id) DO NOTHING
+			RETURNING 1
+		)
+		SELECT (SELECT count(*) FROM released), (SELECT count(*) FROM inserted)`,
+		f.ShipID, f.CargoID, f.From, f.To,
+	).Scan(&released, &inserted)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to release quarantined points")
+	}
+	return c.JSON(fiber.Map{"released": released, "inserted": inserted, "skipped": released - inserted})
+}
+
+// DiscardQuarantine discards the selected quarantined points.
+func DiscardQuarantine(c *fiber.Ctx) error {
+	f, err := parseFilter(c)
+	if f == nil {
+		return err
+	}
+	tag, err := db.Pool.Exec(c.Context(), `DELETE FROM quarantine WHERE `+filterWhere, f.ShipID, f.CargoID, f.From, f.To)
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete quarantined points")
+	}
+	return c.JSON(fiber.Map{"deleted": tag.RowsAffected()})
+}
@@ -0,0 +1,109 @@
+// Package validation checks points against domain rules in the worker:
+// plausible ranges and rates of change per cargo pattern, an allowed ship ID
+// pattern and finite values. Points that fail are written to the quarantine
+// table instead of cargo_data, so they can be inspected and released.
+package validation
+
+import (
+	"context"
+	"time"
+
+	"go-ingest-service/internal/db"
+	"go-ingest-service/internal/glob"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Rule bounds the values of the series whose ship and cargo IDs match its
+// glob patterns. Every matching enabled rule applies. MaxRate is the largest
+// plausible change per second.
+type Rule struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ShipID    string    `json:"ship_id"`
+	CargoID   string    `json:"cargo_id"`
+	Min       *float64  `json:"min"`
+	Max       *float64  `json:"max"`
+	MaxRate   *float64  `json:"max_rate"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Selects reports whether the rule applies to a series.
+func (r *Rule) Selects(shipID, cargoID string) bool {
+	return glob.Match(r.ShipID, shipID) && glob.Match(r.CargoID, cargoID)
+}
+
+const ruleColumns = `id, name, ship_id, cargo_id, min_value, max_value, max_rate, enabled, created_at, updated_at`
+
+func scanRule(row pgx.Row) (Rule, error) {
+	var r Rule
+	err := row.Scan(&r.ID, &r.Name, &r.ShipID, &r.CargoID, &r.Min, &r.Max, &r.MaxRate, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// ListRules returns all rules, or only the enabled ones.
+func ListRules(ctx context.Context, enabledOnly bool) ([]Rule, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+ruleColumns+` FROM validation_rules WHERE enabled OR NOT $1 ORDER BY id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule loads one rule; a nil rule means it does not exist.
+func GetRule(ctx context.Context, id int64) (*Rule, error) {
+	r, err := scanRule(db.Pool.QueryRow(ctx, `SELECT `+ruleColumns+` FROM validation_rules WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRule stores a new rule and fills in its ID and timestamps.
+func CreateRule(ctx context.Context, r *Rule) error {
+	return db.Pool.QueryRow(ctx, `
+		INSERT INTO validation_rules (name, ship_id, cargo_id, min_value, max_value, max_rate, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		r.Name, r.ShipID, r.CargoID, r.Min, r.Max, r.MaxRate, r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// UpdateRule replaces a rule, reporting whether it existed.
+func UpdateRule(ctx context.Context, r *Rule) (bool, error) {
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE validation_rules
+		SET name = $2, ship_id = $3, cargo_id = $4, min_value = $5, max_value = $6, max_rate = $7, enabled = $8, updated_at = now()
+		WHERE id = $1
+		RETURNING created_at, updated_at`,
+		r.ID, r.Name, r.ShipID, r.CargoID, r.Min, r.Max, r.MaxRate, r.Enabled,
+	).Scan(&r.CreatedAt, &r.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeleteRule removes a rule, reporting whether it existed.
+func DeleteRule(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM validation_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+	"go-ingest-service/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// endpointCacheTTL bounds how long endpoint changes take to reach the worker.
+const endpointCacheTTL = 30 * time.Second
+
+// Deliverer posts queued events to their endpoints.
+type Deliverer struct {
+	client      *http.Client
+	maxAttempts int
+	retryBase   time.Duration
+
+	mu        sync.Mutex
+	endpoints []Endpoint
+	loadedAt  time.Time
+}
+
+// NewDeliverer creates a deliverer using the WEBHOOK_* settings.
+func NewDeliverer() *Deliverer {
+	return &Deliverer{
+		client:      &http.Client{Timeout: config.AppConfig.WebhookTimeout},
+		maxAttempts: config.AppConfig.WebhookMaxAttempts,
+		retryBase:   config.AppConfig.WebhookRetryBase,
+	}
+}
+
+// Run delivers events with the given number of concurrent senders until ctx
+// is cancelled, and requeues failed deliveries once their backoff has elapsed.
+// It returns once the senders have stopped; deliveries popped are stored back
+// even while shutting down, so none are lost.
+func (d *Deliverer) Run(ctx context.Context, senders int) {
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.sendLoop(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			d.requeueDue(ctx)
+		}
+	}
+}
+
+func (d *Deliverer) sendLoop(ctx context.Context) {
+	store := context.WithoutCancel(ctx)
+	for ctx.Err() == nil {
+		result, err := cache.RedisClient.BLPop(ctx, time.Second, QueueKey()).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				log.Printf("[Webhook] Error popping from Redis: %v", err)
+				time.Sleep(5 * time.Second)
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			if err := cache.RedisClient.LPush(store, QueueKey(), result[1]).Err(); err != nil {
+				log.Printf("[Webhook] CRITICAL: Failed to return delivery to the queue. DATA: %s", result[1])
+			}
+			return
+		}
+
+		var dl delivery
+		if err := json.Unmarshal([]byte(result[1]), &dl); err != nil {
+			log.Printf("[Webhook] Dropping malformed delivery: %v", err)
+			continue
+		}
+		d.deliver(ctx, dl)
+	}
+}
+
+// requeueDue moves deliveries whose retry time has come back onto the queue.
+// ZREM decides which worker owns an entry when several poll the same set.
+func (d *Deliverer) requeueDue(ctx context.Context) {
+	due, err := cache.RedisClient.ZRangeByScore(ctx, retryKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[Webhook] Failed to read retry set: %v", err)
+		}
+		return
+	}
+	for _, member := range due {
+		if removed, err := cache.RedisClient.ZRem(ctx, retryKey(), member).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if err := cache.RedisClient.RPush(ctx, QueueKey(), member).Err(); err != nil {
+			log.Printf("[Webhook] CRITICAL: Failed to requeue delivery: %v", err)
+		}
+	}
+}
+
+// deliver sends an event to one endpoint, or fans it out to all subscribers.
+// Retries are scheduled even if ctx is cancelled mid-delivery.
+func (d *Deliverer) deliver(ctx context.Context, dl delivery) {
+	store := context.WithoutCancel(ctx)
+	endpoints, err := d.loadEndpoints(ctx)
+	if err != nil {
+		log.Printf("[Webhook] Failed to load endpoints, retrying event %s later: %v", dl.Event.ID, err)
+		d.retry(store, dl, err)
+		return
+	}
+
+	for _, e := range endpoints {
+		if !e.Enabled || (dl.EndpointID != 0 && e.ID != dl.EndpointID) || !e.Subscribes(dl.Event.Type) {
+			continue
+		}
+		if err := d.post(ctx, e, dl.Event); err != nil {
+			log.Printf("[Webhook] Delivery of %s event %s to endpoint %d failed (attempt %d): %v",
+				dl.Event.Type, dl.Event.ID, e.ID, dl.Attempt+1, err)
+			d.retry(store, delivery{Event: dl.Event, EndpointID: e.ID, Attempt: dl.Attempt}, err)
+		}
+	}
+}
+
+// retry schedules another attempt with exponential backoff, or moves the
+// delivery to the dead-letter list once WEBHOOK_MAX_ATTEMPTS is reached.
+func (d *Deliverer) retry(ctx context.Context, dl delivery, cause error) {
+	dl.Attempt++
+	body, err := json.Marshal(dl)
+	if err != nil {
+		log.Printf("[Webhook] CRITICAL: Failed to marshal delivery %s: %v", dl.Event.ID, err)
+		return
+	}
+
+	if dl.Attempt >= d.maxAttempts {
+		log.Printf("[Webhook] Event %s exceeded %d attempts, moving to DLQ: %v", dl.Event.ID, d.maxAttempts, cause)
+		if err := cache.RedisClient.RPush(ctx, dlqKey(), body).Err(); err != nil {
+			log.Printf("[Webhook] CRITICAL: Failed to move delivery to DLQ. DATA: %s", string(body))
+		}
+		return
+	}
+
+	next := time.Now().Add(utils.Backoff(d.retryBase, dl.Attempt))
+	if err := cache.RedisClient.ZAdd(ctx, retryKey(), &redis.Z{Score: float64(next.UnixMilli()), Member: body}).Err(); err != nil {
+		log.Printf("[Webhook] CRITICAL: Failed to schedule retry. DATA: %s", string(body))
+	}
+}
+
+// post sends the event, signing the body with the endpoint secret if it has one.
+func (d *Deliverer) post(ctx context.Context, e Endpoint, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Harbor-Event", event.Type)
+	req.Header.Set("X-Harbor-Delivery", event.ID)
+	if e.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(e.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Harbor-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// loadEndpoints returns the endpoints, reloading them from Postgres when the
+// cached list is older than endpointCacheTTL.
+func (d *Deliverer) loadEndpoints(ctx context.Context) ([]Endpoint, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.endpoints != nil && time.Since(d.loadedAt) < endpointCacheTTL {
+		return d.endpoints, nil
+	}
+	endpoints, err := ListEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.endpoints, d.loadedAt = endpoints, time.Now()
+	return endpoints, nil
+}
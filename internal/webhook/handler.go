@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+
+	"go-ingest-service/internal/models"
+	"go-ingest-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// endpointRequest is the body of POST /webhooks.
+type endpointRequest struct {
+	URL        string   `json:"url"         validate:"required,url,max=2000"`
+	Secret     string   `json:"secret"      validate:"max=200"`
+	EventTypes []string `json:"event_types" validate:"dive,min=1,max=100"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// ListWebhooks returns the registered endpoints. Secrets are never returned.
+func ListWebhooks(c *fiber.Ctx) error {
+	endpoints, err := ListEndpoints(c.Context())
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to load webhooks")
+	}
+	return c.JSON(endpoints)
+}
+
+// CreateWebhook registers an endpoint. Event types are glob patterns such as
+// "alert.*"; leave them empty to receive every event.
+func CreateWebhook(c *fiber.Ctx) error {
+	var req endpointRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	if errs := utils.ValidateStruct(&req); len(errs) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError(errs))
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return c.Status(http.StatusBadRequest).JSON(models.NewValidationError([]models.ErrorDetail{{
+			Loc: []string{"URL"}, Msg: "URL must use http or https", Type: "validation_error.url",
+		}}))
+	}
+	for _, pattern := range req.EventTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(models.NewValidationError([]models.ErrorDetail{{
+				Loc: []string{"EventTypes"}, Msg: "Invalid pattern " + pattern, Type: "validation_error.pattern",
+			}}))
+		}
+	}
+
+	e := Endpoint{URL: req.URL, Secret: req.Secret, EventTypes: req.EventTypes, Enabled: req.Enabled == nil || *req.Enabled}
+	if err := CreateEndpoint(c.Context(), &e); err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to create webhook")
+	}
+	return c.Status(http.StatusCreated).JSON(e)
+}
+
+// DeleteWebhook removes an endpoint. Queued deliveries to it are dropped.
+func DeleteWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+	found, err := DeleteEndpoint(c.Context(), int64(id))
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "Failed to delete webhook")
+	}
+	if !found {
+		return fiber.NewError(http.StatusNotFound, "Webhook not found")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+
+	"go-ingest-service/internal/db"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const endpointColumns = `id, url, COALESCE(secret, ''), event_types, enabled, created_at`
+
+func scanEndpoint(row pgx.Row) (Endpoint, error) {
+	var e Endpoint
+	err := row.Scan(&e.ID, &e.URL, &e.Secret, &e.EventTypes, &e.Enabled, &e.CreatedAt)
+	e.HasSecret = e.Secret != ""
+	if e.EventTypes == nil {
+		e.EventTypes = []string{}
+	}
+	return e, err
+}
+
+// ListEndpoints returns all registered endpoints.
+func ListEndpoints(ctx context.Context) ([]Endpoint, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// CreateEndpoint stores a new endpoint and fills in its ID and creation time.
+func CreateEndpoint(ctx context.Context, e *Endpoint) error {
+	if e.EventTypes == nil {
+		e.EventTypes = []string{}
+	}
+	row := db.Pool.QueryRow(ctx, `
+		INSERT INTO webhook_endpoints (url, secret, event_types, enabled)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		RETURNING id, created_at`,
+		e.URL, e.Secret, e.EventTypes, e.Enabled)
+	e.HasSecret = e.Secret != ""
+	return row.Scan(&e.ID, &e.CreatedAt)
+}
+
+// DeleteEndpoint removes an endpoint, reporting whether it existed.
+func DeleteEndpoint(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
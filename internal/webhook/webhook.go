@@ -0,0 +1,89 @@
+// Package webhook delivers events (alerts, ...) to HTTP endpoints registered in
+// Postgres. Events are queued in Redis and delivered by the worker, which
+// retries failed deliveries with exponential backoff before moving them to a
+// dead-letter list.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"go-ingest-service/internal/cache"
+	"go-ingest-service/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// Event is the JSON body posted to webhook endpoints.
+type Event struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// Endpoint is a registered webhook receiver. EventTypes are glob patterns
+// such as "alert.*"; an empty list subscribes to every event.
+type Endpoint struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	HasSecret  bool      `json:"has_secret"`
+	EventTypes []string  `json:"event_types"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the endpoint wants events of the given type.
+func (e Endpoint) Subscribes(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, pattern := range e.EventTypes {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// delivery is an event on its way to one endpoint, or to every subscribed
+// endpoint when EndpointID is 0.
+type delivery struct {
+	Event      Event `json:"event"`
+	EndpointID int64 `json:"endpoint_id,omitempty"`
+	Attempt    int   `json:"attempt,omitempty"`
+}
+
+// QueueKey is the Redis list of pending deliveries.
+func QueueKey() string {
+	return config.AppConfig.IngestQueueName + "_webhooks"
+}
+
+// retryKey is the Redis sorted set of failed deliveries, scored by the time
+// of their next attempt.
+func retryKey() string {
+	return config.AppConfig.IngestQueueName + "_webhooks_retry"
+}
+
+// dlqKey is the Redis list of deliveries that exhausted their attempts.
+func dlqKey() string {
+	return config.AppConfig.IngestQueueName + "_webhooks_dlq"
+}
+
+// Publish queues an event for every endpoint subscribed to its type.
+func Publish(ctx context.Context, eventType string, data interface{}) error {
+	event := Event{
+		ID:   uuid.NewString(),
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+	body, err := json.Marshal(delivery{Event: event})
+	if err != nil {
+		return err
+	}
+	return cache.RedisClient.RPush(ctx, QueueKey(), body).Err()
+}